@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// addressRaceDelay é o intervalo entre o início das tentativas no modo race (como no happy eyeballs):
+// o próximo endereço é tentado após esse intervalo ou assim que a tentativa anterior falhar
+const addressRaceDelay = 250 * time.Millisecond
+
+// HostAddress é um endereço pelo qual o host pode ser alcançado, com o seu Jump Host (nil = conexão direta)
+type HostAddress struct {
+	Host                   string
+	Port                   int
+	JumpHost               *config.JumpHost
+	JumpHostSSHKeys        []string
+	JumpHostPasswordSource config.PasswordSource
+
+	origin string // Origem do jump e da porta quando não vêm do próprio endereço (exibida por sc explain)
+}
+
+// hostPort retorna o endereço no formato host:porta
+func (a HostAddress) hostPort() string {
+	return fmt.Sprintf("%s:%d", a.Host, a.Port)
+}
+
+// String descreve o endereço nas mensagens (host:porta via jump)
+func (a HostAddress) String() string {
+	if a.JumpHost == nil {
+		return a.hostPort()
+	}
+	return a.hostPort() + " via " + a.JumpHost.Name
+}
+
+// addresses retorna os endereços do host na ordem de tentativa: o principal (Host/JumpHost) e os alternativos
+func (s *SSHConnection) addresses() []HostAddress {
+	addresses := []HostAddress{{
+		Host:                   s.Host,
+		Port:                   s.Port,
+		JumpHost:               s.JumpHost,
+		JumpHostSSHKeys:        s.JumpHostSSHKeys,
+		JumpHostPasswordSource: s.JumpHostPasswordSource,
+	}}
+	for _, address := range s.Alternates {
+		if address.Port == 0 {
+			address.Port = s.Port
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// dialAddresses conecta pelo primeiro endereço que responder, cada um limitado a s.AddressTimeout,
+// e retorna o cliente e a posição do endereço
+// Por padrão os endereços são tentados em ordem; com s.RaceAddresses, as conexões são disputadas em paralelo
+// e o handshake é feito apenas com o vencedor (a senha, se necessária, é pedida uma única vez)
+func (s *SSHConnection) dialAddresses(connectCtx, handshakeCtx context.Context, addresses []HostAddress, config *ssh.ClientConfig) (*ssh.Client, int, error) {
+	errs := make([]string, len(addresses))
+
+	// Jump Hosts que podem pedir senha ao usuário não são disputados em paralelo
+	race := s.RaceAddresses
+	if race && handshakeCtx != connectCtx && slices.ContainsFunc(addresses, func(a HostAddress) bool { return a.JumpHost != nil }) {
+		s.debugLog("Senha do Jump Host pode ser solicitada: endereços tentados em ordem")
+		race = false
+	}
+	if race {
+		s.debugLog("Disputando %d endereços em paralelo (address_strategy: race)", len(addresses))
+		t, index, err := s.raceTransports(connectCtx, addresses, errs)
+		if err != nil {
+			return nil, -1, err
+		}
+		client, err := s.addressHandshake(connectCtx, handshakeCtx, t, config)
+		if err == nil {
+			return client, index, nil
+		}
+		if connectCtx.Err() != nil {
+			return nil, -1, s.connectError(connectCtx, err)
+		}
+		if failureClassOf(err) == FailureAuth {
+			return nil, -1, err
+		}
+		// Os endereços que ainda não falharam são tentados em ordem
+		errs[index] = fmt.Sprintf("%s: %v", addresses[index], err)
+	}
+
+	for i, address := range addresses {
+		if errs[i] != "" {
+			continue
+		}
+		client, err := s.tryAddress(connectCtx, handshakeCtx, i, len(addresses), address, config)
+		if err == nil {
+			return client, i, nil
+		}
+		if connectCtx.Err() != nil {
+			return nil, -1, s.connectError(connectCtx, err)
+		}
+		// As credenciais são as mesmas em todos os endereços
+		if failureClassOf(err) == FailureAuth {
+			return nil, -1, err
+		}
+		errs[i] = fmt.Sprintf("%s: %v", address, err)
+	}
+	return nil, -1, addressesError(errs)
+}
+
+// tryAddress conecta por um endereço (conexão e handshake), limitado a s.AddressTimeout
+func (s *SSHConnection) tryAddress(connectCtx, handshakeCtx context.Context, index, total int, address HostAddress, config *ssh.ClientConfig) (*ssh.Client, error) {
+	s.debugLog("Tentando endereço %d de %d: %s", index+1, total, address)
+	t, err := s.openAddress(connectCtx, handshakeCtx, address)
+	if err != nil {
+		return nil, err
+	}
+	return s.addressHandshake(connectCtx, handshakeCtx, t, config)
+}
+
+// openAddress abre a conexão com um endereço, limitada a s.AddressTimeout
+func (s *SSHConnection) openAddress(connectCtx, handshakeCtx context.Context, address HostAddress) (*transport, error) {
+	attemptCtx, cancel := withTimeout(connectCtx, s.AddressTimeout)
+	defer cancel()
+	if handshakeCtx == connectCtx {
+		handshakeCtx = attemptCtx
+	}
+	t, err := s.openTransport(attemptCtx, handshakeCtx, address)
+	if err != nil {
+		err = s.addressError(attemptCtx, connectCtx, err)
+		if connectCtx.Err() == nil {
+			s.debugLog("Endereço %s falhou: %v", address, err)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// addressHandshake faz o handshake com o host pelo endereço já conectado, limitado a s.AddressTimeout
+// Se a senha puder ser pedida ao usuário, o limite vale apenas até a troca de chaves
+func (s *SSHConnection) addressHandshake(connectCtx, handshakeCtx context.Context, t *transport, config *ssh.ClientConfig) (*ssh.Client, error) {
+	attemptCtx := handshakeCtx
+	var expired atomic.Bool
+	if handshakeCtx == connectCtx {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = withTimeout(connectCtx, s.AddressTimeout)
+		defer cancel()
+	} else if s.AddressTimeout > 0 {
+		timer := time.AfterFunc(s.AddressTimeout, func() {
+			expired.Store(true)
+			t.conn.Close()
+		})
+		defer timer.Stop()
+		limited, hostKeyCallback := *config, config.HostKeyCallback
+		limited.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			timer.Stop()
+			return hostKeyCallback(hostname, remote, key)
+		}
+		config = &limited
+	}
+
+	client, err := s.handshake(attemptCtx, t, config)
+	if err != nil {
+		if expired.Load() && connectCtx.Err() == nil {
+			err = withFailureClass(FailureTimeout, fmt.Errorf("sem resposta em %s", s.AddressTimeout))
+		} else {
+			err = s.addressError(attemptCtx, connectCtx, err)
+		}
+		s.debugLog("Endereço %s falhou: %v", t.address, err)
+		return nil, err
+	}
+	return client, nil
+}
+
+// addressError substitui o erro de uma tentativa que excedeu s.AddressTimeout (mas não o limite da conexão)
+func (s *SSHConnection) addressError(attemptCtx, connectCtx context.Context, err error) error {
+	if attemptCtx.Err() != nil && connectCtx.Err() == nil {
+		return withFailureClass(FailureTimeout, fmt.Errorf("sem resposta em %s", s.AddressTimeout))
+	}
+	return err
+}
+
+// raceTransports disputa as conexões com os endereços em paralelo e retorna a primeira que abrir
+// As tentativas são escalonadas por addressRaceDelay; as falhas são registradas em errs
+func (s *SSHConnection) raceTransports(ctx context.Context, addresses []HostAddress, errs []string) (*transport, int, error) {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		t     *transport
+		err   error
+		index int
+	}
+	results := make(chan attempt, len(addresses))
+	next, pending := 0, 0
+	start := func() {
+		index := next
+		next++
+		pending++
+		s.debugLog("Tentando endereço %d de %d: %s", index+1, len(addresses), addresses[index])
+		go func() {
+			t, err := s.openAddress(raceCtx, raceCtx, addresses[index])
+			results <- attempt{t, err, index}
+		}()
+	}
+
+	// As tentativas restantes são canceladas; as que ainda conectarem são fechadas
+	drain := func() {
+		cancel()
+		go func(remaining int) {
+			for range remaining {
+				if late := <-results; late.t != nil {
+					late.t.close()
+				}
+			}
+		}(pending)
+	}
+
+	start()
+	delay := time.NewTimer(addressRaceDelay)
+	defer delay.Stop()
+
+	for pending > 0 {
+		select {
+		case <-delay.C:
+			if next < len(addresses) {
+				start()
+				delay.Reset(addressRaceDelay)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				drain()
+				return r.t, r.index, nil
+			}
+			if ctx.Err() != nil {
+				drain()
+				return nil, -1, s.connectError(ctx, r.err)
+			}
+			errs[r.index] = fmt.Sprintf("%s: %v", addresses[r.index], r.err)
+			if next < len(addresses) {
+				start()
+				delay.Reset(addressRaceDelay)
+			}
+		}
+	}
+	return nil, -1, addressesError(errs)
+}
+
+// reportAddress informa por qual endereço a conexão foi estabelecida (apenas quando há alternativos)
+func (s *SSHConnection) reportAddress(index, total int) {
+	s.debugLog("Conectado pelo endereço %d de %d: %s", index+1, total, s.Address)
+	if s.InteractivePasswordAllowed {
+		fmt.Fprintf(os.Stderr, "   ↪️  Conectado por %s (endereço %d de %d)\n", s.Address, index+1, total)
+	}
+}
+
+// close fecha a conexão e o tunnel do Jump Host (se houver)
+func (t *transport) close() {
+	t.conn.Close()
+	if t.jumpClient != nil {
+		t.jumpClient.Close()
+	}
+}
+
+// addressesError reúne as falhas de todos os endereços do host
+func addressesError(errs []string) error {
+	var failures []string
+	for _, err := range errs {
+		if err != "" {
+			failures = append(failures, err)
+		}
+	}
+	return withFailureClass(FailureConnection, fmt.Errorf("nenhum dos %d endereços respondeu: %s", len(errs), strings.Join(failures, "; ")))
+}
@@ -1,26 +1,29 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/alexeiev/sshControl/config"
 	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
 )
 
 // TransferResult armazena o resultado de uma transferência
 type TransferResult struct {
-	Host      string
-	Success   bool
-	FilePath  string
-	BytesSent int64
-	Duration  time.Duration
-	Error     string
+	Host         string
+	Success      bool
+	FilePath     string
+	BytesSent    int64
+	StartedAt    time.Time
+	Duration     time.Duration
+	Error        string
+	FailureClass FailureClass // Classe da falha (connection, auth, command) quando a transferência falhou
 }
 
 // FileTransfer gerencia transferências de arquivos via SFTP
@@ -29,6 +32,9 @@ type FileTransfer struct {
 	RemotePath string
 	Recursive  bool
 	Verbose    bool
+	Quiet      bool     // Não exibe barras de progresso (usado com saída estruturada)
+	Timeouts   Timeouts // Limites de tempo das conexões criadas por UploadMultiple
+	Template   bool     // Renderiza RemotePath como template por host em UploadMultiple (-T)
 }
 
 // ProgressWriter implementa io.Writer para exibir progresso
@@ -124,7 +130,8 @@ func formatBytes(bytes int64) string {
 }
 
 // Download baixa um arquivo ou diretório do servidor remoto
-func (ft *FileTransfer) Download(sshConn *SSHConnection) error {
+// A transferência é interrompida se ctx for cancelado ou se exceder sshConn.CommandTimeout
+func (ft *FileTransfer) Download(ctx context.Context, sshConn *SSHConnection) error {
 	sshConn.debugLog("Iniciando download SFTP")
 	sshConn.debugLog("Remoto: %s | Local: %s | Recursivo: %v", ft.RemotePath, ft.LocalPath, ft.Recursive)
 
@@ -136,12 +143,19 @@ func (ft *FileTransfer) Download(sshConn *SSHConnection) error {
 	}
 
 	// Conecta ao host
-	client, err := sshConn.dial(sshConfig)
+	client, err := sshConn.dial(ctx, sshConfig)
 	if err != nil {
-		return fmt.Errorf("erro ao conectar: %w", err)
+		return withFailureClass(dialFailureClass(err), fmt.Errorf("erro ao conectar: %w", err))
 	}
 	defer client.Close()
 
+	return runTransfer(ctx, sshConn, client, func() error {
+		return ft.download(sshConn, client)
+	})
+}
+
+// download executa o download sobre uma conexão já estabelecida
+func (ft *FileTransfer) download(sshConn *SSHConnection, client *ssh.Client) error {
 	// Cria cliente SFTP
 	sshConn.debugLog("Criando cliente SFTP...")
 	sftpClient, err := sftp.NewClient(client)
@@ -208,16 +222,19 @@ func (ft *FileTransfer) downloadFile(sftpClient *sftp.Client, remotePath, localP
 	}
 	defer localFile.Close()
 
-	// Cria progress writer
-	pw := NewProgressWriter(filepath.Base(remotePath), hostLabel, remoteInfo.Size())
-
 	// Copia com progresso
-	_, err = io.Copy(io.MultiWriter(localFile, pw), remoteFile)
+	if ft.Quiet {
+		_, err = io.Copy(localFile, remoteFile)
+	} else {
+		pw := NewProgressWriter(filepath.Base(remotePath), hostLabel, remoteInfo.Size())
+		if _, err = io.Copy(io.MultiWriter(localFile, pw), remoteFile); err == nil {
+			pw.Finish()
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("erro ao copiar arquivo: %w", err)
 	}
 
-	pw.Finish()
 	return nil
 }
 
@@ -253,7 +270,8 @@ func (ft *FileTransfer) downloadDir(sftpClient *sftp.Client, remotePath, localPa
 }
 
 // Upload envia um arquivo ou diretório para o servidor remoto
-func (ft *FileTransfer) Upload(sshConn *SSHConnection) error {
+// A transferência é interrompida se ctx for cancelado ou se exceder sshConn.CommandTimeout
+func (ft *FileTransfer) Upload(ctx context.Context, sshConn *SSHConnection) error {
 	sshConn.debugLog("Iniciando upload SFTP")
 	sshConn.debugLog("Local: %s | Remoto: %s | Recursivo: %v", ft.LocalPath, ft.RemotePath, ft.Recursive)
 
@@ -271,12 +289,19 @@ func (ft *FileTransfer) Upload(sshConn *SSHConnection) error {
 	}
 
 	// Conecta ao host
-	client, err := sshConn.dial(sshConfig)
+	client, err := sshConn.dial(ctx, sshConfig)
 	if err != nil {
-		return fmt.Errorf("erro ao conectar: %w", err)
+		return withFailureClass(dialFailureClass(err), fmt.Errorf("erro ao conectar: %w", err))
 	}
 	defer client.Close()
 
+	return runTransfer(ctx, sshConn, client, func() error {
+		return ft.upload(sshConn, client, localInfo)
+	})
+}
+
+// upload executa o upload sobre uma conexão já estabelecida
+func (ft *FileTransfer) upload(sshConn *SSHConnection, client *ssh.Client, localInfo os.FileInfo) error {
 	// Cria cliente SFTP
 	sshConn.debugLog("Criando cliente SFTP...")
 	sftpClient, err := sftp.NewClient(client)
@@ -298,6 +323,25 @@ func (ft *FileTransfer) Upload(sshConn *SSHConnection) error {
 	return ft.uploadFile(sftpClient, ft.LocalPath, ft.RemotePath, hostLabel)
 }
 
+// runTransfer executa transfer respeitando ctx e o limite de sshConn.CommandTimeout
+// Se o limite for excedido, a conexão é fechada para interromper a cópia em andamento
+func runTransfer(ctx context.Context, sshConn *SSHConnection, client *ssh.Client, transfer func() error) error {
+	transferCtx, cancel := withTimeout(ctx, sshConn.CommandTimeout)
+	defer cancel()
+
+	stop := context.AfterFunc(transferCtx, func() {
+		sshConn.debugLog("Interrompendo transferência: %v", transferCtx.Err())
+		client.Close()
+	})
+	defer stop()
+
+	err := transfer()
+	if err != nil && transferCtx.Err() != nil {
+		return contextError(transferCtx, "transferência", sshConn.CommandTimeout)
+	}
+	return err
+}
+
 // expandRemotePath expande ~ para o diretório home do usuário remoto
 // Também detecta e corrige quando o shell local expandiu ~ para o home local
 func expandRemotePath(sftpClient *sftp.Client, remotePath string) string {
@@ -362,16 +406,19 @@ func (ft *FileTransfer) uploadFile(sftpClient *sftp.Client, localPath, remotePat
 	}
 	defer remoteFile.Close()
 
-	// Cria progress writer
-	pw := NewProgressWriter(filepath.Base(localPath), hostLabel, localInfo.Size())
-
 	// Copia com progresso
-	_, err = io.Copy(io.MultiWriter(remoteFile, pw), localFile)
+	if ft.Quiet {
+		_, err = io.Copy(remoteFile, localFile)
+	} else {
+		pw := NewProgressWriter(filepath.Base(localPath), hostLabel, localInfo.Size())
+		if _, err = io.Copy(io.MultiWriter(remoteFile, pw), localFile); err == nil {
+			pw.Finish()
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("erro ao copiar arquivo: %w", err)
 	}
 
-	pw.Finish()
 	return nil
 }
 
@@ -409,6 +456,7 @@ func (ft *FileTransfer) uploadDir(sftpClient *sftp.Client, localPath, remotePath
 				LocalPath:  localEntryPath,
 				RemotePath: remoteEntryPath,
 				Recursive:  true,
+				Quiet:      ft.Quiet,
 			}
 			if err := subFt.uploadDirRecursive(sftpClient, localEntryPath, remoteEntryPath, hostLabel); err != nil {
 				return err
@@ -455,82 +503,84 @@ func (ft *FileTransfer) uploadDirRecursive(sftpClient *sftp.Client, localPath, r
 }
 
 // UploadMultiple envia arquivo para múltiplos hosts em paralelo
-func (ft *FileTransfer) UploadMultiple(cfg *config.ConfigFile, hostArgs []string, effectiveUser *config.User, jumpHost *config.JumpHost, password string, askPassword bool) []TransferResult {
-	// Expande tags para hosts
-	expandedHosts, tagsFound := expandTagsToHosts(cfg, hostArgs)
-	if len(tagsFound) > 0 {
-		fmt.Printf("Tags: %s\n", strings.Join(tagsFound, ", "))
-	}
-
-	results := make(chan TransferResult, len(expandedHosts))
-	var wg sync.WaitGroup
+// Retorna os resultados das transferências e os hosts não executados por interrupção do rollout
+func (ft *FileTransfer) UploadMultiple(ctx context.Context, cfg *config.ConfigFile, hostArgs []string, selectedUser *config.User, jumpHost *config.JumpHost, password string, askPassword bool, rollout RolloutOptions, format OutputFormat) ([]TransferResult, []string) {
+	useStructuredOutput(format)
 
-	for _, hostArg := range expandedHosts {
-		wg.Add(1)
-		go func(hostArg string) {
-			defer wg.Done()
-			result := ft.uploadToHost(cfg, hostArg, effectiveUser, jumpHost, password)
-			results <- result
-		}(hostArg)
+	// Expande os seletores (@tag, globs, intervalos...) para hosts
+	expandedHosts, tagsFound, err := expandHostSelectors(cfg, hostArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if len(expandedHosts) == 0 {
+		fmt.Fprintf(os.Stderr, "Erro: Nenhum host válido especificado\n")
+		os.Exit(1)
+	}
+	if len(tagsFound) > 0 {
+		fmt.Fprintf(infoOut, "Tags: %s\n", strings.Join(tagsFound, ", "))
 	}
 
-	// Aguarda todas as goroutines
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	results := make([]TransferResult, len(expandedHosts))
+	executed := make([]bool, len(expandedHosts))
+	skipped := runRollout(expandedHosts, rollout, func(i int, hostArg string) bool {
+		results[i] = ft.uploadToHost(ctx, cfg, hostArg, selectedUser, jumpHost, password)
+		executed[i] = true
+		return results[i].Success
+	})
 
-	// Coleta resultados
+	// Coleta resultados na ordem em que os hosts foram informados
 	var allResults []TransferResult
-	for result := range results {
-		allResults = append(allResults, result)
+	for i, result := range results {
+		if executed[i] {
+			allResults = append(allResults, result)
+		}
 	}
 
-	return allResults
+	return allResults, skipped
 }
 
 // uploadToHost envia arquivo para um único host
-func (ft *FileTransfer) uploadToHost(cfg *config.ConfigFile, hostArg string, effectiveUser *config.User, jumpHost *config.JumpHost, password string) TransferResult {
+func (ft *FileTransfer) uploadToHost(ctx context.Context, cfg *config.ConfigFile, hostArg string, selectedUser *config.User, jumpHost *config.JumpHost, password string) TransferResult {
 	startTime := time.Now()
 
-	var hostname string
-	var port int
-	var sshKeys []string
-
-	username := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
+	// Resolve o host (config.yaml ou conexão direta)
+	target, err := resolveHostTarget(ctx, cfg, hostArg, selectedUser)
+	if err != nil {
+		return TransferResult{
+			Host:         hostArg,
+			Success:      false,
+			Error:        fmt.Sprintf("Formato inválido: %v", err),
+			FailureClass: FailureConnection,
+			StartedAt:    startTime,
+		}
 	}
 
-	// Primeiro tenta encontrar no config.yaml
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		// Se não encontrar, tenta parsear como conexão direta
-		host, err := parseDirectConnection(hostArg, effectiveUser)
+	// Com -T, renderiza o destino com os dados do host ({{.Name}}, {{.Vars.app_dir}}, ...)
+	hostTransfer := *ft
+	if ft.Template {
+		hostTransfer.RemotePath, err = renderTemplate(ft.RemotePath, hostTemplateData(cfg, hostArg, target))
 		if err != nil {
 			return TransferResult{
-				Host:    hostArg,
-				Success: false,
-				Error:   fmt.Sprintf("Formato inválido: %v", err),
+				Host:         hostArg,
+				Success:      false,
+				Error:        fmt.Sprintf("Erro no template do destino: %v", err),
+				FailureClass: FailureCommand,
+				StartedAt:    startTime,
 			}
 		}
+	}
 
-		if host.parsedUser != "" && host.parsedUser != effectiveUser.Name {
-			username = host.parsedUser
-			if userFromConfig := cfg.FindUser(username); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				sshKeys = nil
-			}
+	// Sem -j, usa o jump host definido no host (campo jump) ou nas rules
+	jumpHost, err = connectionJumpHost(ctx, cfg, target, jumpHost)
+	if err != nil {
+		return TransferResult{
+			Host:         hostArg,
+			Success:      false,
+			Error:        err.Error(),
+			FailureClass: FailureConnection,
+			StartedAt:    startTime,
 		}
-
-		hostname = host.hostname
-		port = host.port
 	}
 
 	// Busca as chaves SSH do jump host
@@ -541,10 +591,10 @@ func (ft *FileTransfer) uploadToHost(cfg *config.ConfigFile, hostArg string, eff
 
 	// Cria a conexão SSH
 	sshConn := NewSSHConnection(
-		username,
-		hostname,
-		port,
-		sshKeys,
+		target.User,
+		target.Hostname,
+		target.Port,
+		target.SSHKeys,
 		password,
 		jumpHost,
 		jumpHostSSHKeys,
@@ -554,28 +604,33 @@ func (ft *FileTransfer) uploadToHost(cfg *config.ConfigFile, hostArg string, eff
 		0,
 		ft.Verbose,
 	)
+	target.configure(cfg, sshConn, ft.Timeouts)
 	sshConn.InteractivePasswordAllowed = false
 
 	// Verifica arquivo local
 	localInfo, err := os.Stat(ft.LocalPath)
 	if err != nil {
 		return TransferResult{
-			Host:    hostArg,
-			Success: false,
-			Error:   fmt.Sprintf("Erro ao acessar arquivo local: %v", err),
+			Host:         hostArg,
+			Success:      false,
+			Error:        fmt.Sprintf("Erro ao acessar arquivo local: %v", err),
+			FailureClass: FailureCommand,
+			StartedAt:    startTime,
 		}
 	}
 
 	// Executa o upload
-	err = ft.Upload(sshConn)
+	err = hostTransfer.Upload(ctx, sshConn)
 	duration := time.Since(startTime)
 
 	if err != nil {
 		return TransferResult{
-			Host:     hostArg,
-			Success:  false,
-			Error:    err.Error(),
-			Duration: duration,
+			Host:         hostArg,
+			Success:      false,
+			Error:        err.Error(),
+			FailureClass: transferFailureClass(err),
+			StartedAt:    startTime,
+			Duration:     duration,
 		}
 	}
 
@@ -584,12 +639,13 @@ func (ft *FileTransfer) uploadToHost(cfg *config.ConfigFile, hostArg string, eff
 		Success:   true,
 		FilePath:  ft.LocalPath,
 		BytesSent: localInfo.Size(),
+		StartedAt: startTime,
 		Duration:  duration,
 	}
 }
 
 // DisplayTransferResults exibe os resultados das transferências
-func DisplayTransferResults(results []TransferResult, totalDuration time.Duration) {
+func DisplayTransferResults(results []TransferResult, skipped []string, totalDuration time.Duration) {
 	successCount := 0
 	failureCount := 0
 
@@ -606,6 +662,11 @@ func DisplayTransferResults(results []TransferResult, totalDuration time.Duratio
 		}
 	}
 
+	if len(skipped) > 0 {
+		fmt.Println("------------------------------------------------------------------------------------")
+		fmt.Printf(" Não executados: %s\n", strings.Join(skipped, ", "))
+	}
+
 	fmt.Println("------------------------------------------------------------------------------------")
 	fmt.Printf(" Resumo: %d sucesso(s), %d falha(s), %d total | Tempo: %.2fs\n",
 		successCount, failureCount, len(results), totalDuration.Seconds())
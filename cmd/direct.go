@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/user"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -19,12 +21,8 @@ import (
 // 3. user@host: "ubuntu@192.168.1.50" (porta 22 por padrão)
 // 4. host:port: "192.168.1.50:22" (usa usuário especificado ou default)
 // 5. host: "192.168.1.50" (usa usuário especificado ou default e porta 22)
-func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selectedUser *config.User, jumpHost *config.JumpHost, command string, proxyEnabled bool, askPassword bool, verbose bool) {
-	var hostname string
-	var port int
-	var sshKeys []string
-	var shouldAutoCreate bool // Flag para indicar se deve criar o host automaticamente
-
+// Com template (-T), o comando é renderizado com os dados do host ({{.Name}}, {{.Vars.role}}, ...)
+func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selectedUser *config.User, jumpHost *config.JumpHost, command string, template bool, proxyEnabled bool, askPassword bool, verbose bool, timeouts Timeouts) {
 	// Determina o usuário efetivo (flag -u tem precedência sobre default_user)
 	effectiveUser := cfg.GetEffectiveUser(selectedUser)
 	if effectiveUser == nil {
@@ -35,46 +33,29 @@ func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selected
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
-	username := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
+	// Resolve o host (config.yaml ou conexão direta)
+	target, err := resolveHostTarget(context.Background(), cfg, hostArg, selectedUser)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Use o formato: user@host:port ou user@host ou host\n")
+		os.Exit(1)
 	}
+	username, hostname, port := target.User, target.Hostname, target.Port
 
-	// Primeiro tenta encontrar no config.yaml
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		// Se não encontrar, tenta parsear como conexão direta
-		host, err := parseDirectConnection(hostArg, effectiveUser)
+	// Renderiza o comando com os dados do host ({{.Name}}, {{.Vars.role}}, ...)
+	if template {
+		command, err = renderTemplate(command, hostTemplateData(cfg, hostArg, target))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
-			fmt.Fprintf(os.Stderr, "Use o formato: user@host:port ou user@host ou host\n")
+			fmt.Fprintf(os.Stderr, "Erro: template inválido no comando: %v\n", err)
 			os.Exit(1)
 		}
+	}
 
-		// Se a string incluir um usuário explícito (user@host), usa ele
-		if host.parsedUser != "" && host.parsedUser != effectiveUser.Name {
-			username = host.parsedUser
-			// Tenta obter as chaves SSH desse usuário específico
-			if userFromConfig := cfg.FindUser(username); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				// Usuário não está no config, não usa chave SSH
-				sshKeys = nil
-			}
-		}
-
-		hostname = host.hostname
-		port = host.port
-
-		// Verifica se auto_create está habilitado e se o host não existe pelo endereço
-		if cfg.Config.AutoCreate && cfg.FindHostByAddress(hostname) == nil {
-			shouldAutoCreate = true
-		}
+	// Sem -j, usa o jump host definido no host (campo jump) ou nas rules
+	jumpHost, err = connectionJumpHost(context.Background(), cfg, target, jumpHost)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Busca as chaves SSH do jump host se estiver usando jump host
@@ -83,11 +64,11 @@ func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selected
 		jumpHostSSHKeys = cfg.GetJumpHostSSHKeys(jumpHost)
 	}
 
-	// Obtém configuração de proxy
+	// Obtém configuração de proxy (-p ou proxy: true nas rules)
 	proxyAddress, proxyPort, proxyConfigured := cfg.Config.GetProxyConfig()
-	proxyActive := proxyEnabled && proxyConfigured
+	proxyActive := target.proxy(proxyEnabled) && proxyConfigured
 
-	if !proxyActive && proxyEnabled {
+	if !proxyConfigured && proxyEnabled {
 		fmt.Fprintf(os.Stderr, "⚠️  Aviso: Proxy solicitado mas não configurado no config.yaml\n")
 	}
 
@@ -109,7 +90,7 @@ func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selected
 		username,
 		hostname,
 		port,
-		sshKeys,
+		target.SSHKeys,
 		password, // Senha (vazia se -a não for especificado, ou fornecida pelo usuário)
 		jumpHost,
 		jumpHostSSHKeys,
@@ -119,9 +100,9 @@ func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selected
 		proxyPort,
 		verbose,
 	)
+	target.configure(cfg, sshConn, timeouts)
 
 	// Decide se executa comando remoto ou inicia sessão interativa
-	var err error
 	if command != "" {
 		err = sshConn.ExecuteCommand()
 	} else {
@@ -134,7 +115,7 @@ func Connect(cfg *config.ConfigFile, configPath string, hostArg string, selected
 	}
 
 	// Auto-criação do host após conexão bem-sucedida
-	if shouldAutoCreate {
+	if target.shouldAutoCreate(cfg) {
 		autoCreateHost(cfg, configPath, hostArg, hostname, port)
 	}
 }
@@ -170,9 +151,11 @@ func autoCreateHost(cfg *config.ConfigFile, configPath string, name string, host
 
 // parsedHost representa um host parseado de uma string de conexão
 type parsedHost struct {
-	parsedUser string
-	hostname   string
-	port       int
+	parsedUser   string
+	hostname     string
+	port         int
+	explicitUser bool // O usuário veio da string (user@host)
+	explicitPort bool // A porta veio da string (host:port)
 }
 
 // parseDirectConnection analisa uma string de conexão direta
@@ -188,6 +171,7 @@ func parseDirectConnection(input string, effectiveUser *config.User) (*parsedHos
 	parsedUser := matches[1]
 	hostname := matches[2]
 	portStr := matches[3]
+	explicitUser := parsedUser != ""
 
 	// Prioridade do usuário:
 	// 1. Usuário especificado na string (user@host)
@@ -222,12 +206,275 @@ func parseDirectConnection(input string, effectiveUser *config.User) (*parsedHos
 	}
 
 	return &parsedHost{
-		parsedUser: parsedUser,
-		hostname:   hostname,
-		port:       port,
+		parsedUser:   parsedUser,
+		hostname:     hostname,
+		port:         port,
+		explicitUser: explicitUser,
+		explicitPort: portStr != "",
 	}, nil
 }
 
+// hostTarget é o destino resolvido de um argumento de host (nome do config.yaml ou conexão direta)
+type hostTarget struct {
+	User       string
+	Hostname   string
+	Port       int
+	SSHKeys    []string           // Chaves (já expandidas): as das rules primeiro, depois as do usuário
+	ConfigHost *config.Host       // Host do config.yaml (nil para conexões diretas)
+	Rules      *config.RuleResult // Opções das rules que corresponderam ao host
+	Alternates []HostAddress      // Endereços alternativos do host (preenchidos por connectionJumpHost)
+
+	userOrigin string // Origem do usuário e da porta (exibida por sc explain)
+	portOrigin string
+}
+
+// connectionJumpHost retorna o jump host da conexão: o informado com -j ou, sem ele, o campo jump do host
+// ou o jump da primeira regra que o define
+// Também resolve os jump hosts dos endereços alternativos do host (target.Alternates)
+func connectionJumpHost(ctx context.Context, cfg *config.ConfigFile, target *hostTarget, jumpHost *config.JumpHost) (*config.JumpHost, error) {
+	if err := target.resolveAlternates(ctx, cfg, jumpHost); err != nil {
+		return nil, err
+	}
+	if jumpHost != nil {
+		return jumpHost, nil
+	}
+	if target.ConfigHost != nil && target.ConfigHost.Jump != "" {
+		return cfg.HostJumpHost(target.ConfigHost)
+	}
+	if target.Rules.Jump == "" {
+		return nil, nil
+	}
+	jumpHost = cfg.ResolveJumpHost(target.Rules.Jump)
+	if jumpHost == nil {
+		return nil, fmt.Errorf("jump host '%s' da regra %s não encontrado", target.Rules.Jump, target.Rules.Source("jump"))
+	}
+	return jumpHost, nil
+}
+
+// resolveAlternates resolve os endereços alternativos do host (addresses)
+// -j vale para todos os endereços; sem ele, cada endereço usa o próprio jump ou, se não tiver,
+// o das regras avaliadas para o endereço (ex: cidr); sem nenhum, a conexão é direta
+// A porta do endereço, se não definida, é a do host ou (host sem port) a das regras do endereço
+func (t *hostTarget) resolveAlternates(ctx context.Context, cfg *config.ConfigFile, jumpHost *config.JumpHost) error {
+	t.Alternates = nil
+	if t.ConfigHost == nil {
+		return nil
+	}
+	for _, address := range t.ConfigHost.Addresses {
+		addressHost := *t.ConfigHost
+		addressHost.Host = address.Host
+		rules := cfg.EvaluateRules(ctx, &addressHost, address.Host)
+
+		alternate := HostAddress{Host: address.Host, Port: address.Port, JumpHost: jumpHost}
+		var origins []string
+		switch {
+		case jumpHost != nil:
+			origins = append(origins, "jump: -j")
+		case address.Jump != "":
+			alternate.JumpHost = cfg.ResolveJumpHost(address.Jump)
+			if alternate.JumpHost == nil {
+				return fmt.Errorf("jump host '%s' do endereço %s do host '%s' não encontrado", address.Jump, address.Host, t.ConfigHost.Name)
+			}
+		case rules.Jump != "":
+			alternate.JumpHost = cfg.ResolveJumpHost(rules.Jump)
+			if alternate.JumpHost == nil {
+				return fmt.Errorf("jump host '%s' da regra %s (endereço %s) não encontrado", rules.Jump, rules.Source("jump"), address.Host)
+			}
+			origins = append(origins, "jump: regra "+rules.Source("jump"))
+		}
+		if alternate.JumpHost != nil {
+			alternate.JumpHostSSHKeys = cfg.GetJumpHostSSHKeys(alternate.JumpHost)
+			alternate.JumpHostPasswordSource = cfg.JumpHostPasswordSource(alternate.JumpHost)
+		}
+		if alternate.Port == 0 {
+			alternate.Port = t.Port
+			if t.ConfigHost.Port == 0 && rules.Port != 0 {
+				alternate.Port = rules.Port
+				origins = append(origins, "porta: regra "+rules.Source("port"))
+			}
+		}
+		alternate.origin = strings.Join(origins, ", ")
+		t.Alternates = append(t.Alternates, alternate)
+	}
+	return nil
+}
+
+// proxy indica se o proxy reverso foi pedido com -p (ou pelo contexto) ou por uma regra
+func (t *hostTarget) proxy(requested bool) bool {
+	return requested || t.Rules.Proxy
+}
+
+// timeouts aplica os limites de tempo das rules aos que não vieram da linha de comando
+func (t *hostTarget) timeouts(timeouts Timeouts) Timeouts {
+	if t.Rules.ConnectTimeout != 0 && !timeouts.ConnectFlag {
+		timeouts.Connect = t.Rules.ConnectTimeout
+	}
+	if t.Rules.CommandTimeout != 0 && !timeouts.CommandFlag {
+		timeouts.Command = t.Rules.CommandTimeout
+	}
+	return timeouts
+}
+
+// configure aplica à conexão as opções do host que não fazem parte de NewSSHConnection
+// (origens de senha, limites de tempo, variáveis de ambiente das rules e endereços alternativos)
+func (t *hostTarget) configure(cfg *config.ConfigFile, sshConn *SSHConnection, timeouts Timeouts) {
+	sshConn.UsePasswordSources(cfg, t.ConfigHost)
+	timeouts = t.timeouts(timeouts)
+	sshConn.ConnectTimeout = timeouts.Connect
+	sshConn.CommandTimeout = timeouts.Command
+	sshConn.Env = t.Rules.Env
+	sshConn.Alternates = t.Alternates
+	sshConn.AddressTimeout = cfg.Config.GetAddressTimeout()
+	sshConn.RaceAddresses = cfg.Config.AddressStrategy == config.AddressStrategyRace
+}
+
+// NewHostConnection resolve um argumento de host (config.yaml, conexão direta e rules) e cria a
+// conexão SSH sem comando, usada pelas transferências e pelo port forward
+// jumpHost é o jump host de -j (nil usa o do host ou o das rules)
+func NewHostConnection(cfg *config.ConfigFile, hostArg string, selectedUser *config.User, jumpHost *config.JumpHost, timeouts Timeouts, verbose bool) (*SSHConnection, error) {
+	target, err := resolveHostTarget(context.Background(), cfg, hostArg, selectedUser)
+	if err != nil {
+		return nil, err
+	}
+	jumpHost, err = connectionJumpHost(context.Background(), cfg, target, jumpHost)
+	if err != nil {
+		return nil, err
+	}
+	var jumpHostSSHKeys []string
+	if jumpHost != nil {
+		jumpHostSSHKeys = cfg.GetJumpHostSSHKeys(jumpHost)
+	}
+
+	sshConn := NewSSHConnection(
+		target.User,
+		target.Hostname,
+		target.Port,
+		target.SSHKeys,
+		"",
+		jumpHost,
+		jumpHostSSHKeys,
+		"",
+		false,
+		"",
+		0,
+		verbose,
+	)
+	target.configure(cfg, sshConn, timeouts)
+	return sshConn, nil
+}
+
+// resolveHostTarget resolve um argumento de host: primeiro no config.yaml, depois como [user@]host[:port]
+// selectedUser é o usuário de -u (nil usa o user do host, o das rules ou o default_user)
+func resolveHostTarget(ctx context.Context, cfg *config.ConfigFile, hostArg string, selectedUser *config.User) (*hostTarget, error) {
+	// Primeiro tenta encontrar no config.yaml
+	if host := cfg.FindHost(hostArg); host != nil {
+		return configHostTarget(ctx, cfg, host, selectedUser), nil
+	}
+
+	effectiveUser := cfg.GetEffectiveUser(selectedUser)
+
+	// Se não encontrar, tenta parsear como conexão direta
+	host, err := parseDirectConnection(hostArg, effectiveUser)
+	if err != nil {
+		return nil, err
+	}
+	target := &hostTarget{
+		Hostname: host.hostname,
+		Rules:    cfg.EvaluateRules(ctx, nil, host.hostname),
+	}
+
+	// Prioridade: user@host, -u, rules, default_user
+	var userKeys []string
+	switch {
+	case host.explicitUser:
+		target.User, target.userOrigin = host.parsedUser, "user@host"
+		if effectiveUser != nil && host.parsedUser == effectiveUser.Name {
+			userKeys = effectiveUser.SSHKeys
+		} else if userFromConfig := cfg.FindUser(host.parsedUser); userFromConfig != nil {
+			// Tenta obter as chaves SSH desse usuário específico
+			userKeys = userFromConfig.SSHKeys
+		}
+	case selectedUser == nil && target.Rules.User != "":
+		ruleUser := ruleUser(cfg, target.Rules.User)
+		target.User, target.userOrigin = ruleUser.Name, "regra "+target.Rules.Source("user")
+		userKeys = ruleUser.SSHKeys
+	default:
+		target.User, target.userOrigin = host.parsedUser, "default_user"
+		if selectedUser != nil {
+			target.userOrigin = "-u"
+		}
+		if effectiveUser != nil {
+			userKeys = effectiveUser.SSHKeys
+		}
+	}
+	target.SSHKeys = target.keys(userKeys)
+
+	target.Port, target.portOrigin = host.port, "host:porta"
+	if !host.explicitPort {
+		target.setRulePort()
+	}
+	return target, nil
+}
+
+// configHostTarget resolve um host do config.yaml (ou do inventário dinâmico)
+func configHostTarget(ctx context.Context, cfg *config.ConfigFile, host *config.Host, selectedUser *config.User) *hostTarget {
+	target := &hostTarget{
+		Hostname:   host.Host,
+		ConfigHost: host,
+		Rules:      cfg.EvaluateRules(ctx, host, host.Host),
+	}
+
+	// Prioridade: -u, campo user do host, rules, default_user
+	hostUser := cfg.HostUser(host, selectedUser)
+	target.userOrigin = "default_user"
+	switch {
+	case selectedUser != nil:
+		target.userOrigin = "-u"
+	case host.User != "":
+		target.userOrigin = "host"
+	case target.Rules.User != "":
+		hostUser = ruleUser(cfg, target.Rules.User)
+		target.userOrigin = "regra " + target.Rules.Source("user")
+	}
+	target.User = hostUser.Name
+	target.SSHKeys = target.keys(hostUser.SSHKeys)
+
+	target.Port, target.portOrigin = host.Port, "host"
+	if host.Port == 0 {
+		target.setRulePort()
+	}
+	return target
+}
+
+// ruleUser retorna o usuário definido por uma regra (com as chaves do default_user se não estiver em config.users)
+func ruleUser(cfg *config.ConfigFile, name string) *config.User {
+	return cfg.HostUser(&config.Host{User: name}, nil)
+}
+
+// keys monta as chaves da conexão: as das rules primeiro, depois as do usuário (sem repetições)
+func (t *hostTarget) keys(userKeys []string) []string {
+	var keys []string
+	for _, key := range append(slices.Clone(t.Rules.SSHKeys), userKeys...) {
+		if key = config.ExpandHomePath(key); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// setRulePort usa a porta das rules (ou 22) quando o host não define uma
+func (t *hostTarget) setRulePort() {
+	t.Port, t.portOrigin = 22, "padrão"
+	if t.Rules.Port != 0 {
+		t.Port, t.portOrigin = t.Rules.Port, "regra "+t.Rules.Source("port")
+	}
+}
+
+// shouldAutoCreate indica se o host deve ser salvo no config.yaml (auto_create) após conectar
+func (t *hostTarget) shouldAutoCreate(cfg *config.ConfigFile) bool {
+	return t.ConfigHost == nil && cfg.Config.AutoCreate && cfg.FindHostByAddress(t.Hostname) == nil
+}
+
 // ValidateHostFormat valida se o formato da string é válido
 func ValidateHostFormat(input string) bool {
 	_, err := parseDirectConnection(input, nil)
@@ -244,10 +491,35 @@ func ParseConnectionString(input string) (user, host string, port int, err error
 }
 
 // ListServers exibe todos os servidores e jump hosts cadastrados no config
-// Se tagFilter não estiver vazio, filtra os servidores pela tag especificada
-func ListServers(cfg *config.ConfigFile, tagFilter string) {
+// Se houver seletores (@tag, globs, ~regex...), filtra os servidores exibidos
+// Em formatos estruturados (json, yaml, ...), escreve apenas os dados em stdout
+func ListServers(cfg *config.ConfigFile, selectors []string, format OutputFormat) error {
+	// Filtra servidores pelo contexto ativo e pelos seletores, se especificados
+	var selector *config.Selector
+	filter := strings.Join(selectors, " ")
+	if len(selectors) > 0 {
+		var err error
+		if selector, err = config.ParseSelector(selectors...); err != nil {
+			return err
+		}
+	}
+	var hostsToShow []config.Host
+	for i := range cfg.Hosts {
+		if cfg.InContext(&cfg.Hosts[i]) && (selector == nil || selector.Matches(&cfg.Hosts[i])) {
+			hostsToShow = append(hostsToShow, cfg.Hosts[i])
+		}
+	}
+
+	if format.IsStructured() {
+		return writeServerList(os.Stdout, format, cfg.Config.JumpHosts, hostsToShow)
+	}
+
 	fmt.Println()
 
+	if ctx := cfg.Context(); ctx != nil && ctx.Filter != "" {
+		fmt.Printf("🧭 Contexto '%s': apenas hosts de %s\n\n", ctx.Name, ctx.Filter)
+	}
+
 	// Exibe Jump Hosts se houver algum (sempre mostra, independente do filtro)
 	if len(cfg.Config.JumpHosts) > 0 {
 		fmt.Println("🔗 Jump Hosts cadastrados:")
@@ -268,27 +540,19 @@ func ListServers(cfg *config.ConfigFile, tagFilter string) {
 		fmt.Println()
 	}
 
-	// Filtra servidores por tag se especificado
-	var hostsToShow []config.Host
-	if tagFilter != "" {
-		hostsToShow = cfg.FindHostsByTag(tagFilter)
-	} else {
-		hostsToShow = cfg.Hosts
-	}
-
 	// Exibe Servidores
 	if len(hostsToShow) == 0 {
-		if tagFilter != "" {
-			fmt.Printf("ℹ️  Nenhum servidor encontrado com a tag '%s'\n", tagFilter)
+		if filter != "" {
+			fmt.Printf("ℹ️  Nenhum servidor corresponde a '%s'\n", filter)
 		} else {
 			fmt.Println("ℹ️  Nenhum servidor cadastrado no config.yaml")
 		}
 		fmt.Println()
-		return
+		return nil
 	}
 
-	if tagFilter != "" {
-		fmt.Printf("📋 Servidores com tag '%s':\n", tagFilter)
+	if filter != "" {
+		fmt.Printf("📋 Servidores (%s):\n", filter)
 	} else {
 		fmt.Println("📋 Servidores cadastrados:")
 	}
@@ -297,7 +561,13 @@ func ListServers(cfg *config.ConfigFile, tagFilter string) {
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	for _, host := range hostsToShow {
-		hostPort := fmt.Sprintf("%s:%d", host.Host, host.Port)
+		hostPort := host.Host
+		if host.Port != 0 {
+			hostPort = fmt.Sprintf("%s:%d", host.Host, host.Port)
+		}
+		if len(host.Addresses) > 0 {
+			hostPort += fmt.Sprintf(" (+%d)", len(host.Addresses))
+		}
 		tags := "-"
 		if len(host.Tags) > 0 {
 			tags = strings.Join(host.Tags, ", ")
@@ -308,4 +578,5 @@ func ListServers(cfg *config.ConfigFile, tagFilter string) {
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("Total: %d servidor(es)\n", len(hostsToShow))
 	fmt.Println()
+	return nil
 }
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+)
+
+// FailureClass classifica o motivo da falha em um host
+type FailureClass string
+
+const (
+	FailureNone       FailureClass = ""
+	FailureConnection FailureClass = "connection" // Falha de rede, DNS, jump host ou handshake
+	FailureAuth       FailureClass = "auth"       // Nenhum método de autenticação foi aceito
+	FailureCommand    FailureClass = "command"    // Comando executado com exit code diferente de zero ou erro na sessão
+	FailureTimeout    FailureClass = "timeout"    // Tempo limite de conexão ou de execução excedido
+	FailureCanceled   FailureClass = "canceled"   // Execução interrompida pelo usuário (Ctrl+C)
+)
+
+// classifiedError associa uma classe de falha a um erro
+type classifiedError struct {
+	class FailureClass
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// withFailureClass anota o erro com a classe de falha informada
+func withFailureClass(class FailureClass, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// failureClassOf retorna a classe de falha de um erro
+// Erros sem classificação explícita são considerados falhas de conexão
+func failureClassOf(err error) FailureClass {
+	if err == nil {
+		return FailureNone
+	}
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	return FailureConnection
+}
+
+// dialFailureClass diferencia falhas de autenticação das demais falhas de conexão
+// Erros já classificados (ex: tempo limite excedido) mantêm a sua classe
+func dialFailureClass(err error) FailureClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	if err != nil && strings.Contains(err.Error(), "unable to authenticate") {
+		return FailureAuth
+	}
+	return FailureConnection
+}
+
+// transferFailureClass classifica erros de transferência: erros sem classificação
+// ocorrem após a conexão e são falhas da própria cópia
+func transferFailureClass(err error) FailureClass {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	return FailureCommand
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+)
+
+// Explain exibe as regras (seção rules) avaliadas para um host e a conexão resultante,
+// com a origem de cada opção (sc explain)
+// selectedUser, jumpHost e proxyEnabled são os valores de -u, -j e -p (ou do contexto ativo)
+func Explain(cfg *config.ConfigFile, hostArg string, selectedUser *config.User, jumpHost *config.JumpHost, proxyEnabled bool, timeouts Timeouts) error {
+	target, err := resolveHostTarget(context.Background(), cfg, hostArg, selectedUser)
+	if err != nil {
+		return err
+	}
+	connJump, err := connectionJumpHost(context.Background(), cfg, target, jumpHost)
+	if err != nil {
+		return err
+	}
+	rules := target.Rules
+
+	fmt.Println()
+	if target.ConfigHost != nil {
+		fmt.Printf("🔎 %s (%s, host do config.yaml)\n", hostArg, target.Hostname)
+	} else {
+		fmt.Printf("🔎 %s (conexão direta)\n", hostArg)
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	if len(rules.Evaluations) == 0 {
+		fmt.Println("ℹ️  Nenhuma regra definida (seção rules do config.yaml)")
+	}
+	for _, eval := range rules.Evaluations {
+		mark := "✗"
+		if eval.Matched {
+			mark = "✓"
+		}
+		fmt.Printf("%s %-20s %s\n", mark, eval.Label(), eval.Reason)
+		if len(eval.Applied) > 0 {
+			fmt.Printf("     aplicou: %s\n", strings.Join(eval.Applied, ", "))
+		}
+		if len(eval.Ignored) > 0 {
+			fmt.Printf("     ignorou (definido por regra anterior): %s\n", strings.Join(eval.Ignored, ", "))
+		}
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	// Conexão resultante, com a origem de cada opção
+	fmt.Println("Conexão resultante:")
+	fmt.Printf("   %-16s %-30s (%s)\n", "Usuário:", target.User, target.userOrigin)
+	fmt.Printf("   %-16s %-30s (porta: %s)\n", "Host:", fmt.Sprintf("%s:%d", target.Hostname, target.Port), target.portOrigin)
+
+	jumpText, jumpOrigin := "-", ""
+	if connJump != nil {
+		jumpText = fmt.Sprintf("%s (%s:%d)", connJump.Name, connJump.Host, connJump.Port)
+		switch {
+		case jumpHost != nil:
+			jumpOrigin = "-j"
+		case target.ConfigHost != nil && target.ConfigHost.Jump != "":
+			jumpOrigin = "host"
+		default:
+			jumpOrigin = "regra " + rules.Source("jump")
+		}
+	}
+	printExplainLine("Jump host:", jumpText, jumpOrigin)
+
+	// Endereços alternativos (addresses), tentados após o principal
+	for i, address := range target.Alternates {
+		label := ""
+		if i == 0 {
+			label = "Alternativos:"
+		}
+		printExplainLine(label, address.String(), address.origin)
+	}
+	if len(target.Alternates) > 0 {
+		strategy := cfg.Config.AddressStrategy
+		if strategy == "" {
+			strategy = config.AddressStrategyOrder
+		}
+		printExplainLine("Estratégia:", fmt.Sprintf("%s, %s por endereço", strategy, cfg.Config.GetAddressTimeout()), "config")
+	}
+
+	keys := "-"
+	if len(target.SSHKeys) > 0 {
+		keys = strings.Join(target.SSHKeys, ", ")
+	}
+	keysOrigin := ""
+	if len(rules.SSHKeys) > 0 {
+		keysOrigin = "rules primeiro, depois as do usuário"
+	}
+	printExplainLine("Chaves SSH:", keys, keysOrigin)
+
+	_, _, proxyConfigured := cfg.Config.GetProxyConfig()
+	proxyText, proxyOrigin := "não", ""
+	if target.proxy(proxyEnabled) {
+		proxyText, proxyOrigin = "sim", "-p"
+		if !proxyEnabled {
+			proxyOrigin = "regra " + rules.Source("proxy")
+		}
+		if !proxyConfigured {
+			proxyText, proxyOrigin = "não", proxyOrigin+", mas config.proxy não está configurado"
+		}
+	}
+	printExplainLine("Proxy:", proxyText, proxyOrigin)
+
+	resolved := target.timeouts(timeouts)
+	printExplainLine("connect_timeout:", timeoutText(resolved.Connect), timeoutOrigin(rules, "connect_timeout", timeouts.ConnectFlag, "--connect-timeout"))
+	printExplainLine("command_timeout:", timeoutText(resolved.Command), timeoutOrigin(rules, "command_timeout", timeouts.CommandFlag, "--command-timeout"))
+
+	if len(rules.Env) == 0 {
+		printExplainLine("Env:", "-", "")
+	}
+	for i, name := range slices.Sorted(maps.Keys(rules.Env)) {
+		label := ""
+		if i == 0 {
+			label = "Env:"
+		}
+		printExplainLine(label, name+"="+rules.Env[name], "regra "+rules.Source("env."+name))
+	}
+	fmt.Println()
+	return nil
+}
+
+// printExplainLine exibe uma opção da conexão resultante com a sua origem (se houver)
+func printExplainLine(label, value, origin string) {
+	if origin == "" {
+		fmt.Printf("   %-16s %s\n", label, value)
+		return
+	}
+	fmt.Printf("   %-16s %-30s (%s)\n", label, value, origin)
+}
+
+// timeoutText descreve um limite de tempo (0 = sem limite)
+func timeoutText(d time.Duration) string {
+	if d <= 0 {
+		return "sem limite"
+	}
+	return d.String()
+}
+
+// timeoutOrigin descreve a origem de um limite de tempo: flag, regra ou config
+func timeoutOrigin(rules *config.RuleResult, option string, fromFlag bool, flag string) string {
+	if fromFlag {
+		return flag
+	}
+	if source := rules.Source(option); source != "" {
+		return "regra " + source
+	}
+	return "config"
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outputGroup reúne os hosts que produziram a mesma saída e o mesmo exit code (--group)
+type outputGroup struct {
+	Output   string
+	ExitCode int
+	Hosts    []string
+}
+
+// groupResults agrupa os resultados por saída idêntica, do maior grupo para o menor
+// Hosts com erro (conexão, autenticação, tempo limite) não são agrupados e são retornados à parte
+func groupResults(results []HostResult) ([]*outputGroup, []HostResult) {
+	var groups []*outputGroup
+	var errored []HostResult
+	byKey := make(map[[sha256.Size]byte]*outputGroup)
+
+	for _, result := range results {
+		if result.Error != "" {
+			errored = append(errored, result)
+			continue
+		}
+		key := sha256.Sum256([]byte(strconv.Itoa(result.ExitCode) + "\x00" + result.Output))
+		group, ok := byKey[key]
+		if !ok {
+			group = &outputGroup{Output: result.Output, ExitCode: result.ExitCode}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.Hosts = append(group.Hosts, result.Host)
+	}
+
+	// Em caso de empate, mantém a ordem em que os hosts foram informados
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Hosts) > len(groups[j].Hosts)
+	})
+	return groups, errored
+}
+
+// hostNumberPattern separa o prefixo e o sufixo numérico de um nome de host (ex: web01)
+var hostNumberPattern = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// foldHosts compacta nomes com sufixo numérico em intervalos (web1 web2 web3 web7 -> web[1-3,7])
+func foldHosts(hosts []string) string {
+	type series struct {
+		prefix string
+		width  int // Largura com zeros à esquerda (0 = sem preenchimento)
+		nums   []int
+	}
+	var order []string
+	seriesByKey := make(map[string]*series)
+	var plain []string
+
+	for _, host := range hosts {
+		match := hostNumberPattern.FindStringSubmatch(host)
+		if match == nil {
+			order = append(order, host)
+			plain = append(plain, host)
+			continue
+		}
+		digits := match[2]
+		width := 0
+		if len(digits) > 1 && digits[0] == '0' {
+			width = len(digits)
+		}
+		num, err := strconv.Atoi(digits)
+		if err != nil {
+			order = append(order, host)
+			plain = append(plain, host)
+			continue
+		}
+		key := fmt.Sprintf("%s\x00%d", match[1], width)
+		s, ok := seriesByKey[key]
+		if !ok {
+			s = &series{prefix: match[1], width: width}
+			seriesByKey[key] = s
+			order = append(order, key)
+		}
+		s.nums = append(s.nums, num)
+	}
+
+	var parts []string
+	for _, key := range order {
+		s, ok := seriesByKey[key]
+		if !ok {
+			parts = append(parts, key)
+			continue
+		}
+		if len(s.nums) == 1 {
+			parts = append(parts, fmt.Sprintf("%s%0*d", s.prefix, s.width, s.nums[0]))
+			continue
+		}
+
+		sort.Ints(s.nums)
+		var ranges []string
+		for i := 0; i < len(s.nums); {
+			j := i
+			for j+1 < len(s.nums) && s.nums[j+1] <= s.nums[j]+1 {
+				j++
+			}
+			if s.nums[i] == s.nums[j] {
+				ranges = append(ranges, fmt.Sprintf("%0*d", s.width, s.nums[i]))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%0*d-%0*d", s.width, s.nums[i], s.width, s.nums[j]))
+			}
+			i = j + 1
+		}
+		parts = append(parts, fmt.Sprintf("%s[%s]", s.prefix, strings.Join(ranges, ",")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// displayGroupedResults exibe cada saída distinta uma única vez, com a lista de hosts que a produziram (--group)
+// Com showDiff, os grupos minoritários são exibidos como diff unificado em relação à saída majoritária
+func displayGroupedResults(results []HostResult, skipped []string, duration time.Duration, showDiff bool) {
+	successCount := 0
+	failureCount := 0
+	for _, result := range results {
+		if result.Failed() {
+			failureCount++
+		} else {
+			successCount++
+		}
+	}
+
+	groups, errored := groupResults(results)
+	for i, group := range groups {
+		icon := "✅"
+		if group.ExitCode != 0 {
+			icon = "❌"
+		}
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("%s %d host(s) (Exit Code: %d): %s\n", icon, len(group.Hosts), group.ExitCode, foldHosts(group.Hosts))
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+		output := group.Output
+		if showDiff && i > 0 {
+			majority := groups[0]
+			output = unifiedDiff(
+				fmt.Sprintf("maioria (%d host(s))", len(majority.Hosts)),
+				fmt.Sprintf("grupo (%d host(s))", len(group.Hosts)),
+				majority.Output, group.Output,
+			)
+			if output == "" {
+				output = fmt.Sprintf("(saída igual à da maioria; exit code %d em vez de %d)\n", group.ExitCode, majority.ExitCode)
+			}
+		}
+		if output != "" {
+			fmt.Print(output)
+			if output[len(output)-1] != '\n' {
+				fmt.Println()
+			}
+		}
+		fmt.Println()
+	}
+
+	// Hosts com erro são exibidos individualmente (a mensagem costuma conter dados do host)
+	for _, result := range errored {
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("❌ Host: %s (Exit Code: %d)\n", result.Host, result.ExitCode)
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		if result.Output != "" {
+			fmt.Print(result.Output)
+			if result.Output[len(result.Output)-1] != '\n' {
+				fmt.Println()
+			}
+		}
+		fmt.Printf("Erro (%s): %s\n", result.FailureClass, result.Error)
+		fmt.Println()
+	}
+
+	if len(skipped) > 0 {
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("⏭️  %d host(s) não executado(s): %s\n", len(skipped), foldHosts(skipped))
+	}
+
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("🧩 Grupos: %d saída(s) distinta(s), %d host(s) com erro\n", len(groups), len(errored))
+	fmt.Printf("📊 Resumo: %d sucesso(s), %d falha(s), %d total | ⏱️  Tempo: %.2fs\n", successCount, failureCount, len(results), duration.Seconds())
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alexeiev/sshControl/config"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// HostsImportOptions são as opções de sc hosts import
+type HostsImportOptions struct {
+	Format  string            // csv ou json (vazio = pela extensão do arquivo)
+	Mapping map[string]string // Campo do host -> coluna do arquivo (--map)
+	Tags    config.TagMergeStrategy
+	AddTags []string // Tags adicionadas a todos os hosts importados (--tag)
+	DryRun  bool
+	Yes     bool // Aplica sem pedir confirmação
+}
+
+// ImportHosts importa hosts de um arquivo CSV ou JSON ("-" lê do stdin)
+// Exibe o resumo e o diff da seção hosts do config.yaml antes de gravar via SaveConfig
+func ImportHosts(cfg *config.ConfigFile, configPath string, filename string, opts HostsImportOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+		if format != "csv" && format != "json" {
+			return fmt.Errorf("não foi possível detectar o formato de '%s' (use --format csv ou --format json)", filename)
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	records, err := config.ReadHostRecords(input, format, opts.Mapping)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range records {
+		for _, tag := range opts.AddTags {
+			if !slices.Contains(records[i].Host.Tags, tag) {
+				records[i].Host.Tags = append(records[i].Host.Tags, tag)
+			}
+		}
+	}
+
+	before, err := staticHostsYAML(cfg)
+	if err != nil {
+		return err
+	}
+	result := cfg.MergeHosts(records, opts.Tags)
+	after, err := staticHostsYAML(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📥 %s: %d host(s) lido(s)\n", filename, len(records))
+	fmt.Printf("   %d novo(s), %d atualizado(s), %d sem alteração (estratégia: %s)\n",
+		len(result.Added), len(result.Updated), len(result.Unchanged), opts.Tags)
+	for _, name := range result.Updated {
+		fmt.Printf("   ~ %s\n", name)
+	}
+	if len(result.Conflicts) > 0 {
+		fmt.Printf("   %d campo(s) divergente(s) mantido(s) (use --tags replace para aplicar os valores importados):\n", len(result.Conflicts))
+		for _, conflict := range result.Conflicts {
+			fmt.Printf("   ≠ %s\n", conflict)
+		}
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", warning)
+	}
+
+	diff := unifiedDiff("config.yaml (atual)", "config.yaml (com importação)", before, after)
+	if diff == "" {
+		fmt.Println("ℹ️  Nenhuma alteração no config.yaml")
+		return nil
+	}
+	fmt.Println()
+	fmt.Print(diff)
+	fmt.Println()
+
+	if opts.DryRun {
+		fmt.Println("ℹ️  --dry-run: nada foi gravado")
+		return nil
+	}
+
+	// Confirma no terminal (com o arquivo lido do stdin, não há como perguntar)
+	if !opts.Yes && filename != "-" && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print("Aplicar as alterações? [s/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "s" && response != "S" {
+			fmt.Println("Importação cancelada.")
+			return nil
+		}
+	}
+
+	if err := cfg.SaveConfig(configPath); err != nil {
+		return fmt.Errorf("erro ao salvar %s: %w", configPath, err)
+	}
+	fmt.Printf("✅ config.yaml atualizado: %d host(s) adicionado(s), %d atualizado(s)\n", len(result.Added), len(result.Updated))
+	return nil
+}
+
+// staticHostsYAML retorna a seção hosts como seria gravada por SaveConfig (sem hosts do inventário dinâmico)
+func staticHostsYAML(cfg *config.ConfigFile) (string, error) {
+	var hosts []config.Host
+	for _, host := range cfg.Hosts {
+		if host.Source() == "" {
+			hosts = append(hosts, host)
+		}
+	}
+	data, err := yaml.Marshal(map[string][]config.Host{"hosts": hosts})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -50,9 +51,7 @@ var (
 
 // hostItem implementa list.Item para trabalhar com bubbles/list
 type hostItem struct {
-	host          config.Host
-	sshKeys       []string
-	effectiveUser string
+	host config.Host
 }
 
 func (i hostItem) FilterValue() string {
@@ -78,20 +77,18 @@ func (i hostItem) Description() string {
 
 // model representa o estado da aplicação
 type model struct {
-	list            list.Model
-	filter          textinput.Model
-	filterActive    bool
-	cfg             *config.ConfigFile
-	selectedUser    *config.User
-	jumpHost        *config.JumpHost
-	allItems        []list.Item
-	selectedHost    *config.Host
-	selectedSSHKeys []string
-	effectiveUser   string
-	version         string
-	quitting        bool
-	proxyEnabled    bool
-	verbose         bool
+	list         list.Model
+	filter       textinput.Model
+	filterActive bool
+	cfg          *config.ConfigFile
+	selectedUser *config.User
+	jumpHost     *config.JumpHost
+	allItems     []list.Item
+	selectedHost *config.Host
+	version      string
+	quitting     bool
+	proxyEnabled bool
+	verbose      bool
 }
 
 // ShowInteractive exibe o menu interativo usando bubbletea
@@ -100,6 +97,10 @@ func ShowInteractive(cfg *config.ConfigFile, selectedUser *config.User, jumpHost
 	tuiHosts := cfg.GetHostsForTUI()
 
 	if len(tuiHosts) == 0 {
+		if ctx := cfg.Context(); ctx != nil && ctx.Filter != "" {
+			fmt.Printf("Nenhum host do contexto '%s' (filtro %s)\n", ctx.Name, ctx.Filter)
+			return
+		}
 		fmt.Println("Nenhum host configurado no arquivo config.yaml")
 		return
 	}
@@ -117,18 +118,8 @@ func ShowInteractive(cfg *config.ConfigFile, selectedUser *config.User, jumpHost
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
-	// Obtém as chaves SSH do usuário efetivo
-	var sshKeys []string
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
-	}
-
 	for i, h := range tuiHosts {
-		items[i] = hostItem{
-			host:          h,
-			sshKeys:       sshKeys,
-			effectiveUser: effectiveUser.Name,
-		}
+		items[i] = hostItem{host: h}
 	}
 
 	// Cria o filtro de texto
@@ -164,27 +155,39 @@ func ShowInteractive(cfg *config.ConfigFile, selectedUser *config.User, jumpHost
 
 	// Conecta ao host selecionado
 	if m, ok := finalModel.(model); ok && m.selectedHost != nil {
+		// O destino (e as rules, que podem resolver DNS) só é resolvido para o host selecionado
+		// Sem -u, cada host pode definir o seu usuário (campo user ou rules)
+		ctx := context.Background()
+		target := configHostTarget(ctx, m.cfg, m.selectedHost, m.selectedUser)
+
+		// Sem -j, usa o jump host definido no host (campo jump) ou nas rules
+		jumpHost, err := connectionJumpHost(ctx, m.cfg, target, m.jumpHost)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Busca as chaves SSH do jump host se estiver usando jump host
 		var jumpHostSSHKeys []string
-		if m.jumpHost != nil {
-			jumpHostSSHKeys = m.cfg.GetJumpHostSSHKeys(m.jumpHost)
+		if jumpHost != nil {
+			jumpHostSSHKeys = m.cfg.GetJumpHostSSHKeys(jumpHost)
 		}
 
 		// Obtém configuração de proxy
 		proxyAddress, proxyPort, proxyConfigured := m.cfg.Config.GetProxyConfig()
-		proxyActive := m.proxyEnabled && proxyConfigured
+		proxyActive := target.proxy(m.proxyEnabled) && proxyConfigured
 
-		if !proxyActive && m.proxyEnabled {
+		if !proxyConfigured && m.proxyEnabled {
 			fmt.Fprintf(os.Stderr, "⚠️  Aviso: Proxy solicitado mas não configurado no config.yaml\n")
 		}
 
 		sshConn := NewSSHConnection(
-			m.effectiveUser,
-			m.selectedHost.Host,
-			m.selectedHost.Port,
-			m.selectedSSHKeys,
+			target.User,
+			target.Hostname,
+			target.Port,
+			target.SSHKeys,
 			"", // Senha vazia - será pedida interativamente se necessário
-			m.jumpHost,
+			jumpHost,
 			jumpHostSSHKeys,
 			"", // Modo interativo não executa comandos remotos
 			proxyActive,
@@ -192,6 +195,7 @@ func ShowInteractive(cfg *config.ConfigFile, selectedUser *config.User, jumpHost
 			proxyPort,
 			m.verbose,
 		)
+		target.configure(m.cfg, sshConn, Timeouts{Connect: m.cfg.Config.ConnectTimeout})
 
 		if err := sshConn.Connect(); err != nil {
 			fmt.Fprintf(os.Stderr, "\n❌ Erro na conexão SSH: %v\n", err)
@@ -240,8 +244,6 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			if i, ok := m.list.SelectedItem().(hostItem); ok {
 				m.selectedHost = &i.host
-				m.selectedSSHKeys = i.sshKeys
-				m.effectiveUser = i.effectiveUser
 				return m, tea.Quit
 			}
 		}
@@ -286,10 +288,17 @@ func (m model) View() string {
 		proxyStatus = infoStyle.Render("Not Configured")
 	}
 
+	// Contexto ativo (sc context), exibido apenas quando há um
+	contextInfo := ""
+	if ctx := m.cfg.Context(); ctx != nil {
+		contextInfo = "  |  Context: " + userInfoStyle.Render(ctx.Name)
+	}
+
 	banner := fmt.Sprintf(
-		"%s %s  |  SSH User: %s  |  Jump Host: %s  |  Proxy: %s  |  %s",
+		"%s %s%s  |  SSH User: %s  |  Jump Host: %s  |  Proxy: %s  |  %s",
 		titleStyle.Render("🚀 SSH Control"),
 		infoStyle.Render(fmt.Sprintf("%s", m.version)),
+		contextInfo,
 		sshUserInfo,
 		jumpHostStatus,
 		proxyStatus,
@@ -308,6 +317,8 @@ func (m model) View() string {
 }
 
 // applyFilter filtra os items baseado no texto digitado
+// Textos com sintaxe de seletor (@tag, web-*, ~regex, @web&@prod...) usam config.ParseSelector;
+// os demais buscam por substring no nome, endereço e tags
 func (m *model) applyFilter() {
 	filterText := strings.ToLower(m.filter.Value())
 	if filterText == "" {
@@ -316,6 +327,21 @@ func (m *model) applyFilter() {
 	}
 
 	var filtered []list.Item
+	if config.IsSelectorExpression(m.filter.Value()) {
+		// Enquanto o seletor está incompleto ou inválido, mantém a lista atual
+		selector, err := config.ParseSelector(strings.Fields(m.filter.Value())...)
+		if err != nil {
+			return
+		}
+		for _, item := range m.allItems {
+			if host, ok := item.(hostItem); ok && selector.Matches(&host.host) {
+				filtered = append(filtered, item)
+			}
+		}
+		m.list.SetItems(filtered)
+		return
+	}
+
 	for _, item := range m.allItems {
 		if strings.Contains(strings.ToLower(item.FilterValue()), filterText) {
 			filtered = append(filtered, item)
@@ -2,66 +2,98 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/alexeiev/sshControl/config"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/term"
 )
 
 // HostResult armazena o resultado da execução em um host
 type HostResult struct {
-	Host           string
-	Success        bool
-	Output         string
-	Error          string
-	ExitCode       int
+	Host             string
+	Success          bool
+	Output           string // Saída combinada (stdout seguido de stderr)
+	Stdout           string
+	Stderr           string
+	Error            string
+	ExitCode         int
+	FailureClass     FailureClass // Classe da falha (connection, auth, command) quando o host falhou
+	StartedAt        time.Time
+	Duration         time.Duration
 	ShouldAutoCreate bool   // Indica se o host deve ser auto-criado
-	Hostname       string // Hostname real para auto-criação
-	Port           int    // Porta para auto-criação
+	Hostname         string // Hostname real para auto-criação
+	Port             int    // Porta para auto-criação
+	Address          string // Endereço que respondeu (apenas para hosts com addresses)
 }
 
-// expandTagsToHosts expande argumentos com @tag para lista de hosts
-// Retorna a lista expandida de hosts e as tags encontradas
-func expandTagsToHosts(cfg *config.ConfigFile, hostArgs []string) ([]string, []string) {
-	var expandedHosts []string
-	var tagsFound []string
-	hostSet := make(map[string]bool) // Para evitar duplicatas
-
-	for _, arg := range hostArgs {
-		if strings.HasPrefix(arg, "@") {
-			// É uma tag - expande para todos os hosts com essa tag
-			tag := strings.TrimPrefix(arg, "@")
-			tagsFound = append(tagsFound, tag)
-			hosts := cfg.FindHostsByTag(tag)
-			if len(hosts) == 0 {
-				fmt.Fprintf(os.Stderr, "⚠️  Aviso: Nenhum host encontrado com a tag '%s'\n", tag)
-				continue
-			}
-			for _, host := range hosts {
-				if !hostSet[host.Name] {
-					hostSet[host.Name] = true
-					expandedHosts = append(expandedHosts, host.Name)
-				}
-			}
-		} else {
-			// É um host normal
-			if !hostSet[arg] {
-				hostSet[arg] = true
-				expandedHosts = append(expandedHosts, arg)
-			}
-		}
-	}
+// Failed indica se o host falhou (erro de conexão/execução ou exit code diferente de zero)
+func (r HostResult) Failed() bool {
+	return !r.Success || r.ExitCode != 0
+}
+
+// MultipleOptions agrupa as opções da execução em múltiplos hosts
+type MultipleOptions struct {
+	Rollout  RolloutOptions // Controle de concorrência e estratégia de rollout
+	Stream   bool           // Exibe a saída de cada host em tempo real, prefixada pelo nome do host
+	Group    bool           // Exibe cada saída distinta uma única vez, com os hosts que a produziram
+	Diff     bool           // Com Group, exibe os grupos minoritários como diff em relação à maioria
+	Output   OutputFormat   // Formato dos resultados (text, json, ndjson, yaml, csv)
+	Timeouts Timeouts       // Limites de tempo de conexão e de execução em cada host
+	Stdin    io.Reader      // Entrada replicada para o stdin do comando em cada host (nil = sem stdin)
+	Sudo     bool           // Executa o comando via sudo em cada host
+	SudoUser string         // Usuário alvo do sudo (vazio = root)
+	Script   *Script        // Script local executado no lugar do comando (sc run)
+	Template bool           // Renderiza o comando como template por host (-T)
+}
+
+// multiRun armazena os parâmetros compartilhados por todos os hosts de uma execução múltipla
+type multiRun struct {
+	cfg          *config.ConfigFile
+	selectedUser *config.User // Usuário de -u (nil = user do host ou default_user)
+	jumpHost     *config.JumpHost
+	password     string
+	command      string
+	proxyEnabled bool   // -p (ou contexto); proxy: true nas rules também habilita
+	proxyAddress string // Vazio se o proxy não estiver configurado
+	proxyPort    int
+	askPassword  bool
+	verbose      bool
+	streamer     *streamPrinter // nil quando o modo --stream não está ativo
+	timeouts     Timeouts
+	stdin        *stdinBroadcaster // nil quando não há stdin a replicar
+	sudo         bool
+	sudoUser     string
+	sudoPassword string
+	script       *Script
+	template     bool // Renderiza o comando por host (-T)
+}
 
-	return expandedHosts, tagsFound
+// expandHostSelectors expande seletores de hosts (@tag, globs, intervalos, ~regex, &, ! - ver config.ParseSelector)
+// Retorna a lista expandida de hosts e as tags citadas
+func expandHostSelectors(cfg *config.ConfigFile, hostArgs []string) ([]string, []string, error) {
+	selector, err := config.ParseSelector(hostArgs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	hosts, warnings := selector.Select(cfg)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", warning)
+	}
+	return hosts, selector.Tags(), nil
 }
 
 // ConnectMultiple executa um comando em múltiplos hosts em paralelo
-func ConnectMultiple(cfg *config.ConfigFile, configPath string, hostArgs []string, selectedUser *config.User, jumpHost *config.JumpHost, command string, proxyEnabled bool, askPassword bool, verbose bool) {
+// Retorna false se algum host falhou ou não foi executado
+// O cancelamento de ctx interrompe as conexões e encerra os comandos remotos em andamento
+func ConnectMultiple(ctx context.Context, cfg *config.ConfigFile, configPath string, hostArgs []string, selectedUser *config.User, jumpHost *config.JumpHost, command string, proxyEnabled bool, askPassword bool, verbose bool, opts MultipleOptions) bool {
+	useStructuredOutput(opts.Output)
+
 	// Determina o usuário efetivo
 	effectiveUser := cfg.GetEffectiveUser(selectedUser)
 	if effectiveUser == nil {
@@ -72,31 +104,59 @@ func ConnectMultiple(cfg *config.ConfigFile, configPath string, hostArgs []strin
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
-	// Expande tags para hosts
-	expandedHosts, tagsFound := expandTagsToHosts(cfg, hostArgs)
+	// Expande os seletores (@tag, globs, intervalos...) para hosts
+	expandedHosts, tagsFound, err := expandHostSelectors(cfg, hostArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
 	if len(expandedHosts) == 0 {
 		fmt.Fprintf(os.Stderr, "Erro: Nenhum host válido especificado\n")
 		os.Exit(1)
 	}
 	hostArgs = expandedHosts
 
+	// Com -T, o comando é um template ({{.Name}}, {{.Vars.role}}) renderizado por host
+	if opts.Template && opts.Script == nil {
+		if err := ValidateTemplate(command); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: template inválido no comando: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Obtém configuração de proxy uma vez
 	proxyAddress, proxyPort, proxyConfigured := cfg.Config.GetProxyConfig()
-	proxyActive := proxyEnabled && proxyConfigured
-
-	if !proxyActive && proxyEnabled {
+	if !proxyConfigured && proxyEnabled {
 		fmt.Fprintf(os.Stderr, "⚠️  Aviso: Proxy solicitado mas não configurado no config.yaml\n\n")
 	}
 
-	fmt.Println()
+	fmt.Fprintln(infoOut)
 	if len(tagsFound) > 0 {
-		fmt.Printf("🏷️  Tags: %s\n", strings.Join(tagsFound, ", "))
+		fmt.Fprintf(infoOut, "🏷️  Tags: %s\n", strings.Join(tagsFound, ", "))
+	}
+	if opts.Script != nil {
+		fmt.Fprintf(infoOut, "🚀 Executando script em %d host(s): %s\n", len(hostArgs), command)
+		fmt.Fprintf(infoOut, "   Interpretador: %s\n", opts.Script.Interpreter)
+	} else {
+		fmt.Fprintf(infoOut, "🚀 Executando comando em %d host(s): %s\n", len(hostArgs), command)
 	}
-	fmt.Printf("🚀 Executando comando em %d host(s): %s\n", len(hostArgs), command)
 	if jumpHost != nil {
-		fmt.Printf("   via Jump Host: %s (%s@%s:%d)\n", jumpHost.Name, jumpHost.User, jumpHost.Host, jumpHost.Port)
+		fmt.Fprintf(infoOut, "   via Jump Host: %s (%s@%s:%d)\n", jumpHost.Name, jumpHost.User, jumpHost.Host, jumpHost.Port)
 	}
-	fmt.Println()
+	if summary := opts.Rollout.describe(); summary != "" {
+		fmt.Fprintf(infoOut, "   Rollout: %s\n", summary)
+	}
+	if opts.Stdin != nil {
+		fmt.Fprintln(infoOut, "   Stdin: replicado para todos os hosts")
+	}
+	if opts.Sudo {
+		sudoTarget := opts.SudoUser
+		if sudoTarget == "" {
+			sudoTarget = "root"
+		}
+		fmt.Fprintf(infoOut, "   Sudo: como %s\n", sudoTarget)
+	}
+	fmt.Fprintln(infoOut)
 
 	// Em modo múltiplos hosts, solicita senha apenas se -a for especificado
 	// Isso evita interrupção em automações/loops
@@ -105,110 +165,167 @@ func ConnectMultiple(cfg *config.ConfigFile, configPath string, hostArgs []strin
 		// Flag -a foi especificada, solicita senha antecipadamente
 		if len(effectiveUser.SSHKeys) == 0 {
 			// Usuário sem chave configurada - senha é obrigatória
-			fmt.Printf("Password for %s (será usada para todos os hosts): ", effectiveUser.Name)
+			fmt.Fprintf(infoOut, "Password for %s (será usada para todos os hosts): ", effectiveUser.Name)
 		} else {
 			// Usuário com chave configurada - senha como fallback
-			fmt.Printf("Password for %s (fallback caso chave SSH falhe, Enter para pular): ", effectiveUser.Name)
+			fmt.Fprintf(infoOut, "Password for %s (fallback caso chave SSH falhe, Enter para pular): ", effectiveUser.Name)
 		}
 
-		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Println()
+		passwordBytes, err := readPassword()
+		fmt.Fprintln(infoOut)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
 			os.Exit(1)
 		}
 		password = string(passwordBytes)
-		fmt.Println()
+		fmt.Fprintln(infoOut)
+	}
+
+	// A senha do sudo é pedida uma única vez; se -a foi usado, a mesma senha é reaproveitada
+	// Sem terminal (CI, cron), segue sem senha: hosts cujo sudo a exigir falham com a classe auth
+	sudoPassword := password
+	if opts.Sudo && sudoPassword == "" && !canPromptPassword() {
+		fmt.Fprintln(infoOut, "ℹ️  Sem terminal para a senha do sudo: executando sem senha (NOPASSWD)")
+	} else if opts.Sudo && sudoPassword == "" {
+		fmt.Fprintf(infoOut, "[sudo] Password for %s (será usada para todos os hosts, Enter se não for necessária): ", effectiveUser.Name)
+		passwordBytes, err := readPassword()
+		fmt.Fprintln(infoOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao ler senha do sudo: %v\n", err)
+			os.Exit(1)
+		}
+		sudoPassword = string(passwordBytes)
+		fmt.Fprintln(infoOut)
 	}
 
 	// Captura o tempo de início
 	startTime := time.Now()
 
-	// Canal para coletar resultados
-	results := make(chan HostResult, len(hostArgs))
-	var wg sync.WaitGroup
-
-	// Executa comando em cada host em paralelo
-	for _, hostArg := range hostArgs {
-		wg.Add(1)
-		go func(hostArg string) {
-			defer wg.Done()
-			result := executeOnHost(cfg, hostArg, effectiveUser, jumpHost, password, command, proxyActive, proxyAddress, proxyPort, askPassword, verbose)
-			results <- result
-		}(hostArg)
+	run := &multiRun{
+		cfg:          cfg,
+		selectedUser: selectedUser,
+		jumpHost:     jumpHost,
+		password:     password,
+		command:      command,
+		proxyEnabled: proxyEnabled,
+		proxyAddress: proxyAddress,
+		proxyPort:    proxyPort,
+		askPassword:  askPassword,
+		verbose:      verbose,
+		timeouts:     opts.Timeouts,
+		sudo:         opts.Sudo,
+		sudoUser:     opts.SudoUser,
+		sudoPassword: sudoPassword,
+		script:       opts.Script,
+		template:     opts.Template,
+	}
+	if opts.Stream {
+		run.streamer = newStreamPrinter(hostArgs)
+	}
+	if opts.Stdin != nil {
+		broadcaster, err := newStdinBroadcaster(opts.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		defer broadcaster.Close()
+		run.stdin = broadcaster
 	}
 
-	// Aguarda todas as goroutines terminarem
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	// Executa comando em cada host respeitando os limites de concorrência e lotes
+	results := make([]HostResult, len(hostArgs))
+	executed := make([]bool, len(hostArgs))
+	skipped := runRollout(hostArgs, opts.Rollout, func(i int, hostArg string) bool {
+		results[i] = run.executeOnHost(ctx, hostArg)
+		executed[i] = true
+		return !results[i].Failed()
+	})
 
-	// Coleta e exibe resultados
+	// Coleta os resultados na ordem em que os hosts foram informados
 	var allResults []HostResult
-	for result := range results {
-		allResults = append(allResults, result)
+	for i, result := range results {
+		if executed[i] {
+			allResults = append(allResults, result)
+		}
 	}
 
 	// Calcula o tempo total de execução
 	duration := time.Since(startTime)
 
-	// Exibe resultados organizados
-	displayResults(allResults, duration)
+	// Exibe resultados organizados (no modo stream a saída já foi exibida, mostra apenas o resumo)
+	switch {
+	case opts.Output.IsStructured():
+		if err := writeHostResults(os.Stdout, opts.Output, command, allResults, skipped, startTime, duration); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao gerar saída %s: %v\n", opts.Output, err)
+		}
+	case opts.Stream:
+		displayStreamSummary(allResults, skipped, duration)
+	case opts.Group || opts.Diff:
+		displayGroupedResults(allResults, skipped, duration, opts.Diff)
+	default:
+		displayResults(allResults, skipped, duration)
+	}
 
 	// Auto-criação de hosts após execução bem-sucedida
 	if cfg.Config.AutoCreate {
 		autoCreateHostsFromResults(cfg, configPath, allResults)
 	}
+
+	if len(skipped) > 0 {
+		return false
+	}
+	for _, result := range allResults {
+		if result.Failed() {
+			return false
+		}
+	}
+	return true
 }
 
 // executeOnHost executa o comando em um único host e retorna o resultado
-func executeOnHost(cfg *config.ConfigFile, hostArg string, effectiveUser *config.User, jumpHost *config.JumpHost, password string, command string, proxyEnabled bool, proxyAddress string, proxyPort int, askPassword bool, verbose bool) HostResult {
-	var hostname string
-	var port int
-	var sshKeys []string
-	var shouldAutoCreate bool
+func (r *multiRun) executeOnHost(ctx context.Context, hostArg string) HostResult {
+	cfg, jumpHost, password := r.cfg, r.jumpHost, r.password
+	startTime := time.Now()
 
-	username := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
+	// Resolve o host (config.yaml ou conexão direta)
+	target, err := resolveHostTarget(ctx, cfg, hostArg, r.selectedUser)
+	if err != nil {
+		return HostResult{
+			Host:         hostArg,
+			Success:      false,
+			Error:        fmt.Sprintf("Formato inválido: %v", err),
+			FailureClass: FailureConnection,
+			StartedAt:    startTime,
+			Duration:     time.Since(startTime),
+		}
 	}
 
-	// Primeiro tenta encontrar no config.yaml
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		// Se não encontrar, tenta parsear como conexão direta
-		host, err := parseDirectConnection(hostArg, effectiveUser)
+	// Renderiza o comando com os dados do host
+	command := r.command
+	if r.template && r.script == nil {
+		command, err = renderTemplate(r.command, hostTemplateData(cfg, hostArg, target))
 		if err != nil {
 			return HostResult{
-				Host:    hostArg,
-				Success: false,
-				Error:   fmt.Sprintf("Formato inválido: %v", err),
+				Host:         hostArg,
+				Success:      false,
+				Error:        fmt.Sprintf("Erro no template do comando: %v", err),
+				FailureClass: FailureCommand,
+				StartedAt:    startTime,
+				Duration:     time.Since(startTime),
 			}
 		}
+	}
 
-		// Se a string incluir um usuário explícito (user@host), usa ele
-		if host.parsedUser != "" && host.parsedUser != effectiveUser.Name {
-			username = host.parsedUser
-			// Tenta obter as chaves SSH desse usuário específico
-			if userFromConfig := cfg.FindUser(username); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				sshKeys = nil
-			}
-		}
-
-		hostname = host.hostname
-		port = host.port
-
-		// Verifica se auto_create está habilitado e se o host não existe pelo endereço
-		if cfg.Config.AutoCreate && cfg.FindHostByAddress(hostname) == nil {
-			shouldAutoCreate = true
+	// Sem -j, usa o jump host definido no host (campo jump) ou nas rules
+	jumpHost, err = connectionJumpHost(ctx, cfg, target, jumpHost)
+	if err != nil {
+		return HostResult{
+			Host:         hostArg,
+			Success:      false,
+			Error:        err.Error(),
+			FailureClass: FailureConnection,
+			StartedAt:    startTime,
+			Duration:     time.Since(startTime),
 		}
 	}
 
@@ -220,54 +337,102 @@ func executeOnHost(cfg *config.ConfigFile, hostArg string, effectiveUser *config
 
 	// Cria a conexão SSH
 	sshConn := NewSSHConnection(
-		username,
-		hostname,
-		port,
-		sshKeys,
+		target.User,
+		target.Hostname,
+		target.Port,
+		target.SSHKeys,
 		password, // Senha pré-fornecida ou vazia
 		jumpHost,
 		jumpHostSSHKeys,
 		command,
-		proxyEnabled,
-		proxyAddress,
-		proxyPort,
-		verbose,
+		target.proxy(r.proxyEnabled) && r.proxyAddress != "",
+		r.proxyAddress,
+		r.proxyPort,
+		r.verbose,
 	)
+	target.configure(cfg, sshConn, r.timeouts)
 
 	// Em modo múltiplos hosts, desabilita prompt interativo de senha
 	// A senha já foi solicitada uma vez antes das conexões paralelas
 	sshConn.InteractivePasswordAllowed = false
+	sshConn.Sudo = r.sudo
+	sshConn.SudoUser = r.sudoUser
+	sshConn.SudoPassword = r.sudoPassword
+	sshConn.Script = r.script
+
+	// Cada host lê o stdin replicado a partir do início, no seu próprio ritmo
+	if r.stdin != nil {
+		stdinReader := r.stdin.newReader()
+		defer stdinReader.Close()
+		sshConn.Stdin = stdinReader
+	}
+
+	// No modo stream, a saída também é enviada em tempo real para o terminal
+	if r.streamer != nil {
+		stdoutWriter, stderrWriter := r.streamer.writers(hostArg)
+		defer stdoutWriter.Flush()
+		defer stderrWriter.Flush()
+		sshConn.StreamStdout = stdoutWriter
+		sshConn.StreamStderr = stderrWriter
+	}
 
 	// Executa o comando e captura a saída
-	output, exitCode, err := sshConn.ExecuteCommandWithOutput()
+	stdout, stderr, exitCode, err := sshConn.ExecuteCommandWithOutput(ctx)
+	output := stdout + stderr
+	duration := time.Since(startTime)
+	address := ""
+	if len(sshConn.Alternates) > 0 {
+		address = sshConn.Address
+	}
 	if err != nil {
 		errorMsg := err.Error()
+		failureClass := failureClassOf(err)
 
 		// Se falhou por autenticação e não foi pedida senha (-a), sugere usar a flag
-		if !askPassword && password == "" && len(sshKeys) == 0 {
+		// (a dica não se aplica a tempo limite excedido, cancelamento nem à senha do sudo)
+		needsHint := !r.askPassword && password == "" && (failureClass == FailureAuth || failureClass == FailureConnection) &&
+			!errors.Is(err, errSudoAuth)
+		if needsHint && len(target.SSHKeys) == 0 {
 			errorMsg += " (DICA: Use a opção -a ou --ask-password para fornecer senha)"
-		} else if !askPassword && password == "" && len(sshKeys) > 0 {
+		} else if needsHint && len(target.SSHKeys) > 0 {
 			// Tem chave configurada mas pode não estar instalada
 			errorMsg += " (DICA: Se a chave SSH não estiver instalada, use -a para fornecer senha)"
 		}
 
 		return HostResult{
-			Host:     hostArg,
-			Success:  false,
-			Output:   output,
-			Error:    errorMsg,
-			ExitCode: exitCode,
+			Host:         hostArg,
+			Success:      false,
+			Output:       output,
+			Stdout:       stdout,
+			Stderr:       stderr,
+			Error:        errorMsg,
+			ExitCode:     exitCode,
+			FailureClass: failureClass,
+			StartedAt:    startTime,
+			Duration:     duration,
+			Address:      address,
 		}
 	}
 
+	failureClass := FailureNone
+	if exitCode != 0 {
+		failureClass = FailureCommand
+	}
+
 	return HostResult{
 		Host:             hostArg,
 		Success:          true,
 		Output:           output,
+		Stdout:           stdout,
+		Stderr:           stderr,
 		ExitCode:         exitCode,
-		ShouldAutoCreate: shouldAutoCreate,
-		Hostname:         hostname,
-		Port:             port,
+		FailureClass:     failureClass,
+		StartedAt:        startTime,
+		Duration:         duration,
+		ShouldAutoCreate: target.shouldAutoCreate(cfg),
+		Hostname:         target.Hostname,
+		Port:             target.Port,
+		Address:          address,
 	}
 }
 
@@ -304,25 +469,25 @@ func autoCreateHostsFromResults(cfg *config.ConfigFile, configPath string, resul
 	}
 
 	// Exibe mensagem informativa
-	fmt.Println()
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("✅ %d host(s) adicionado(s) automaticamente ao config.yaml:\n", len(hostsToCreate))
+	fmt.Fprintln(infoOut)
+	fmt.Fprintln(infoOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(infoOut, "✅ %d host(s) adicionado(s) automaticamente ao config.yaml:\n", len(hostsToCreate))
 	for _, result := range hostsToCreate {
-		fmt.Printf("   - %s (%s:%d) [autocreated]\n", result.Host, result.Hostname, result.Port)
+		fmt.Fprintf(infoOut, "   - %s (%s:%d) [autocreated]\n", result.Host, result.Hostname, result.Port)
 	}
-	fmt.Println()
-	fmt.Println("📝 Finalize a configuração dos hosts editando o arquivo:")
-	fmt.Printf("   %s\n", configPath)
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(infoOut)
+	fmt.Fprintln(infoOut, "📝 Finalize a configuração dos hosts editando o arquivo:")
+	fmt.Fprintf(infoOut, "   %s\n", configPath)
+	fmt.Fprintln(infoOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
 
 // displayResults exibe os resultados de forma organizada
-func displayResults(results []HostResult, duration time.Duration) {
+func displayResults(results []HostResult, skipped []string, duration time.Duration) {
 	successCount := 0
 	failureCount := 0
 
 	for _, result := range results {
-		if result.Success {
+		if !result.Failed() {
 			successCount++
 		} else {
 			failureCount++
@@ -330,11 +495,14 @@ func displayResults(results []HostResult, duration time.Duration) {
 
 		// Cabeçalho do host
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		if result.Success {
+		if !result.Failed() {
 			fmt.Printf("✅ Host: %s (Exit Code: %d)\n", result.Host, result.ExitCode)
 		} else {
 			fmt.Printf("❌ Host: %s (Exit Code: %d)\n", result.Host, result.ExitCode)
 		}
+		if result.Address != "" {
+			fmt.Printf("   ↪️  Conectado por %s\n", result.Address)
+		}
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 		// Exibe a saída
@@ -348,20 +516,27 @@ func displayResults(results []HostResult, duration time.Duration) {
 
 		// Exibe erro se houver
 		if result.Error != "" {
-			fmt.Printf("Erro: %s\n", result.Error)
+			fmt.Printf("Erro (%s): %s\n", result.FailureClass, result.Error)
 		}
 
 		fmt.Println()
 	}
 
+	// Hosts não executados (rollout interrompido)
+	if len(skipped) > 0 {
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("⏭️  %d host(s) não executado(s): %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+
 	// Resumo final
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Printf("📊 Resumo: %d sucesso(s), %d falha(s), %d total | ⏱️  Tempo: %.2fs\n", successCount, failureCount, len(results), duration.Seconds())
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
 
-// ExecuteCommandWithOutput executa um comando remoto e retorna a saída
-func (s *SSHConnection) ExecuteCommandWithOutput() (output string, exitCode int, err error) {
+// ExecuteCommandWithOutput executa um comando remoto e retorna stdout e stderr separadamente
+// Se StreamStdout/StreamStderr estiverem definidos, a saída também é enviada a eles em tempo real
+func (s *SSHConnection) ExecuteCommandWithOutput(ctx context.Context) (stdout string, stderr string, exitCode int, err error) {
 	s.debugLog("Iniciando execução com captura de saída")
 	s.debugLog("Host: %s:%d | Comando: %s", s.Host, s.Port, s.Command)
 
@@ -369,13 +544,13 @@ func (s *SSHConnection) ExecuteCommandWithOutput() (output string, exitCode int,
 	s.debugLog("Criando configuração SSH...")
 	config, err := s.createSSHConfig()
 	if err != nil {
-		return "", -1, fmt.Errorf("erro ao criar configuração SSH: %w", err)
+		return "", "", -1, fmt.Errorf("erro ao criar configuração SSH: %w", err)
 	}
 
 	// Conecta ao host (via Jump Host se necessário)
-	client, err := s.dial(config)
+	client, err := s.dial(ctx, config)
 	if err != nil {
-		return "", -1, fmt.Errorf("erro ao conectar: %w", err)
+		return "", "", -1, withFailureClass(dialFailureClass(err), fmt.Errorf("erro ao conectar: %w", err))
 	}
 	defer client.Close()
 	s.debugLog("Conexão SSH estabelecida com sucesso")
@@ -383,28 +558,38 @@ func (s *SSHConnection) ExecuteCommandWithOutput() (output string, exitCode int,
 	// Tenta instalar a chave pública se necessário (não bloqueia em caso de erro)
 	_ = s.installPublicKeyIfNeeded(client)
 
+	// No modo sc run, o script é enviado pela mesma conexão e removido ao final
+	if s.Script != nil {
+		s.debugLog("Enviando script %s...", s.Script.Name)
+		shared := s.Sudo && s.SudoUser != "" && s.SudoUser != "root"
+		remotePath, err := s.Script.upload(ctx, client, shared)
+		if err != nil {
+			if class := failureClassOf(err); class == FailureCanceled {
+				return "", "", -1, err
+			}
+			return "", "", -1, withFailureClass(FailureCommand, fmt.Errorf("erro ao enviar script: %w", err))
+		}
+		defer s.Script.cleanup(client, remotePath)
+		s.Command = s.Script.command(remotePath)
+		s.debugLog("Script enviado para %s", remotePath)
+	}
+
 	// Cria uma sessão SSH
 	s.debugLog("Criando sessão SSH...")
 	session, err := client.NewSession()
 	if err != nil {
-		return "", -1, fmt.Errorf("erro ao criar sessão: %w", err)
+		return "", "", -1, withFailureClass(FailureConnection, fmt.Errorf("erro ao criar sessão: %w", err))
 	}
 	defer session.Close()
 
-	// Buffers para capturar stdout e stderr
-	var stdout, stderr bytes.Buffer
-	session.Stdout = &stdout
-	session.Stderr = &stderr
+	// Buffers para capturar stdout e stderr (e destinos de streaming, se houver)
+	var stdoutBuf, stderrBuf bytes.Buffer
+	session.Stdout = teeWriter(&stdoutBuf, s.StreamStdout)
+	session.Stderr = teeWriter(&stderrBuf, s.StreamStderr)
 
 	// Executa o comando
 	s.debugLog("Executando comando...")
-	err = session.Run(s.Command)
-
-	// Combina stdout e stderr
-	combinedOutput := stdout.String()
-	if stderr.Len() > 0 {
-		combinedOutput += stderr.String()
-	}
+	err = s.runSession(ctx, client, session)
 
 	// Captura o exit code
 	exitCode = 0
@@ -413,11 +598,23 @@ func (s *SSHConnection) ExecuteCommandWithOutput() (output string, exitCode int,
 			exitCode = exitErr.ExitStatus()
 			s.debugLog("Comando encerrado com exit code: %d", exitCode)
 			// Se temos um exit code, não é um erro de conexão
-			return combinedOutput, exitCode, nil
+			return stdoutBuf.String(), stderrBuf.String(), exitCode, nil
+		}
+		// Tempo limite, cancelamento e senha do sudo recusada já vêm classificados
+		if class := failureClassOf(err); class == FailureTimeout || class == FailureCanceled || class == FailureAuth {
+			return stdoutBuf.String(), stderrBuf.String(), -1, err
 		}
-		return combinedOutput, -1, fmt.Errorf("erro ao executar comando: %w", err)
+		return stdoutBuf.String(), stderrBuf.String(), -1, withFailureClass(FailureCommand, fmt.Errorf("erro ao executar comando: %w", err))
 	}
 
 	s.debugLog("Comando executado com sucesso (exit code: 0)")
-	return combinedOutput, exitCode, nil
+	return stdoutBuf.String(), stderrBuf.String(), exitCode, nil
+}
+
+// teeWriter retorna um writer que escreve no buffer e, se definido, também no destino extra
+func teeWriter(buf io.Writer, extra io.Writer) io.Writer {
+	if extra == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, extra)
 }
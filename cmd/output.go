@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat define o formato de saída dos resultados
+type OutputFormat string
+
+const (
+	OutputText   OutputFormat = "text"
+	OutputJSON   OutputFormat = "json"
+	OutputNDJSON OutputFormat = "ndjson"
+	OutputYAML   OutputFormat = "yaml"
+	OutputCSV    OutputFormat = "csv"
+)
+
+// ParseOutputFormat valida o valor da flag --output
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch OutputFormat(strings.ToLower(strings.TrimSpace(value))) {
+	case "", OutputText:
+		return OutputText, nil
+	case OutputJSON:
+		return OutputJSON, nil
+	case OutputNDJSON:
+		return OutputNDJSON, nil
+	case OutputYAML:
+		return OutputYAML, nil
+	case OutputCSV:
+		return OutputCSV, nil
+	}
+	return "", fmt.Errorf("formato de saída inválido: '%s' (use text, json, ndjson, yaml ou csv)", value)
+}
+
+// IsStructured indica se o formato é legível por máquina
+func (f OutputFormat) IsStructured() bool {
+	return f != "" && f != OutputText
+}
+
+// infoOut recebe mensagens informativas (cabeçalhos, progresso de lotes, avisos).
+// Em formatos estruturados, é redirecionado para stderr para não misturar com os dados.
+var infoOut io.Writer = os.Stdout
+
+// useStructuredOutput redireciona as mensagens informativas quando o formato é estruturado
+func useStructuredOutput(format OutputFormat) {
+	if format.IsStructured() {
+		infoOut = os.Stderr
+	}
+}
+
+// structuredOutput reúne as representações de um resultado para cada formato
+type structuredOutput struct {
+	Document any        // Documento completo (json/yaml)
+	Records  []any      // Um objeto por linha (ndjson)
+	Header   []string   // Cabeçalho (csv)
+	Rows     [][]string // Linhas (csv)
+}
+
+// write escreve a saída no formato solicitado
+func (o structuredOutput) write(w io.Writer, format OutputFormat) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(o.Document)
+	case OutputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, record := range o.Records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(o.Document); err != nil {
+			return err
+		}
+		return enc.Close()
+	case OutputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(o.Header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(o.Rows); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("formato de saída não suportado: %s", format)
+}
+
+// Status de um host nos relatórios estruturados
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// hostResultRecord é a representação estruturada de um HostResult
+type hostResultRecord struct {
+	Host       string    `json:"host" yaml:"host"`
+	Address    string    `json:"address,omitempty" yaml:"address,omitempty"`
+	Status     string    `json:"status" yaml:"status"`
+	ExitCode   int       `json:"exit_code" yaml:"exit_code"`
+	Stdout     string    `json:"stdout" yaml:"stdout"`
+	Stderr     string    `json:"stderr" yaml:"stderr"`
+	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty" yaml:"error_class,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero" yaml:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitzero" yaml:"finished_at,omitempty"`
+	DurationMs int64     `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// runSummary é o resumo de uma execução em múltiplos hosts
+type runSummary struct {
+	Total      int       `json:"total" yaml:"total"`
+	Succeeded  int       `json:"succeeded" yaml:"succeeded"`
+	Failed     int       `json:"failed" yaml:"failed"`
+	Skipped    int       `json:"skipped" yaml:"skipped"`
+	StartedAt  time.Time `json:"started_at" yaml:"started_at"`
+	FinishedAt time.Time `json:"finished_at" yaml:"finished_at"`
+	DurationMs int64     `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// hostResultsDocument é o documento completo de uma execução em múltiplos hosts
+type hostResultsDocument struct {
+	Command string             `json:"command" yaml:"command"`
+	Results []hostResultRecord `json:"results" yaml:"results"`
+	Summary runSummary         `json:"summary" yaml:"summary"`
+}
+
+// writeHostResults escreve os resultados da execução em formato estruturado
+func writeHostResults(w io.Writer, format OutputFormat, command string, results []HostResult, skipped []string, startTime time.Time, duration time.Duration) error {
+	doc := hostResultsDocument{
+		Command: command,
+		Summary: runSummary{
+			Total:      len(results) + len(skipped),
+			Skipped:    len(skipped),
+			StartedAt:  startTime,
+			FinishedAt: startTime.Add(duration),
+			DurationMs: duration.Milliseconds(),
+		},
+	}
+
+	for _, result := range results {
+		status := statusOK
+		if result.Failed() {
+			status = statusFailed
+			doc.Summary.Failed++
+		} else {
+			doc.Summary.Succeeded++
+		}
+		doc.Results = append(doc.Results, hostResultRecord{
+			Host:       result.Host,
+			Address:    result.Address,
+			Status:     status,
+			ExitCode:   result.ExitCode,
+			Stdout:     result.Stdout,
+			Stderr:     result.Stderr,
+			Error:      result.Error,
+			ErrorClass: string(result.FailureClass),
+			StartedAt:  result.StartedAt,
+			FinishedAt: result.StartedAt.Add(result.Duration),
+			DurationMs: result.Duration.Milliseconds(),
+		})
+	}
+	for _, host := range skipped {
+		doc.Results = append(doc.Results, hostResultRecord{Host: host, Status: statusSkipped, ExitCode: -1})
+	}
+
+	out := structuredOutput{
+		Document: doc,
+		Header:   []string{"host", "address", "status", "exit_code", "error_class", "error", "started_at", "finished_at", "duration_ms", "stdout", "stderr"},
+	}
+	for _, record := range doc.Results {
+		out.Records = append(out.Records, record)
+		out.Rows = append(out.Rows, []string{
+			record.Host,
+			record.Address,
+			record.Status,
+			strconv.Itoa(record.ExitCode),
+			record.ErrorClass,
+			record.Error,
+			formatTimestamp(record.StartedAt),
+			formatTimestamp(record.FinishedAt),
+			strconv.FormatInt(record.DurationMs, 10),
+			record.Stdout,
+			record.Stderr,
+		})
+	}
+
+	return out.write(w, format)
+}
+
+// transferResultRecord é a representação estruturada de um TransferResult
+type transferResultRecord struct {
+	Host       string    `json:"host" yaml:"host"`
+	Status     string    `json:"status" yaml:"status"`
+	File       string    `json:"file,omitempty" yaml:"file,omitempty"`
+	Bytes      int64     `json:"bytes" yaml:"bytes"`
+	Error      string    `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty" yaml:"error_class,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero" yaml:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitzero" yaml:"finished_at,omitempty"`
+	DurationMs int64     `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// transferResultsDocument é o documento completo de uma transferência em múltiplos hosts
+type transferResultsDocument struct {
+	Results []transferResultRecord `json:"results" yaml:"results"`
+	Summary runSummary             `json:"summary" yaml:"summary"`
+}
+
+// WriteTransferResults escreve os resultados das transferências em formato estruturado
+func WriteTransferResults(w io.Writer, format OutputFormat, results []TransferResult, skipped []string, startTime time.Time, duration time.Duration) error {
+	doc := transferResultsDocument{
+		Summary: runSummary{
+			Total:      len(results) + len(skipped),
+			Skipped:    len(skipped),
+			StartedAt:  startTime,
+			FinishedAt: startTime.Add(duration),
+			DurationMs: duration.Milliseconds(),
+		},
+	}
+
+	for _, result := range results {
+		status := statusOK
+		if !result.Success {
+			status = statusFailed
+			doc.Summary.Failed++
+		} else {
+			doc.Summary.Succeeded++
+		}
+		doc.Results = append(doc.Results, transferResultRecord{
+			Host:       result.Host,
+			Status:     status,
+			File:       result.FilePath,
+			Bytes:      result.BytesSent,
+			Error:      result.Error,
+			ErrorClass: string(result.FailureClass),
+			StartedAt:  result.StartedAt,
+			FinishedAt: result.StartedAt.Add(result.Duration),
+			DurationMs: result.Duration.Milliseconds(),
+		})
+	}
+	for _, host := range skipped {
+		doc.Results = append(doc.Results, transferResultRecord{Host: host, Status: statusSkipped})
+	}
+
+	out := structuredOutput{
+		Document: doc,
+		Header:   []string{"host", "status", "file", "bytes", "error_class", "error", "started_at", "finished_at", "duration_ms"},
+	}
+	for _, record := range doc.Results {
+		out.Records = append(out.Records, record)
+		out.Rows = append(out.Rows, []string{
+			record.Host,
+			record.Status,
+			record.File,
+			strconv.FormatInt(record.Bytes, 10),
+			record.ErrorClass,
+			record.Error,
+			formatTimestamp(record.StartedAt),
+			formatTimestamp(record.FinishedAt),
+			strconv.FormatInt(record.DurationMs, 10),
+		})
+	}
+
+	return out.write(w, format)
+}
+
+// serverRecord é a representação estruturada de um host ou jump host na listagem
+type serverRecord struct {
+	Kind  string   `json:"kind" yaml:"kind"`
+	Index int      `json:"index,omitempty" yaml:"index,omitempty"`
+	Name  string   `json:"name" yaml:"name"`
+	Host  string   `json:"host" yaml:"host"`
+	Port  int      `json:"port,omitempty" yaml:"port,omitempty"` // 0 = padrão (rules ou 22)
+	User  string   `json:"user,omitempty" yaml:"user,omitempty"`
+	Tags  []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	Addresses []config.HostAddress `json:"addresses,omitempty" yaml:"addresses,omitempty"` // Endereços alternativos (aceitos por sc hosts import)
+}
+
+// serverListDocument é o documento completo da listagem de servidores
+type serverListDocument struct {
+	JumpHosts []serverRecord `json:"jump_hosts" yaml:"jump_hosts"`
+	Hosts     []serverRecord `json:"hosts" yaml:"hosts"`
+}
+
+// writeServerList escreve a listagem de jump hosts e hosts em formato estruturado
+func writeServerList(w io.Writer, format OutputFormat, jumpHosts []config.JumpHost, hosts []config.Host) error {
+	doc := serverListDocument{
+		JumpHosts: []serverRecord{},
+		Hosts:     []serverRecord{},
+	}
+	for i, jh := range jumpHosts {
+		doc.JumpHosts = append(doc.JumpHosts, serverRecord{Kind: "jump_host", Index: i + 1, Name: jh.Name, Host: jh.Host, Port: jh.Port, User: jh.User})
+	}
+	for _, h := range hosts {
+		doc.Hosts = append(doc.Hosts, serverRecord{Kind: "host", Name: h.Name, Host: h.Host, Port: h.Port, User: h.User, Tags: h.Tags, Addresses: h.Addresses})
+	}
+
+	out := structuredOutput{
+		Document: doc,
+		Header:   []string{"kind", "index", "name", "host", "port", "user", "tags", "addresses"},
+	}
+	for _, record := range append(doc.JumpHosts, doc.Hosts...) {
+		out.Records = append(out.Records, record)
+		index, port := "", ""
+		if record.Index > 0 {
+			index = strconv.Itoa(record.Index)
+		}
+		if record.Port > 0 {
+			port = strconv.Itoa(record.Port)
+		}
+		out.Rows = append(out.Rows, []string{
+			record.Kind,
+			index,
+			record.Name,
+			record.Host,
+			port,
+			record.User,
+			strings.Join(record.Tags, ";"),
+			config.FormatHostAddresses(record.Addresses),
+		})
+	}
+
+	return out.write(w, format)
+}
+
+// formatTimestamp formata um horário em RFC3339 (vazio se não definido)
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+)
+
+// passwordCommandTimeout limita cada execução de password_command (inclui um eventual prompt de desbloqueio)
+const passwordCommandTimeout = 2 * time.Minute
+
+// passwordCommands guarda a execução de cada password_command: cada comando roda no máximo uma vez
+// por execução, mesmo com vários hosts conectando em paralelo (ConnectMultiple, UploadMultiple).
+// A trava protege apenas o mapa; quem pede um comando já em execução aguarda o resultado dele.
+var passwordCommands struct {
+	mu    sync.Mutex
+	calls map[string]*passwordCommandCall
+}
+
+// passwordCommandCall é a execução (em andamento ou concluída) de um password_command
+type passwordCommandCall struct {
+	done     chan struct{} // Fechado quando a execução termina
+	password string
+	err      error
+}
+
+// sourcePassword obtém a senha de um password_command ou do cofre
+func sourcePassword(ctx context.Context, source config.PasswordSource) (string, error) {
+	if source.Command != "" {
+		return commandPassword(ctx, source.Command)
+	}
+	return vaultSecret(source.Ref)
+}
+
+// passwordReady indica se a senha já está disponível sem interação (origem vazia, comando já executado ou cofre aberto)
+func passwordReady(source config.PasswordSource) bool {
+	if source.Command != "" {
+		passwordCommands.mu.Lock()
+		call, ok := passwordCommands.calls[source.Command]
+		passwordCommands.mu.Unlock()
+		if !ok {
+			return false
+		}
+		select {
+		case <-call.done:
+			return true
+		default:
+			return false
+		}
+	}
+	return source.Ref == "" || vaultOpen()
+}
+
+// describePasswordSource descreve a origem da senha para o modo debug
+func describePasswordSource(source config.PasswordSource) string {
+	if source.Command != "" {
+		return "password_command"
+	}
+	return fmt.Sprintf("cofre, password_ref: %s", source.Ref)
+}
+
+// commandPassword executa o password_command (uma única vez) e retorna a primeira linha da saída
+// O stderr do comando vai para o terminal e o stdin vem de /dev/tty, permitindo prompts de desbloqueio
+// (ex: senha mestra do gerenciador) sem consumir o stdin replicado para os hosts
+// Uma execução interrompida pelo contexto de quem a iniciou não é guardada: a próxima chamada executa o comando novamente
+func commandPassword(ctx context.Context, command string) (string, error) {
+	for {
+		passwordCommands.mu.Lock()
+		call, running := passwordCommands.calls[command]
+		if !running {
+			if passwordCommands.calls == nil {
+				passwordCommands.calls = make(map[string]*passwordCommandCall)
+			}
+			call = &passwordCommandCall{done: make(chan struct{})}
+			passwordCommands.calls[command] = call
+		}
+		passwordCommands.mu.Unlock()
+
+		if !running {
+			call.password, call.err = runPasswordCommand(ctx, command)
+			if ctx.Err() != nil {
+				passwordCommands.mu.Lock()
+				delete(passwordCommands.calls, command)
+				passwordCommands.mu.Unlock()
+			}
+			close(call.done)
+			return call.password, call.err
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", fmt.Errorf("password_command interrompido: %w", ctx.Err())
+		}
+		passwordCommands.mu.Lock()
+		current := passwordCommands.calls[command]
+		passwordCommands.mu.Unlock()
+		// Se a execução aguardada foi interrompida por outra conexão, o comando é executado novamente
+		if current == call {
+			return call.password, call.err
+		}
+	}
+}
+
+// runPasswordCommand executa o comando via sh -c (limitado a passwordCommandTimeout) e extrai a senha da saída
+func runPasswordCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Processos filhos que herdaram a saída não prendem a espera após o encerramento do comando
+	cmd.WaitDelay = time.Second
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		defer tty.Close()
+		cmd.Stdin = tty
+	}
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("password_command excedeu o tempo limite de %s", passwordCommandTimeout)
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("password_command interrompido: %w", ctx.Err())
+		}
+		return "", fmt.Errorf("password_command falhou: %w", err)
+	}
+	password, _, _ := strings.Cut(stdout.String(), "\n")
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("password_command não retornou uma senha")
+	}
+	return password, nil
+}
+
+// UsePasswordSources configura a origem da senha (password_command ou password_ref) do host e do jump host
+// host é o host do config.yaml (nil para conexões diretas, que usam a do usuário)
+func (s *SSHConnection) UsePasswordSources(cfg *config.ConfigFile, host *config.Host) {
+	s.PasswordSource = cfg.HostPasswordSource(host, s.User)
+	s.JumpHostPasswordSource = cfg.JumpHostPasswordSource(s.JumpHost)
+}
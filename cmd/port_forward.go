@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -45,7 +46,8 @@ func NewPortForwardSession(sshConn *SSHConnection, forward PortForward) *PortFor
 }
 
 // Start inicia o port forwarding
-func (pf *PortForwardSession) Start() error {
+// A sessão é encerrada com Ctrl+C ou quando ctx for cancelado
+func (pf *PortForwardSession) Start(ctx context.Context) error {
 	// Exibe informações de conexão
 	fmt.Println()
 	fmt.Println("🔗 Conectando...")
@@ -62,7 +64,7 @@ func (pf *PortForwardSession) Start() error {
 	}
 
 	// Conecta ao host (via Jump Host se necessário)
-	client, err := pf.SSHConn.dial(config)
+	client, err := pf.SSHConn.dial(ctx, config)
 	if err != nil {
 		return fmt.Errorf("erro ao conectar: %w", err)
 	}
@@ -97,10 +99,13 @@ func (pf *PortForwardSession) Start() error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Goroutine para aceitar conexões
-	go pf.acceptConnections()
+	go pf.acceptConnections(ctx)
 
-	// Aguarda sinal de interrupção
-	<-sigChan
+	// Aguarda sinal de interrupção ou cancelamento do contexto
+	select {
+	case <-sigChan:
+	case <-ctx.Done():
+	}
 
 	// Encerra
 	close(pf.done)
@@ -110,7 +115,7 @@ func (pf *PortForwardSession) Start() error {
 }
 
 // acceptConnections aceita novas conexões no listener local
-func (pf *PortForwardSession) acceptConnections() {
+func (pf *PortForwardSession) acceptConnections(ctx context.Context) {
 	for {
 		select {
 		case <-pf.done:
@@ -143,12 +148,12 @@ func (pf *PortForwardSession) acceptConnections() {
 		timestamp := time.Now().Format("15:04:05")
 		fmt.Printf("[%s] #%d ✅ Conexão de %s\n", timestamp, connNum, conn.RemoteAddr().String())
 
-		go pf.handleConnection(conn, connNum)
+		go pf.handleConnection(ctx, conn, connNum)
 	}
 }
 
 // handleConnection gerencia uma conexão individual
-func (pf *PortForwardSession) handleConnection(localConn net.Conn, connNum int64) {
+func (pf *PortForwardSession) handleConnection(ctx context.Context, localConn net.Conn, connNum int64) {
 	defer func() {
 		localConn.Close()
 		atomic.AddInt64(&pf.activeConns, -1)
@@ -156,7 +161,10 @@ func (pf *PortForwardSession) handleConnection(localConn net.Conn, connNum int64
 
 	// Conecta ao destino remoto via SSH
 	remoteAddr := fmt.Sprintf("%s:%d", pf.Forward.RemoteHost, pf.Forward.RemotePort)
-	remoteConn, err := pf.client.Dial("tcp", remoteAddr)
+	// Respeita o limite de conexão para não prender o cliente local indefinidamente
+	dialCtx, cancel := withTimeout(ctx, pf.SSHConn.ConnectTimeout)
+	remoteConn, err := pf.client.DialContext(dialCtx, "tcp", remoteAddr)
+	cancel()
 	if err != nil {
 		timestamp := time.Now().Format("15:04:05")
 		fmt.Printf("[%s] #%d ❌ Erro ao conectar ao remoto: %v\n", timestamp, connNum, err)
@@ -220,4 +228,3 @@ func (pf *PortForwardSession) Stop() {
 		pf.client.Close()
 	}
 }
-
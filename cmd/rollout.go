@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RolloutOptions controla como a execução em múltiplos hosts é distribuída
+type RolloutOptions struct {
+	Forks    int           // Número máximo de hosts executando ao mesmo tempo (0 = sem limite)
+	Batch    string        // Tamanho de cada lote: "N" hosts ou "P%" do total (vazio = lote único)
+	Canary   int           // Hosts executados antes dos demais; aborta o rollout se algum falhar
+	FailFast bool          // Não inicia novos hosts após a primeira falha
+	Pause    time.Duration // Pausa entre lotes
+}
+
+// Validate verifica se as opções de rollout são consistentes
+func (o RolloutOptions) Validate() error {
+	if o.Forks < 0 {
+		return fmt.Errorf("--forks deve ser maior ou igual a 0")
+	}
+	if o.Canary < 0 {
+		return fmt.Errorf("--canary deve ser maior ou igual a 0")
+	}
+	if o.Pause < 0 {
+		return fmt.Errorf("--pause não pode ser negativo")
+	}
+	if _, err := o.batchSize(1); err != nil {
+		return err
+	}
+	return nil
+}
+
+// batchSize calcula o tamanho de cada lote para o total de hosts informado
+// Retorna 0 quando não há divisão em lotes
+func (o RolloutOptions) batchSize(total int) (int, error) {
+	batch := strings.TrimSpace(o.Batch)
+	if batch == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(batch, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(batch, "%"))
+		if err != nil || percent < 1 || percent > 100 {
+			return 0, fmt.Errorf("--batch inválido: '%s' (use N ou P%%, ex: 5 ou 25%%)", o.Batch)
+		}
+		// Arredonda para cima para nunca gerar lotes vazios
+		size := (total*percent + 99) / 100
+		if size < 1 {
+			size = 1
+		}
+		return size, nil
+	}
+
+	size, err := strconv.Atoi(batch)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("--batch inválido: '%s' (use N ou P%%, ex: 5 ou 25%%)", o.Batch)
+	}
+	return size, nil
+}
+
+// rolloutBatch representa um lote de hosts a ser executado
+type rolloutBatch struct {
+	indexes []int
+	canary  bool
+}
+
+// planBatches divide os índices dos hosts em lotes (canary primeiro, depois lotes regulares)
+func (o RolloutOptions) planBatches(total int) ([]rolloutBatch, error) {
+	var batches []rolloutBatch
+
+	start := 0
+	if o.Canary > 0 && total > 0 {
+		end := o.Canary
+		if end > total {
+			end = total
+		}
+		batches = append(batches, rolloutBatch{indexes: indexRange(0, end), canary: true})
+		start = end
+	}
+
+	remaining := total - start
+	if remaining <= 0 {
+		return batches, nil
+	}
+
+	size, err := o.batchSize(remaining)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		size = remaining
+	}
+
+	for i := start; i < total; i += size {
+		end := i + size
+		if end > total {
+			end = total
+		}
+		batches = append(batches, rolloutBatch{indexes: indexRange(i, end)})
+	}
+
+	return batches, nil
+}
+
+// indexRange retorna os inteiros de start (inclusivo) até end (exclusivo)
+func indexRange(start, end int) []int {
+	indexes := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// runRollout executa task em cada host respeitando forks, lotes, canary e fail-fast.
+// task recebe o índice e o nome do host e deve retornar true se o host foi bem-sucedido.
+// Retorna os hosts que não foram executados porque o rollout foi interrompido.
+func runRollout(hosts []string, opts RolloutOptions, task func(index int, host string) bool) []string {
+	batches, err := opts.planBatches(len(hosts))
+	if err != nil {
+		// Opções já deveriam ter sido validadas; executa tudo em um único lote
+		batches = []rolloutBatch{{indexes: indexRange(0, len(hosts))}}
+	}
+
+	var skipped []string
+	var skippedMu sync.Mutex
+	var aborted atomic.Bool
+
+	for b, batch := range batches {
+		if aborted.Load() {
+			for _, idx := range batch.indexes {
+				skipped = append(skipped, hosts[idx])
+			}
+			continue
+		}
+
+		if b > 0 && opts.Pause > 0 {
+			fmt.Fprintf(infoOut, "⏸️  Aguardando %s antes do próximo lote...\n\n", opts.Pause)
+			time.Sleep(opts.Pause)
+		}
+
+		if len(batches) > 1 {
+			label := ""
+			if batch.canary {
+				label = " (canary)"
+			}
+			fmt.Fprintf(infoOut, "📦 Lote %d/%d%s: %d host(s)\n", b+1, len(batches), label, len(batch.indexes))
+		}
+
+		batchFailed := runBatch(hosts, batch.indexes, opts, task, &aborted, func(host string) {
+			skippedMu.Lock()
+			skipped = append(skipped, host)
+			skippedMu.Unlock()
+		})
+
+		if batchFailed && batch.canary && !aborted.Load() {
+			aborted.Store(true)
+			fmt.Fprintf(infoOut, "🛑 Falha no canary: rollout interrompido\n\n")
+		} else if batchFailed && opts.FailFast {
+			fmt.Fprintf(infoOut, "🛑 Falha detectada (--fail-fast): rollout interrompido\n\n")
+		}
+	}
+
+	return skipped
+}
+
+// runBatch executa um lote com no máximo opts.Forks hosts simultâneos
+// Retorna true se algum host do lote falhou
+func runBatch(hosts []string, indexes []int, opts RolloutOptions, task func(index int, host string) bool, aborted *atomic.Bool, skip func(host string)) bool {
+	forks := opts.Forks
+	if forks <= 0 || forks > len(indexes) {
+		forks = len(indexes)
+	}
+
+	sem := make(chan struct{}, forks)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+
+	for _, idx := range indexes {
+		sem <- struct{}{}
+
+		// Com fail-fast, hosts ainda não iniciados são pulados após a primeira falha
+		if opts.FailFast && aborted.Load() {
+			<-sem
+			skip(hosts[idx])
+			continue
+		}
+
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if !task(idx, hosts[idx]) {
+				failed.Store(true)
+				if opts.FailFast {
+					aborted.Store(true)
+				}
+			}
+		}(idx)
+	}
+
+	wg.Wait()
+	return failed.Load()
+}
+
+// describe retorna um resumo legível das opções de rollout (vazio quando são as padrão)
+func (o RolloutOptions) describe() string {
+	var parts []string
+	if o.Forks > 0 {
+		parts = append(parts, fmt.Sprintf("forks=%d", o.Forks))
+	}
+	if o.Batch != "" {
+		parts = append(parts, fmt.Sprintf("lote=%s", o.Batch))
+	}
+	if o.Canary > 0 {
+		parts = append(parts, fmt.Sprintf("canary=%d", o.Canary))
+	}
+	if o.FailFast {
+		parts = append(parts, "fail-fast")
+	}
+	if o.Pause > 0 {
+		parts = append(parts, fmt.Sprintf("pausa=%s", o.Pause))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultInterpreter é usado quando o script não tem shebang
+const defaultInterpreter = "/bin/sh"
+
+// envNamePattern valida nomes de variáveis de ambiente
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Script representa um script local executado nos hosts remotos (sc run)
+type Script struct {
+	Name        string   // Nome do arquivo local (exibição)
+	Content     []byte   // Conteúdo enviado a cada host
+	Interpreter string   // Interpretador (do shebang ou --interpreter)
+	Args        []string // Argumentos repassados ao script
+	Env         []string // Variáveis de ambiente no formato KEY=VALUE
+}
+
+// LoadScript lê um script local e determina o interpretador
+// env aceita KEY=VALUE ou apenas KEY (valor copiado do ambiente local)
+func LoadScript(path string, args []string, env []string, interpreter string) (*Script, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler script: %w", err)
+	}
+
+	if interpreter == "" {
+		interpreter = shebangInterpreter(content)
+	}
+
+	var vars []string
+	for _, entry := range env {
+		name, value, found := strings.Cut(entry, "=")
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("variável de ambiente inválida: '%s' (use KEY=VALUE)", entry)
+		}
+		if !found {
+			value = os.Getenv(name)
+		}
+		vars = append(vars, name+"="+value)
+	}
+
+	return &Script{
+		Name:        filepath.Base(path),
+		Content:     content,
+		Interpreter: interpreter,
+		Args:        args,
+		Env:         vars,
+	}, nil
+}
+
+// shebangInterpreter extrai o interpretador da primeira linha do script (ex: "#!/usr/bin/env bash")
+func shebangInterpreter(content []byte) string {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return defaultInterpreter
+	}
+	interpreter := strings.TrimSpace(string(line[2:]))
+	if interpreter == "" {
+		return defaultInterpreter
+	}
+	return interpreter
+}
+
+// String retorna a descrição do script com seus argumentos (exibida no cabeçalho e nos resultados)
+func (sc *Script) String() string {
+	parts := []string{sc.Name}
+	for _, arg := range sc.Args {
+		parts = append(parts, shellQuoteIfNeeded(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// command monta o comando remoto que executa o script enviado para remotePath
+func (sc *Script) command(remotePath string) string {
+	var parts []string
+	if len(sc.Env) > 0 {
+		parts = append(parts, "env")
+		for _, v := range sc.Env {
+			parts = append(parts, shellQuote(v))
+		}
+	}
+	// O interpretador vem do shebang e pode conter argumentos (ex: "/bin/bash -e")
+	parts = append(parts, sc.Interpreter, shellQuote(remotePath))
+	for _, arg := range sc.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// upload envia o script para um arquivo temporário no host usando uma sessão da conexão existente
+// Retorna o caminho remoto do arquivo. Com shared, o arquivo fica legível por outros usuários (sudo -u)
+func (sc *Script) upload(ctx context.Context, client *ssh.Client, shared bool) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	mode := ""
+	if shared {
+		mode = ` && chmod 644 "$f"`
+	}
+	session.Stdin = bytes.NewReader(sc.Content)
+	output, err := session.Output(`umask 077 && f=$(mktemp "${TMPDIR:-/tmp}/sc-run.XXXXXX") && cat > "$f"` + mode + ` && echo "$f"`)
+	if ctx.Err() != nil {
+		return "", contextError(ctx, "transferência do script", 0)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	var remotePath string
+	for scanner.Scan() {
+		remotePath = strings.TrimSpace(scanner.Text())
+	}
+	if remotePath == "" {
+		return "", fmt.Errorf("caminho temporário não retornado pelo host")
+	}
+	return remotePath, nil
+}
+
+// cleanup remove o script temporário do host (melhor esforço)
+func (sc *Script) cleanup(client *ssh.Client, remotePath string) {
+	session, err := client.NewSession()
+	if err != nil {
+		return
+	}
+	defer session.Close()
+	session.Run("rm -f " + shellQuote(remotePath))
+}
+
+// shellQuoteIfNeeded protege o argumento apenas quando contém caracteres especiais (para exibição)
+func shellQuoteIfNeeded(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`;&|<>*?()[]{}!#~") {
+		return s
+	}
+	return shellQuote(s)
+}
@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWaitPortTimeout é o tempo máximo de espera de um passo wait_port sem timeout definido
+const defaultWaitPortTimeout = 60 * time.Second
+
+// registerNamePattern valida nomes de register (iniciam com minúscula para não colidir com os campos do host)
+var registerNamePattern = regexp.MustCompile(`^[a-z][A-Za-z0-9_]*$`)
+
+// Runbook é uma sequência de passos declarada em YAML (sc runbook)
+type Runbook struct {
+	Name  string        `yaml:"name"`
+	Hosts HostSelector  `yaml:"hosts"` // Hosts padrão dos passos sem "hosts"
+	Steps []RunbookStep `yaml:"steps"`
+
+	dir string // Diretório do arquivo: base dos caminhos locais relativos
+}
+
+// HostSelector aceita hosts e @tags como string ("@web db1") ou lista
+type HostSelector []string
+
+// UnmarshalYAML implementa yaml.Unmarshaler para aceitar string ou lista
+func (s *HostSelector) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = strings.Fields(node.Value)
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return fmt.Errorf("hosts deve ser uma string ou lista: %w", err)
+	}
+	*s = list
+	return nil
+}
+
+// RunbookStep é um passo do runbook: exatamente uma ação (exec, upload, download, script ou wait_port)
+type RunbookStep struct {
+	Name         string       `yaml:"name"`
+	Hosts        HostSelector `yaml:"hosts"`
+	When         string       `yaml:"when"`          // Condição avaliada por host (template)
+	IgnoreErrors bool         `yaml:"ignore_errors"` // Falha não interrompe os passos seguintes do host
+	Register     string       `yaml:"register"`      // Nome para acessar o resultado nos passos seguintes
+	Sudo         bool         `yaml:"sudo"`          // exec e script via sudo
+	SudoUser     string       `yaml:"sudo_user"`     // Usuário alvo do sudo (implica sudo)
+
+	Exec     string        `yaml:"exec"`
+	Upload   *TransferStep `yaml:"upload"`
+	Download *TransferStep `yaml:"download"`
+	Script   *ScriptStep   `yaml:"script"`
+	WaitPort *WaitPortStep `yaml:"wait_port"`
+
+	when   *template.Template
+	script *Script
+}
+
+// TransferStep define um passo upload ou download
+type TransferStep struct {
+	Src       string `yaml:"src"`
+	Dest      string `yaml:"dest"`
+	Recursive bool   `yaml:"recursive"`
+	Flat      bool   `yaml:"flat"` // download: não cria um subdiretório por host em dest
+}
+
+// ScriptStep define um passo script (aceita apenas o caminho como string)
+type ScriptStep struct {
+	Path        string            `yaml:"path"`
+	Args        []string          `yaml:"args"`
+	Env         map[string]string `yaml:"env"`
+	Interpreter string            `yaml:"interpreter"`
+}
+
+// UnmarshalYAML implementa yaml.Unmarshaler para aceitar "script: ./arquivo.sh"
+func (s *ScriptStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Path = node.Value
+		return nil
+	}
+	type plain ScriptStep
+	return node.Decode((*plain)(s))
+}
+
+// WaitPortStep aguarda uma porta aceitar conexões, testada a partir do próprio host remoto
+type WaitPortStep struct {
+	Port    int           `yaml:"port"`
+	Host    string        `yaml:"host"`    // Endereço testado a partir do host remoto (padrão: 127.0.0.1)
+	Timeout time.Duration `yaml:"timeout"` // Padrão: 60s
+}
+
+// LoadRunbook lê e valida um runbook
+func LoadRunbook(path string) (*Runbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler runbook: %w", err)
+	}
+
+	var rb Runbook
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rb); err != nil {
+		return nil, fmt.Errorf("erro ao interpretar runbook: %w", err)
+	}
+
+	rb.dir = filepath.Dir(path)
+	if rb.Name == "" {
+		rb.Name = filepath.Base(path)
+	}
+	if err := rb.validate(); err != nil {
+		return nil, err
+	}
+	return &rb, nil
+}
+
+// validate verifica a estrutura do runbook e prepara condições e scripts
+func (rb *Runbook) validate() error {
+	if len(rb.Steps) == 0 {
+		return fmt.Errorf("runbook sem passos (steps)")
+	}
+
+	for i := range rb.Steps {
+		step := &rb.Steps[i]
+		label := fmt.Sprintf("passo %d", i+1)
+		if step.Name == "" {
+			step.Name = label
+		} else {
+			label += " (" + step.Name + ")"
+		}
+
+		if step.action() == "" {
+			return fmt.Errorf("%s: defina uma ação (exec, upload, download, script ou wait_port)", label)
+		}
+		if n := step.actionCount(); n > 1 {
+			return fmt.Errorf("%s: apenas uma ação por passo (encontradas %d)", label, n)
+		}
+		if len(step.Hosts) == 0 && len(rb.Hosts) == 0 {
+			return fmt.Errorf("%s: nenhum host definido (use hosts no passo ou no runbook)", label)
+		}
+		if _, err := config.ParseSelector(rb.hostsFor(step)...); err != nil {
+			return fmt.Errorf("%s: hosts inválido: %w", label, err)
+		}
+		if step.SudoUser != "" {
+			step.Sudo = true
+		}
+		if step.Sudo && step.Exec == "" && step.Script == nil {
+			return fmt.Errorf("%s: sudo se aplica apenas a exec e script", label)
+		}
+
+		if step.Register != "" {
+			if !registerNamePattern.MatchString(step.Register) {
+				return fmt.Errorf("%s: register inválido '%s' (use letras, números e _, iniciando com minúscula)", label, step.Register)
+			}
+		}
+
+		if step.When != "" {
+			tmpl, err := parseTemplate(conditionTemplate(step.When))
+			if err != nil {
+				return fmt.Errorf("%s: when inválido: %w", label, err)
+			}
+			step.when = tmpl
+		}
+
+		for _, field := range step.templates() {
+			if _, err := parseTemplate(field); err != nil {
+				return fmt.Errorf("%s: template inválido: %w", label, err)
+			}
+		}
+
+		switch {
+		case step.Upload != nil:
+			if step.Upload.Src == "" || step.Upload.Dest == "" {
+				return fmt.Errorf("%s: upload requer src e dest", label)
+			}
+			if !isTemplate(step.Upload.Src) {
+				if _, err := os.Stat(rb.localPath(step.Upload.Src)); err != nil {
+					return fmt.Errorf("%s: arquivo local '%s' não encontrado", label, step.Upload.Src)
+				}
+			}
+		case step.Download != nil:
+			if step.Download.Src == "" || step.Download.Dest == "" {
+				return fmt.Errorf("%s: download requer src e dest", label)
+			}
+		case step.Script != nil:
+			var env []string
+			for _, key := range slices.Sorted(maps.Keys(step.Script.Env)) {
+				env = append(env, key+"="+step.Script.Env[key])
+			}
+			script, err := LoadScript(rb.localPath(step.Script.Path), step.Script.Args, env, step.Script.Interpreter)
+			if err != nil {
+				return fmt.Errorf("%s: %w", label, err)
+			}
+			step.script = script
+		case step.WaitPort != nil:
+			if step.WaitPort.Port < 1 || step.WaitPort.Port > 65535 {
+				return fmt.Errorf("%s: wait_port requer uma porta entre 1 e 65535", label)
+			}
+			if step.WaitPort.Timeout < 0 {
+				return fmt.Errorf("%s: timeout do wait_port não pode ser negativo", label)
+			}
+		}
+	}
+	return nil
+}
+
+// localPath resolve caminhos locais relativos ao diretório do runbook
+func (rb *Runbook) localPath(path string) string {
+	if strings.HasPrefix(path, "~") {
+		return config.ExpandHomePath(path)
+	}
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(rb.dir, path)
+}
+
+// hostsFor retorna o seletor de hosts do passo (ou o padrão do runbook)
+func (rb *Runbook) hostsFor(step *RunbookStep) []string {
+	if len(step.Hosts) > 0 {
+		return step.Hosts
+	}
+	return rb.Hosts
+}
+
+// action retorna o nome da ação do passo
+func (step *RunbookStep) action() string {
+	switch {
+	case step.Exec != "":
+		return "exec"
+	case step.Upload != nil:
+		return "upload"
+	case step.Download != nil:
+		return "download"
+	case step.Script != nil:
+		return "script"
+	case step.WaitPort != nil:
+		return "wait_port"
+	}
+	return ""
+}
+
+// actionCount conta quantas ações foram definidas no passo
+func (step *RunbookStep) actionCount() int {
+	count := 0
+	for _, defined := range []bool{step.Exec != "", step.Upload != nil, step.Download != nil, step.Script != nil, step.WaitPort != nil} {
+		if defined {
+			count++
+		}
+	}
+	return count
+}
+
+// templates retorna os campos do passo renderizados por host
+func (step *RunbookStep) templates() []string {
+	switch {
+	case step.Exec != "":
+		return []string{step.Exec}
+	case step.Upload != nil:
+		return []string{step.Upload.Src, step.Upload.Dest}
+	case step.Download != nil:
+		return []string{step.Download.Src, step.Download.Dest}
+	case step.WaitPort != nil:
+		return []string{step.WaitPort.Host}
+	}
+	return nil
+}
+
+// conditionTemplate permite escrever when sem chaves (ex: "not .drain.Failed")
+func conditionTemplate(when string) string {
+	if isTemplate(when) {
+		return when
+	}
+	return "{{ " + when + " }}"
+}
+
+// evaluateCondition avalia o when do passo para um host
+// O resultado é falso quando vazio, "false" ou "0"
+func (step *RunbookStep) evaluateCondition(data map[string]any) (bool, error) {
+	if step.when == nil {
+		return true, nil
+	}
+	var out strings.Builder
+	if err := step.when.Execute(&out, data); err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(out.String()) {
+	case "", "false", "0":
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,642 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexeiev/sshControl/config"
+)
+
+// RunbookOptions controla a execução de um runbook
+type RunbookOptions struct {
+	Check    bool         // Apenas exibe o plano, sem conectar aos hosts
+	Forks    int          // Máximo de hosts simultâneos em cada passo (0 = sem limite)
+	Timeouts Timeouts     // Limites de tempo de conexão e de execução
+	Output   OutputFormat // Formato do relatório final
+}
+
+// StepOutput é o resultado de um passo em um host, acessível em when e templates via register
+type StepOutput struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Failed   bool
+	Skipped  bool
+	Error    string
+}
+
+// Status de um passo em um host
+const (
+	stepOK      = "ok"
+	stepFailed  = "failed"
+	stepIgnored = "ignored" // Falhou, mas com ignore_errors
+	stepSkipped = "skipped" // Condição when falsa
+	stepNotRun  = "not_run" // Host falhou em um passo anterior
+	stepPlanned = "planned" // Modo --check
+)
+
+// stepHostResult é o resultado de um passo em um host
+type stepHostResult struct {
+	Host         string
+	Status       string
+	Detail       string // Ação renderizada (modo --check) ou motivo do skip
+	Output       StepOutput
+	FailureClass FailureClass
+	StartedAt    time.Time
+	Duration     time.Duration
+}
+
+// runbookRun armazena o estado de uma execução de runbook
+type runbookRun struct {
+	rb           *Runbook
+	cfg          *config.ConfigFile
+	selectedUser *config.User // Usuário de -u (nil = user do host ou default_user)
+	jumpHost     *config.JumpHost
+	proxyEnabled bool   // -p (ou contexto); proxy: true nas rules também habilita
+	proxyAddress string // Vazio se o proxy não estiver configurado
+	proxyPort    int
+	password     string
+	sudoPassword string
+	verbose      bool
+	opts         RunbookOptions
+
+	hosts    []string                  // Todos os hosts, na ordem em que aparecem
+	data     map[string]map[string]any // Dados de template de cada host (inclui os registers)
+	failed   map[string]bool           // Hosts que falharam (sem ignore_errors)
+	stepHost [][]string                // Hosts selecionados em cada passo
+	results  []map[string]stepHostResult
+	mu       sync.Mutex
+}
+
+// RunRunbook executa os passos do runbook em sequência; cada passo roda em paralelo nos seus hosts.
+// Um host que falha deixa de executar os passos seguintes. Retorna false se algum host falhou.
+func RunRunbook(ctx context.Context, cfg *config.ConfigFile, rb *Runbook, selectedUser *config.User, jumpHost *config.JumpHost, proxyEnabled bool, askPassword bool, verbose bool, opts RunbookOptions) bool {
+	useStructuredOutput(opts.Output)
+
+	effectiveUser := cfg.GetEffectiveUser(selectedUser)
+	if effectiveUser == nil {
+		fmt.Fprintf(os.Stderr, "Erro: Nenhum usuário configurado\n")
+		os.Exit(1)
+	}
+	if !opts.Check {
+		config.ValidateEffectiveUserSSHKeys(effectiveUser)
+	}
+
+	// Obtém configuração de proxy uma vez
+	proxyAddress, proxyPort, proxyConfigured := cfg.Config.GetProxyConfig()
+	if !proxyConfigured && proxyEnabled {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: Proxy solicitado mas não configurado no config.yaml\n\n")
+	}
+
+	run := &runbookRun{
+		rb:           rb,
+		cfg:          cfg,
+		selectedUser: selectedUser,
+		jumpHost:     jumpHost,
+		proxyEnabled: proxyEnabled,
+		proxyAddress: proxyAddress,
+		proxyPort:    proxyPort,
+		verbose:      verbose,
+		opts:         opts,
+		data:         make(map[string]map[string]any),
+		failed:       make(map[string]bool),
+		results:      make([]map[string]stepHostResult, len(rb.Steps)),
+	}
+
+	// Resolve os hosts de cada passo
+	needsSudo := false
+	for i := range rb.Steps {
+		step := &rb.Steps[i]
+		hosts, _, err := expandHostSelectors(cfg, rb.hostsFor(step))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: passo %d (%s): %v\n", i+1, step.Name, err)
+			os.Exit(1)
+		}
+		if len(hosts) == 0 {
+			fmt.Fprintf(os.Stderr, "Erro: passo %d (%s): nenhum host válido\n", i+1, step.Name)
+			os.Exit(1)
+		}
+		run.stepHost = append(run.stepHost, hosts)
+		for _, host := range hosts {
+			if run.data[host] == nil {
+				run.data[host] = map[string]any{"Name": host}
+				run.hosts = append(run.hosts, host)
+			}
+		}
+		needsSudo = needsSudo || step.Sudo
+	}
+
+	// Dados de template de cada host, resolvidos em paralelo (rules com cidr podem consultar o DNS)
+	targets := make([]*hostTarget, len(run.hosts))
+	var wg sync.WaitGroup
+	for i, host := range run.hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			targets[i], _ = resolveHostTarget(ctx, cfg, host, selectedUser)
+		}(i, host)
+	}
+	wg.Wait()
+	for i, host := range run.hosts {
+		if targets[i] != nil {
+			run.data[host] = hostTemplateData(cfg, host, targets[i])
+		}
+	}
+
+	fmt.Fprintln(infoOut)
+	fmt.Fprintf(infoOut, "📒 Runbook: %s (%d passo(s), %d host(s))\n", rb.Name, len(rb.Steps), len(run.hosts))
+	if jumpHost != nil {
+		fmt.Fprintf(infoOut, "   via Jump Host: %s (%s@%s:%d)\n", jumpHost.Name, jumpHost.User, jumpHost.Host, jumpHost.Port)
+	}
+	if opts.Check {
+		fmt.Fprintln(infoOut, "   Modo --check: nenhuma conexão será feita")
+	}
+	fmt.Fprintln(infoOut)
+
+	// Senhas são pedidas uma única vez, antes do primeiro passo
+	if !opts.Check {
+		if askPassword {
+			run.password = promptRunbookPassword(fmt.Sprintf("Password for %s (será usada para todos os hosts): ", effectiveUser.Name))
+		}
+		run.sudoPassword = run.password
+		// Sem terminal (CI, cron), o sudo segue sem senha (NOPASSWD)
+		if needsSudo && run.sudoPassword == "" && !canPromptPassword() {
+			fmt.Fprintln(infoOut, "ℹ️  Sem terminal para a senha do sudo: executando sem senha (NOPASSWD)")
+		} else if needsSudo && run.sudoPassword == "" {
+			run.sudoPassword = promptRunbookPassword(fmt.Sprintf("[sudo] Password for %s (Enter se não for necessária): ", effectiveUser.Name))
+		}
+	}
+
+	startTime := time.Now()
+	for i := range rb.Steps {
+		run.runStep(ctx, i)
+	}
+	duration := time.Since(startTime)
+
+	if opts.Output.IsStructured() {
+		if err := run.writeReport(os.Stdout, startTime, duration); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao gerar saída %s: %v\n", opts.Output, err)
+		}
+	} else {
+		run.displayReport(duration)
+	}
+
+	return len(run.failed) == 0
+}
+
+// promptRunbookPassword lê uma senha do terminal e encerra em caso de erro
+func promptRunbookPassword(prompt string) string {
+	fmt.Fprint(infoOut, prompt)
+	passwordBytes, err := readPassword()
+	fmt.Fprintln(infoOut)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(infoOut)
+	return string(passwordBytes)
+}
+
+// runStep executa um passo em todos os seus hosts
+func (r *runbookRun) runStep(ctx context.Context, index int) {
+	step := &r.rb.Steps[index]
+	hosts := r.stepHost[index]
+	r.results[index] = make(map[string]stepHostResult, len(hosts))
+
+	fmt.Fprintf(infoOut, "▶️  [%d/%d] %s (%s, %d host(s))\n", index+1, len(r.rb.Steps), step.Name, step.action(), len(hosts))
+
+	// Hosts que falharam em passos anteriores não executam mais nada
+	var active []string
+	for _, host := range hosts {
+		if r.failed[host] {
+			r.results[index][host] = stepHostResult{Host: host, Status: stepNotRun}
+			continue
+		}
+		active = append(active, host)
+	}
+
+	runRollout(active, RolloutOptions{Forks: r.opts.Forks}, func(_ int, host string) bool {
+		result := r.runStepOnHost(ctx, step, host)
+		r.mu.Lock()
+		r.results[index][host] = result
+		r.mu.Unlock()
+		return true
+	})
+
+	// Atualiza registers e falhas após o passo (nenhum host está executando aqui)
+	for _, host := range hosts {
+		result := r.results[index][host]
+		if result.Status == stepNotRun {
+			continue
+		}
+		// No modo --check não há resultados: os templates que dependem de register são exibidos como estão
+		if step.Register != "" && !r.opts.Check {
+			r.data[host][step.Register] = result.Output
+		}
+		if result.Status == stepFailed {
+			r.failed[host] = true
+		}
+		r.printStepResult(result)
+	}
+	fmt.Fprintln(infoOut)
+}
+
+// runStepOnHost avalia a condição e executa a ação do passo em um host
+func (r *runbookRun) runStepOnHost(ctx context.Context, step *RunbookStep, host string) stepHostResult {
+	result := stepHostResult{Host: host, StartedAt: time.Now()}
+	data := r.data[host]
+
+	fail := func(class FailureClass, err error) stepHostResult {
+		result.Output.Failed = true
+		result.Output.Error = err.Error()
+		result.FailureClass = class
+		result.Status = stepFailed
+		if step.IgnoreErrors {
+			result.Status = stepIgnored
+		}
+		result.Duration = time.Since(result.StartedAt)
+		return result
+	}
+
+	// No modo --check, registers de passos anteriores não existem: a condição não é avaliada
+	if r.opts.Check {
+		result.Status = stepPlanned
+		result.Detail = r.describeStep(step, data)
+		return result
+	}
+
+	run, err := step.evaluateCondition(data)
+	if err != nil {
+		return fail(FailureCommand, fmt.Errorf("erro ao avaliar when: %w", err))
+	}
+	if !run {
+		result.Status = stepSkipped
+		result.Output.Skipped = true
+		result.Detail = "when: " + step.When
+		return result
+	}
+
+	sshConn, err := r.newConnection(ctx, host)
+	if err != nil {
+		return fail(FailureConnection, fmt.Errorf("formato inválido: %w", err))
+	}
+
+	switch {
+	case step.Exec != "", step.Script != nil:
+		if step.Exec != "" {
+			command, err := renderTemplate(step.Exec, data)
+			if err != nil {
+				return fail(FailureCommand, fmt.Errorf("erro no template: %w", err))
+			}
+			sshConn.Command = command
+		} else {
+			sshConn.Script = step.script
+		}
+		if step.Sudo {
+			sshConn.Sudo = true
+			sshConn.SudoUser = step.SudoUser
+			sshConn.SudoPassword = r.sudoPassword
+		}
+		stdout, stderr, exitCode, err := sshConn.ExecuteCommandWithOutput(ctx)
+		result.Output.Stdout, result.Output.Stderr, result.Output.ExitCode = stdout, stderr, exitCode
+		if err != nil {
+			return fail(failureClassOf(err), err)
+		}
+		if exitCode != 0 {
+			return fail(FailureCommand, fmt.Errorf("exit code %d", exitCode))
+		}
+
+	case step.Upload != nil, step.Download != nil:
+		if err := r.transfer(ctx, sshConn, step, host, data); err != nil {
+			return fail(transferFailureClass(err), err)
+		}
+
+	case step.WaitPort != nil:
+		address, err := renderTemplate(step.WaitPort.Host, data)
+		if err != nil {
+			return fail(FailureCommand, fmt.Errorf("erro no template: %w", err))
+		}
+		if err := sshConn.waitPort(ctx, address, step.WaitPort.Port, step.WaitPort.Timeout); err != nil {
+			return fail(failureClassOf(err), err)
+		}
+	}
+
+	result.Status = stepOK
+	result.Duration = time.Since(result.StartedAt)
+	return result
+}
+
+// newConnection cria a conexão SSH de um host (sem prompt de senha interativo)
+func (r *runbookRun) newConnection(ctx context.Context, host string) (*SSHConnection, error) {
+	target, err := resolveHostTarget(ctx, r.cfg, host, r.selectedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sem -j, usa o jump host definido no host (campo jump) ou nas rules
+	jumpHost, err := connectionJumpHost(ctx, r.cfg, target, r.jumpHost)
+	if err != nil {
+		return nil, err
+	}
+
+	var jumpHostSSHKeys []string
+	if jumpHost != nil {
+		jumpHostSSHKeys = r.cfg.GetJumpHostSSHKeys(jumpHost)
+	}
+
+	sshConn := NewSSHConnection(
+		target.User,
+		target.Hostname,
+		target.Port,
+		target.SSHKeys,
+		r.password,
+		jumpHost,
+		jumpHostSSHKeys,
+		"",
+		target.proxy(r.proxyEnabled) && r.proxyAddress != "",
+		r.proxyAddress,
+		r.proxyPort,
+		r.verbose,
+	)
+	target.configure(r.cfg, sshConn, r.opts.Timeouts)
+	sshConn.InteractivePasswordAllowed = false
+	return sshConn, nil
+}
+
+// transfer executa um passo upload ou download
+func (r *runbookRun) transfer(ctx context.Context, sshConn *SSHConnection, step *RunbookStep, host string, data map[string]any) error {
+	spec := step.Upload
+	if spec == nil {
+		spec = step.Download
+	}
+	src, err := renderTemplate(spec.Src, data)
+	if err != nil {
+		return fmt.Errorf("erro no template: %w", err)
+	}
+	dest, err := renderTemplate(spec.Dest, data)
+	if err != nil {
+		return fmt.Errorf("erro no template: %w", err)
+	}
+
+	ft := &FileTransfer{Recursive: spec.Recursive, Verbose: r.verbose, Quiet: true}
+	if step.Upload != nil {
+		ft.LocalPath = r.rb.localPath(src)
+		ft.RemotePath = dest
+		return ft.Upload(ctx, sshConn)
+	}
+
+	// Download: cada host recebe um subdiretório próprio em dest (exceto com flat)
+	localDir := r.rb.localPath(dest)
+	if !spec.Flat {
+		localDir = filepath.Join(localDir, host)
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return fmt.Errorf("erro ao criar diretório local: %w", err)
+		}
+	}
+	ft.LocalPath = localDir
+	ft.RemotePath = src
+	return ft.Download(ctx, sshConn)
+}
+
+// describeStep descreve a ação do passo para um host (modo --check)
+func (r *runbookRun) describeStep(step *RunbookStep, data map[string]any) string {
+	render := func(text string) string {
+		if rendered, err := renderTemplate(text, data); err == nil {
+			return rendered
+		}
+		return text // Depende de registers: exibe o template
+	}
+
+	var detail string
+	switch {
+	case step.Exec != "":
+		detail = render(step.Exec)
+	case step.Script != nil:
+		detail = step.script.String() + " (" + step.script.Interpreter + ")"
+	case step.Upload != nil:
+		detail = render(step.Upload.Src) + " → " + render(step.Upload.Dest)
+	case step.Download != nil:
+		detail = render(step.Download.Src) + " → " + render(step.Download.Dest)
+	case step.WaitPort != nil:
+		detail = fmt.Sprintf("%s:%d", waitPortHost(render(step.WaitPort.Host)), step.WaitPort.Port)
+	}
+	if step.Sudo {
+		detail = "sudo: " + detail
+	}
+	if step.When != "" {
+		detail += " (when: " + step.When + ")"
+	}
+	return detail
+}
+
+// printStepResult exibe o resultado de um passo em um host durante a execução
+func (r *runbookRun) printStepResult(result stepHostResult) {
+	switch result.Status {
+	case stepOK:
+		fmt.Fprintf(infoOut, "   ✅ %s (%.2fs)\n", result.Host, result.Duration.Seconds())
+	case stepFailed:
+		fmt.Fprintf(infoOut, "   ❌ %s: %s (%s)\n", result.Host, result.Output.Error, result.FailureClass)
+	case stepIgnored:
+		fmt.Fprintf(infoOut, "   ⚠️  %s: %s (ignorado)\n", result.Host, result.Output.Error)
+	case stepSkipped:
+		fmt.Fprintf(infoOut, "   ⏭️  %s: condição falsa (%s)\n", result.Host, result.Detail)
+	case stepPlanned:
+		fmt.Fprintf(infoOut, "   📝 %s: %s\n", result.Host, result.Detail)
+	}
+	printIndented(infoOut, result.Output.Stdout)
+	printIndented(infoOut, result.Output.Stderr)
+}
+
+// printIndented exibe a saída de um comando recuada sob o host
+func printIndented(w io.Writer, output string) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fmt.Fprintf(w, "      %s\n", line)
+	}
+}
+
+// statusLabel retorna o texto exibido no relatório para cada status
+func statusLabel(status string) string {
+	switch status {
+	case stepOK:
+		return "✅ ok"
+	case stepFailed:
+		return "❌ falhou"
+	case stepIgnored:
+		return "⚠️  falhou (ignorado)"
+	case stepSkipped:
+		return "⏭️  pulado"
+	case stepNotRun:
+		return "⛔ não executado"
+	case stepPlanned:
+		return "📝 planejado"
+	}
+	return status
+}
+
+// displayReport exibe o relatório final por host e por passo
+func (r *runbookRun) displayReport(duration time.Duration) {
+	nameWidth := 0
+	for _, step := range r.rb.Steps {
+		nameWidth = max(nameWidth, len([]rune(step.Name)))
+	}
+
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("📋 Relatório: %s\n", r.rb.Name)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for _, host := range r.hosts {
+		icon := "✅"
+		if r.failed[host] {
+			icon = "❌"
+		}
+		fmt.Printf("%s %s\n", icon, host)
+		for i, step := range r.rb.Steps {
+			result, ok := r.results[i][host]
+			if !ok {
+				continue // Passo não se aplica a este host
+			}
+			fmt.Printf("   %2d. %-*s  %s", i+1, nameWidth, step.Name, statusLabel(result.Status))
+			if result.Duration > 0 {
+				fmt.Printf(" (%.2fs)", result.Duration.Seconds())
+			}
+			fmt.Println()
+		}
+	}
+
+	succeeded := len(r.hosts) - len(r.failed)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("📊 Resumo: %d host(s) ok, %d com falha, %d passo(s) | ⏱️  Tempo: %.2fs\n", succeeded, len(r.failed), len(r.rb.Steps), duration.Seconds())
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+}
+
+// runbookStepRecord é a representação estruturada de um passo em um host
+type runbookStepRecord struct {
+	Host       string `json:"host" yaml:"host"`
+	Step       int    `json:"step" yaml:"step"`
+	Name       string `json:"name" yaml:"name"`
+	Action     string `json:"action" yaml:"action"`
+	Status     string `json:"status" yaml:"status"`
+	ExitCode   int    `json:"exit_code" yaml:"exit_code"`
+	Stdout     string `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+	Detail     string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorClass string `json:"error_class,omitempty" yaml:"error_class,omitempty"`
+	DurationMs int64  `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// runbookDocument é o relatório completo de um runbook
+type runbookDocument struct {
+	Runbook string              `json:"runbook" yaml:"runbook"`
+	Check   bool                `json:"check" yaml:"check"`
+	Results []runbookStepRecord `json:"results" yaml:"results"`
+	Summary runSummary          `json:"summary" yaml:"summary"`
+}
+
+// writeReport escreve o relatório final em formato estruturado
+func (r *runbookRun) writeReport(w io.Writer, startTime time.Time, duration time.Duration) error {
+	doc := runbookDocument{
+		Runbook: r.rb.Name,
+		Check:   r.opts.Check,
+		Summary: runSummary{
+			Total:      len(r.hosts),
+			Succeeded:  len(r.hosts) - len(r.failed),
+			Failed:     len(r.failed),
+			StartedAt:  startTime,
+			FinishedAt: startTime.Add(duration),
+			DurationMs: duration.Milliseconds(),
+		},
+	}
+
+	out := structuredOutput{
+		Header: []string{"host", "step", "name", "action", "status", "exit_code", "error_class", "duration_ms", "error"},
+	}
+	for _, host := range r.hosts {
+		for i, step := range r.rb.Steps {
+			result, ok := r.results[i][host]
+			if !ok {
+				continue
+			}
+			record := runbookStepRecord{
+				Host:       host,
+				Step:       i + 1,
+				Name:       step.Name,
+				Action:     step.action(),
+				Status:     result.Status,
+				ExitCode:   result.Output.ExitCode,
+				Stdout:     result.Output.Stdout,
+				Stderr:     result.Output.Stderr,
+				Detail:     result.Detail,
+				Error:      result.Output.Error,
+				ErrorClass: string(result.FailureClass),
+				DurationMs: result.Duration.Milliseconds(),
+			}
+			doc.Results = append(doc.Results, record)
+			out.Records = append(out.Records, record)
+			out.Rows = append(out.Rows, []string{
+				host, strconv.Itoa(i + 1), step.Name, record.Action, record.Status,
+				strconv.Itoa(record.ExitCode), record.ErrorClass, strconv.FormatInt(record.DurationMs, 10), record.Error,
+			})
+		}
+	}
+	out.Document = doc
+	return out.write(w, r.opts.Output)
+}
+
+// waitPortHost retorna o endereço testado pelo wait_port (padrão: o próprio host remoto)
+func waitPortHost(host string) string {
+	if host == "" {
+		return "127.0.0.1"
+	}
+	return host
+}
+
+// waitPort aguarda até que host:port aceite conexões, testando a partir do host remoto (via túnel SSH)
+func (s *SSHConnection) waitPort(ctx context.Context, host string, port int, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultWaitPortTimeout
+	}
+
+	sshConfig, err := s.createSSHConfig()
+	if err != nil {
+		return fmt.Errorf("erro ao criar configuração SSH: %w", err)
+	}
+	client, err := s.dial(ctx, sshConfig)
+	if err != nil {
+		return withFailureClass(dialFailureClass(err), fmt.Errorf("erro ao conectar: %w", err))
+	}
+	defer client.Close()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(waitPortHost(host), strconv.Itoa(port))
+	for {
+		s.debugLog("Testando %s a partir do host remoto...", address)
+		conn, err := client.DialContext(waitCtx, "tcp", address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return contextError(ctx, "espera pela porta", 0)
+			}
+			return withFailureClass(FailureTimeout, fmt.Errorf("porta %s não respondeu em %s", address, timeout))
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/alexeiev/sshControl/config"
 	"golang.org/x/crypto/ssh"
@@ -20,16 +24,34 @@ type SSHConnection struct {
 	User                       string
 	Host                       string
 	Port                       int
-	SSHKeys                    []string // Múltiplas chaves SSH para tentar autenticação
-	Password                   string   // Senha pré-fornecida (opcional)
+	SSHKeys                    []string              // Múltiplas chaves SSH para tentar autenticação
+	Password                   string                // Senha pré-fornecida (opcional)
+	PasswordSource             config.PasswordSource // Origem da senha (password_command ou password_ref), usada se Password estiver vazio
 	JumpHost                   *config.JumpHost
-	JumpHostSSHKeys            []string // Múltiplas chaves SSH para o jump host
+	JumpHostSSHKeys            []string              // Múltiplas chaves SSH para o jump host
+	JumpHostPasswordSource     config.PasswordSource // Origem da senha do jump host
 	Command                    string
 	ProxyEnabled               bool
 	ProxyAddress               string
 	ProxyPort                  int
-	InteractivePasswordAllowed bool // Se false, não pede senha interativamente (para modo múltiplos hosts)
-	Verbose                    bool // Modo debug: exibe informações detalhadas da conexão
+	InteractivePasswordAllowed bool              // Se false, não pede senha interativamente (para modo múltiplos hosts)
+	Verbose                    bool              // Modo debug: exibe informações detalhadas da conexão
+	StreamStdout               io.Writer         // Destino opcional para stdout em tempo real (modo --stream)
+	StreamStderr               io.Writer         // Destino opcional para stderr em tempo real (modo --stream)
+	ConnectTimeout             time.Duration     // Tempo máximo para conectar (0 = sem limite)
+	CommandTimeout             time.Duration     // Tempo máximo de execução do comando (0 = sem limite)
+	Stdin                      io.Reader         // Entrada opcional enviada ao stdin do comando remoto
+	Sudo                       bool              // Executa o comando via sudo (modo não interativo)
+	SudoUser                   string            // Usuário alvo do sudo (vazio = root)
+	SudoPassword               string            // Senha enviada ao prompt do sudo
+	Script                     *Script           // Script local enviado e executado no lugar de Command (sc run)
+	Env                        map[string]string // Variáveis de ambiente (env das rules) exportadas antes do comando
+	Alternates                 []HostAddress     // Endereços alternativos (addresses do host), tentados após Host/JumpHost
+	AddressTimeout             time.Duration     // Tempo máximo por endereço quando há alternativos
+	RaceAddresses              bool              // Disputa os endereços em paralelo (address_strategy: race)
+	Address                    string            // Endereço que respondeu (preenchido ao conectar)
+
+	passwordCtx context.Context // Contexto da conexão em andamento (interrompe o password_command)
 }
 
 // debugLog imprime mensagens de debug quando o modo verbose está ativo
@@ -65,7 +87,7 @@ func (s *SSHConnection) Connect() error {
 	}
 
 	// Conecta ao host (via Jump Host se necessário)
-	client, err := s.dial(config)
+	client, err := s.dial(context.Background(), config)
 	if err != nil {
 		return fmt.Errorf("erro ao conectar: %w", err)
 	}
@@ -129,7 +151,8 @@ func (s *SSHConnection) ExecuteCommand() error {
 	}
 
 	// Conecta ao host (via Jump Host se necessário)
-	client, err := s.dial(config)
+	ctx := context.Background()
+	client, err := s.dial(ctx, config)
 	if err != nil {
 		return fmt.Errorf("erro ao conectar: %w", err)
 	}
@@ -156,7 +179,7 @@ func (s *SSHConnection) ExecuteCommand() error {
 
 	// Executa o comando
 	s.debugLog("Executando comando...")
-	if err := session.Run(s.Command); err != nil {
+	if err := s.runSession(ctx, client, session); err != nil {
 		if exitErr, ok := err.(*ssh.ExitError); ok {
 			s.debugLog("Comando encerrado com exit code: %d", exitErr.ExitStatus())
 			return fmt.Errorf("comando encerrado com código: %d", exitErr.ExitStatus())
@@ -174,7 +197,8 @@ func (s *SSHConnection) createSSHConfig() (*ssh.ClientConfig, error) {
 }
 
 // createAuthMethods cria os métodos de autenticação para SSH
-func (s *SSHConnection) createAuthMethods(sshKeyPaths []string, context string) []ssh.AuthMethod {
+// passwordSource é a origem da senha (password_command ou password_ref) quando não há senha pré-fornecida
+func (s *SSHConnection) createAuthMethods(sshKeyPaths []string, passwordSource config.PasswordSource, context string) []ssh.AuthMethod {
 	authMethods := []ssh.AuthMethod{}
 	var authNames []string
 
@@ -217,6 +241,14 @@ func (s *SSHConnection) createAuthMethods(sshKeyPaths []string, context string)
 		authMethods = append(authMethods, ssh.Password(s.Password))
 		s.debugLog("Senha: pré-fornecida")
 		authNames = append(authNames, "password (pré-fornecida)")
+	} else if !passwordSource.IsZero() {
+		// Senha de password_command ou do cofre: só é obtida se a autenticação por chave falhar
+		authMethods = append(authMethods, ssh.PasswordCallback(func() (string, error) {
+			s.debugLog("Senha: obtendo de %s", describePasswordSource(passwordSource))
+			return sourcePassword(s.passwordContext(), passwordSource)
+		}))
+		s.debugLog("Senha: %s", describePasswordSource(passwordSource))
+		authNames = append(authNames, "password ("+describePasswordSource(passwordSource)+")")
 	} else if s.InteractivePasswordAllowed {
 		// Só pede senha interativamente se permitido (modo single host)
 		// Em modo múltiplos hosts, isso é desabilitado para evitar múltiplos prompts
@@ -238,9 +270,17 @@ func (s *SSHConnection) createAuthMethods(sshKeyPaths []string, context string)
 	return authMethods
 }
 
+// passwordContext retorna o contexto da conexão em andamento para obter a senha (Background fora de dial)
+func (s *SSHConnection) passwordContext() context.Context {
+	if s.passwordCtx == nil {
+		return context.Background()
+	}
+	return s.passwordCtx
+}
+
 // createSSHConfigWithContext cria a configuração do cliente SSH com contexto para prompts
 func (s *SSHConnection) createSSHConfigWithContext(context string) (*ssh.ClientConfig, error) {
-	authMethods := s.createAuthMethods(s.SSHKeys, context)
+	authMethods := s.createAuthMethods(s.SSHKeys, s.PasswordSource, context)
 
 	config := &ssh.ClientConfig{
 		User:            s.User,
@@ -252,61 +292,125 @@ func (s *SSHConnection) createSSHConfigWithContext(context string) (*ssh.ClientC
 }
 
 // dial conecta ao host (via Jump Host se necessário)
-func (s *SSHConnection) dial(config *ssh.ClientConfig) (*ssh.Client, error) {
-	address := fmt.Sprintf("%s:%d", s.Host, s.Port)
-
-	// Conexão direta se não usar Jump Host
-	if s.JumpHost == nil {
-		s.debugLog("Conectando diretamente a %s...", address)
-		client, err := ssh.Dial("tcp", address, config)
+// A conexão respeita o contexto e o limite de s.ConnectTimeout
+// Com endereços alternativos (addresses do host), o endereço é escolhido por dialAddresses
+func (s *SSHConnection) dial(ctx context.Context, config *ssh.ClientConfig) (*ssh.Client, error) {
+	connectCtx, cancel := withTimeout(ctx, s.ConnectTimeout)
+	defer cancel()
+	s.passwordCtx = ctx
+
+	// Com senha interativa (ou de password_command/cofre ainda não obtida), o handshake pode aguardar o usuário:
+	// nesse caso o limite vale apenas para a abertura das conexões TCP
+	addresses := s.addresses()
+	handshakeCtx := connectCtx
+	if s.InteractivePasswordAllowed || !passwordReady(s.PasswordSource) || slices.ContainsFunc(addresses, func(a HostAddress) bool {
+		return !passwordReady(a.JumpHostPasswordSource)
+	}) {
+		handshakeCtx = ctx
+	}
+
+	if len(addresses) == 1 {
+		t, err := s.openTransport(connectCtx, handshakeCtx, addresses[0])
 		if err != nil {
+			return nil, err
+		}
+		s.Address = addresses[0].String()
+		return s.handshake(handshakeCtx, t, config)
+	}
+
+	client, index, err := s.dialAddresses(connectCtx, handshakeCtx, addresses, config)
+	if err != nil {
+		return nil, err
+	}
+	s.Address = addresses[index].String()
+	s.reportAddress(index, len(addresses))
+	return client, nil
+}
+
+// handshake cria o cliente SSH sobre a conexão (direta ou pelo tunnel do Jump Host, com config do target)
+// Falhas de autenticação são classificadas para não serem repetidas nos endereços alternativos
+func (s *SSHConnection) handshake(ctx context.Context, t *transport, config *ssh.ClientConfig) (*ssh.Client, error) {
+	client, err := newClient(ctx, t.conn, t.address.hostPort(), config)
+	if err != nil {
+		err = s.connectError(ctx, err)
+		if dialFailureClass(err) == FailureAuth {
+			err = withFailureClass(FailureAuth, err)
+		}
+		if t.jumpClient == nil {
 			s.debugLog("Falha na conexão direta: %v", err)
 			return nil, err
 		}
+		t.jumpClient.Close()
+		s.debugLog("Falha ao criar conexão SSH sobre tunnel: %v", err)
+		return nil, fmt.Errorf("erro ao criar conexão SSH: %w", err)
+	}
+
+	if t.jumpClient == nil {
 		s.debugLog("Conexão direta estabelecida")
-		return client, nil
+	} else {
+		s.debugLog("Tunnel estabelecido com sucesso")
+	}
+	return client, nil
+}
+
+// transport é a conexão de rede com o host, antes do handshake SSH
+type transport struct {
+	conn       net.Conn
+	jumpClient *ssh.Client // Cliente do Jump Host que mantém o tunnel (nil para conexão direta)
+	address    HostAddress
+}
+
+// openTransport abre a conexão com um endereço: TCP direto ou tunnel pelo Jump Host
+// O handshake com o Jump Host usa handshakeCtx (pode aguardar a senha do usuário)
+func (s *SSHConnection) openTransport(connectCtx, handshakeCtx context.Context, address HostAddress) (*transport, error) {
+	target := address.hostPort()
+
+	// Conexão direta se não usar Jump Host
+	if address.JumpHost == nil {
+		s.debugLog("Conectando diretamente a %s...", target)
+		conn, err := dialTCP(connectCtx, target)
+		if err != nil {
+			s.debugLog("Falha na conexão direta: %v", err)
+			return nil, s.connectError(connectCtx, err)
+		}
+		return &transport{conn: conn, address: address}, nil
 	}
 
 	// Cria métodos de autenticação específicos para o Jump Host
-	s.debugLog("Preparando autenticação do Jump Host: %s (%s@%s:%d)", s.JumpHost.Name, s.JumpHost.User, s.JumpHost.Host, s.JumpHost.Port)
-	jumpAuthMethods := s.createAuthMethods(s.JumpHostSSHKeys, fmt.Sprintf("%s@%s (Jump Host)", s.JumpHost.User, s.JumpHost.Host))
+	jumpHost := address.JumpHost
+	s.debugLog("Preparando autenticação do Jump Host: %s (%s@%s:%d)", jumpHost.Name, jumpHost.User, jumpHost.Host, jumpHost.Port)
+	jumpAuthMethods := s.createAuthMethods(address.JumpHostSSHKeys, address.JumpHostPasswordSource, fmt.Sprintf("%s@%s (Jump Host)", jumpHost.User, jumpHost.Host))
 
 	// Cria configuração separada para Jump Host
 	jumpConfig := &ssh.ClientConfig{
-		User:            s.JumpHost.User,
+		User:            jumpHost.User,
 		Auth:            jumpAuthMethods,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 	}
 
 	// Conecta ao Jump Host
-	jumpAddress := fmt.Sprintf("%s:%d", s.JumpHost.Host, s.JumpHost.Port)
+	jumpAddress := fmt.Sprintf("%s:%d", jumpHost.Host, jumpHost.Port)
 	s.debugLog("Conectando ao Jump Host %s...", jumpAddress)
-	jumpClient, err := ssh.Dial("tcp", jumpAddress, jumpConfig)
+	jumpConn, err := dialTCP(connectCtx, jumpAddress)
 	if err != nil {
 		s.debugLog("Falha na conexão ao Jump Host: %v", err)
-		return nil, fmt.Errorf("erro ao conectar ao Jump Host %s: %w", s.JumpHost.Name, err)
+		return nil, fmt.Errorf("erro ao conectar ao Jump Host %s: %w", jumpHost.Name, s.connectError(connectCtx, err))
 	}
-	s.debugLog("Jump Host conectado, criando tunnel para %s...", address)
-
-	// Conecta ao host final através do Jump Host
-	conn, err := jumpClient.Dial("tcp", address)
+	jumpClient, err := newClient(handshakeCtx, jumpConn, jumpAddress, jumpConfig)
 	if err != nil {
-		jumpClient.Close()
-		s.debugLog("Falha ao criar tunnel: %v", err)
-		return nil, fmt.Errorf("erro ao conectar ao host através do Jump Host: %w", err)
+		s.debugLog("Falha na conexão ao Jump Host: %v", err)
+		return nil, fmt.Errorf("erro ao conectar ao Jump Host %s: %w", jumpHost.Name, s.connectError(handshakeCtx, err))
 	}
+	s.debugLog("Jump Host conectado, criando tunnel para %s...", target)
 
-	// Cria o cliente SSH sobre a conexão do Jump Host (com config do target)
-	ncc, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	// Conecta ao host final através do Jump Host
+	conn, err := jumpClient.DialContext(connectCtx, "tcp", target)
 	if err != nil {
-		conn.Close()
 		jumpClient.Close()
-		s.debugLog("Falha ao criar conexão SSH sobre tunnel: %v", err)
-		return nil, fmt.Errorf("erro ao criar conexão SSH: %w", err)
+		s.debugLog("Falha ao criar tunnel: %v", err)
+		return nil, fmt.Errorf("erro ao conectar ao host através do Jump Host: %w", s.connectError(connectCtx, err))
 	}
-
-	s.debugLog("Tunnel estabelecido com sucesso")
-	return ssh.NewClient(ncc, chans, reqs), nil
+	return &transport{conn: conn, jumpClient: jumpClient, address: address}, nil
 }
 
 // startInteractiveSession inicia uma sessão SSH interativa
@@ -344,6 +448,13 @@ func (s *SSHConnection) startInteractiveSession(session *ssh.Session) error {
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
 
+	// Variáveis das rules: o servidor pode recusar (AcceptEnv do sshd), o que não impede a sessão
+	for _, name := range slices.Sorted(maps.Keys(s.Env)) {
+		if err := session.Setenv(name, s.Env[name]); err != nil {
+			s.debugLog("Servidor recusou a variável %s (AcceptEnv): %v", name, err)
+		}
+	}
+
 	// Monitora mudanças no tamanho do terminal
 	go s.monitorTerminalResize(session, fd)
 
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// stdinBroadcaster lê uma entrada (ex: stdin local) uma única vez e a distribui para vários leitores.
+// Os dados são gravados em um arquivo temporário: cada host lê no seu próprio ritmo, hosts lentos
+// não bloqueiam os demais e hosts de lotes posteriores recebem a entrada completa desde o início.
+type stdinBroadcaster struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	file   *os.File
+	size   int64 // Bytes já gravados no arquivo temporário
+	done   bool  // A entrada chegou ao fim (EOF ou erro)
+	err    error // Erro de leitura da entrada (nil em EOF)
+	closed bool
+}
+
+// newStdinBroadcaster cria o broadcaster e começa a consumir src em segundo plano
+func newStdinBroadcaster(src io.Reader) (*stdinBroadcaster, error) {
+	file, err := os.CreateTemp("", "sc-stdin-*")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar buffer temporário para o stdin: %w", err)
+	}
+	// Remove o arquivo imediatamente: ele continua acessível pelo descritor até ser fechado
+	os.Remove(file.Name())
+
+	b := &stdinBroadcaster{file: file}
+	b.cond = sync.NewCond(&b.mu)
+	go b.pump(src)
+	return b, nil
+}
+
+// pump copia src para o arquivo temporário e acorda os leitores a cada bloco recebido
+func (b *stdinBroadcaster) pump(src io.Reader) {
+	buf := make([]byte, 32*1024)
+	var offset int64
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := b.file.WriteAt(buf[:n], offset); werr != nil {
+				b.finish(fmt.Errorf("erro ao gravar buffer do stdin: %w", werr))
+				return
+			}
+			offset += int64(n)
+
+			b.mu.Lock()
+			b.size = offset
+			b.cond.Broadcast()
+			b.mu.Unlock()
+		}
+		if err == io.EOF {
+			b.finish(nil)
+			return
+		}
+		if err != nil {
+			b.finish(fmt.Errorf("erro ao ler stdin: %w", err))
+			return
+		}
+	}
+}
+
+// finish marca o fim da entrada
+func (b *stdinBroadcaster) finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	b.err = err
+	b.cond.Broadcast()
+}
+
+// newReader cria um leitor independente que começa do início da entrada
+func (b *stdinBroadcaster) newReader() *stdinReader {
+	return &stdinReader{b: b}
+}
+
+// Close libera o arquivo temporário e encerra todos os leitores
+func (b *stdinBroadcaster) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	return b.file.Close()
+}
+
+// stdinReader lê a entrada do broadcaster a partir da sua própria posição
+type stdinReader struct {
+	b      *stdinBroadcaster
+	offset int64
+	closed bool // Protegido por b.mu
+}
+
+// Read implementa io.Reader, bloqueando até haver novos dados ou a entrada terminar
+func (r *stdinReader) Read(p []byte) (int, error) {
+	b := r.b
+	b.mu.Lock()
+	for r.offset >= b.size && !b.done && !r.closed && !b.closed {
+		b.cond.Wait()
+	}
+	if r.closed || b.closed {
+		b.mu.Unlock()
+		return 0, io.EOF
+	}
+	available := b.size - r.offset
+	if available <= 0 {
+		err := b.err
+		b.mu.Unlock()
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	b.mu.Unlock()
+
+	if int64(len(p)) > available {
+		p = p[:available]
+	}
+	n, err := b.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Close encerra o leitor, desbloqueando uma leitura em andamento
+func (r *stdinReader) Close() error {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	r.closed = true
+	r.b.cond.Broadcast()
+	return nil
+}
+
+// canPromptPassword indica se há um terminal para pedir uma senha (stdin ou /dev/tty)
+// Em CI, cron ou pipelines sem terminal, senhas opcionais (como a do sudo) não são pedidas
+func canPromptPassword() bool {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return true
+	}
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	tty.Close()
+	return true
+}
+
+// readPassword lê uma senha sem eco
+// Se o stdin não for um terminal (ex: redirecionado para os hosts), a senha é lida de /dev/tty
+func readPassword() ([]byte, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		return term.ReadPassword(fd)
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, fmt.Errorf("stdin não é um terminal e /dev/tty não está disponível: %w", err)
+	}
+	defer tty.Close()
+	return term.ReadPassword(int(tty.Fd()))
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// hostPrefixColors é a paleta usada para diferenciar os hosts no modo --stream
+var hostPrefixColors = []lipgloss.Color{
+	"#7D56F4",
+	"#04B575",
+	"#3C9AE8",
+	"#E5C07B",
+	"#C678DD",
+	"#56B6C2",
+	"#D19A66",
+	"#FF6B6B",
+}
+
+// streamPrinter serializa a escrita de linhas de vários hosts no terminal
+type streamPrinter struct {
+	mu     sync.Mutex
+	width  int
+	styles map[string]lipgloss.Style
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// newStreamPrinter cria um streamPrinter com prefixos alinhados e coloridos para os hosts
+func newStreamPrinter(hosts []string) *streamPrinter {
+	p := &streamPrinter{
+		styles: make(map[string]lipgloss.Style),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	for i, host := range hosts {
+		if len(host) > p.width {
+			p.width = len(host)
+		}
+		p.styles[host] = lipgloss.NewStyle().
+			Foreground(hostPrefixColors[i%len(hostPrefixColors)]).
+			Bold(true)
+	}
+	return p
+}
+
+// writers retorna os writers de stdout e stderr para um host
+func (p *streamPrinter) writers(host string) (*hostLineWriter, *hostLineWriter) {
+	stdout := &hostLineWriter{printer: p, host: host, out: p.stdout, separator: "|"}
+	stderr := &hostLineWriter{printer: p, host: host, out: p.stderr, separator: "!"}
+	return stdout, stderr
+}
+
+// printLine escreve uma linha já completa com o prefixo do host
+func (p *streamPrinter) printLine(out io.Writer, host, separator string, line []byte) {
+	style, ok := p.styles[host]
+	if !ok {
+		style = lipgloss.NewStyle().Bold(true)
+	}
+	prefix := style.Render(fmt.Sprintf("%-*s", p.width, host))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(out, "%s %s %s\n", prefix, separator, line)
+}
+
+// hostLineWriter acumula a saída de um host e emite uma linha prefixada a cada '\n'
+type hostLineWriter struct {
+	printer   *streamPrinter
+	host      string
+	out       io.Writer
+	separator string
+	buf       []byte
+}
+
+// Write implementa io.Writer
+func (w *hostLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		w.printer.printLine(w.out, w.host, w.separator, bytes.TrimRight(w.buf[:idx], "\r"))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emite o conteúdo restante que não terminou com '\n'
+func (w *hostLineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.printer.printLine(w.out, w.host, w.separator, w.buf)
+		w.buf = nil
+	}
+}
+
+// displayStreamSummary exibe o resumo por host ao final do modo --stream
+func displayStreamSummary(results []HostResult, skipped []string, duration time.Duration) {
+	successCount := 0
+	failureCount := 0
+
+	width := len("Host")
+	for _, result := range results {
+		if len(result.Host) > width {
+			width = len(result.Host)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("   %-*s  %-9s  %s\n", width, "Host", "Exit Code", "Tempo")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	for _, result := range results {
+		icon := "✅"
+		if result.Failed() {
+			icon = "❌"
+			failureCount++
+		} else {
+			successCount++
+		}
+		fmt.Printf("%s %-*s  %-9d  %.2fs\n", icon, width, result.Host, result.ExitCode, result.Duration.Seconds())
+		if result.Error != "" {
+			fmt.Printf("   Erro: %s\n", result.Error)
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("⏭️  %d host(s) não executado(s): %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("📊 Resumo: %d sucesso(s), %d falha(s), %d total | ⏱️  Tempo: %.2fs\n", successCount, failureCount, len(results), duration.Seconds())
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errSudoAuth identifica falhas de autenticação do sudo (distintas da autenticação SSH)
+var errSudoAuth = errors.New("falha de autenticação no sudo")
+
+// shellQuote protege uma string para uso como um único argumento em /bin/sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sudoSession envolve a execução de um comando com sudo em modo não interativo.
+// O prompt de senha e o início do comando são identificados por marcadores únicos no stderr,
+// que são removidos da saída exibida ao usuário.
+type sudoSession struct {
+	user     string
+	password string
+	prompt   []byte // Marcador usado como prompt de senha (sudo -p)
+	started  []byte // Marcador emitido quando o comando começa a executar (após a autenticação)
+
+	dst     io.Writer // Destino real do stderr
+	buf     []byte    // Dados retidos que podem ser o início de um marcador
+	running bool      // O marcador de início já foi visto (repasse direto)
+	prompts int       // Quantidade de prompts de senha recebidos
+
+	authErr error // Falha de autenticação detectada (senha incorreta ou ausente)
+
+	onPrompt func(answer string, ok bool) // Responde ao prompt (ok=false fecha o stdin)
+	onStart  func()                       // Chamado quando o comando começa a executar
+}
+
+// newSudoSession cria uma sessão de sudo com marcadores aleatórios
+func newSudoSession(user, password string) *sudoSession {
+	id := make([]byte, 8)
+	rand.Read(id)
+	token := strings.ToUpper(hex.EncodeToString(id))
+	return &sudoSession{
+		user:     user,
+		password: password,
+		prompt:   []byte("SC_SUDO_PROMPT_" + token + ":"),
+		started:  []byte("SC_SUDO_OK_" + token + "\n"),
+	}
+}
+
+// wrap monta o comando executado via sudo
+func (s *sudoSession) wrap(command string) string {
+	var b strings.Builder
+	b.WriteString("sudo -S -p ")
+	b.WriteString(shellQuote(string(s.prompt)))
+	if s.user != "" {
+		b.WriteString(" -u ")
+		b.WriteString(shellQuote(s.user))
+	}
+	b.WriteString(" -- /bin/sh -c ")
+	b.WriteString(shellQuote(fmt.Sprintf("echo %s >&2; %s", bytes.TrimSuffix(s.started, []byte("\n")), command)))
+	return b.String()
+}
+
+// Write implementa io.Writer para o stderr da sessão, detectando e removendo os marcadores
+func (s *sudoSession) Write(p []byte) (int, error) {
+	if s.running {
+		return s.dst.Write(p)
+	}
+
+	s.buf = append(s.buf, p...)
+	for {
+		if i := bytes.Index(s.buf, s.prompt); i >= 0 {
+			if err := s.emit(s.buf[:i]); err != nil {
+				return 0, err
+			}
+			s.buf = s.buf[i+len(s.prompt):]
+			s.handlePrompt()
+			continue
+		}
+
+		if i := bytes.Index(s.buf, s.started); i >= 0 {
+			if err := s.emit(s.buf[:i]); err != nil {
+				return 0, err
+			}
+			rest := s.buf[i+len(s.started):]
+			s.buf = nil
+			s.running = true
+			if s.onStart != nil {
+				s.onStart()
+			}
+			if err := s.emit(rest); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+
+		// Retém um possível início de marcador até o próximo bloco
+		keep := partialMarkerLen(s.buf, s.prompt, s.started)
+		if err := s.emit(s.buf[:len(s.buf)-keep]); err != nil {
+			return 0, err
+		}
+		s.buf = append([]byte(nil), s.buf[len(s.buf)-keep:]...)
+		return len(p), nil
+	}
+}
+
+// Flush escreve os dados retidos ao final da sessão
+func (s *sudoSession) Flush() {
+	if len(s.buf) > 0 {
+		s.emit(s.buf)
+		s.buf = nil
+	}
+}
+
+// emit escreve no destino real do stderr
+func (s *sudoSession) emit(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := s.dst.Write(p)
+	return err
+}
+
+// handlePrompt responde ao prompt de senha do sudo
+// A senha é enviada uma única vez: um segundo prompt indica senha incorreta
+func (s *sudoSession) handlePrompt() {
+	s.prompts++
+	switch {
+	case s.prompts == 1 && s.password != "":
+		s.onPrompt(s.password, true)
+	case s.prompts == 1:
+		s.authErr = fmt.Errorf("%w: senha solicitada, mas nenhuma foi informada", errSudoAuth)
+		s.onPrompt("", false)
+	default:
+		s.authErr = fmt.Errorf("%w: senha incorreta", errSudoAuth)
+		s.onPrompt("", false)
+	}
+}
+
+// partialMarkerLen retorna o tamanho do maior sufixo de buf que é prefixo de algum marcador
+func partialMarkerLen(buf []byte, markers ...[]byte) int {
+	longest := 0
+	for _, marker := range markers {
+		for n := min(len(marker)-1, len(buf)); n > longest; n-- {
+			if bytes.HasSuffix(buf, marker[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
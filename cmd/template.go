@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"text/template"
+
+	"github.com/alexeiev/sshControl/config"
+)
+
+// templateFuncs são as funções disponíveis nos templates (comandos, caminhos e runbooks)
+var templateFuncs = template.FuncMap{
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"trim":      strings.TrimSpace,
+}
+
+// isTemplate indica se o texto precisa ser renderizado por host
+func isTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// parseTemplate compila um template (chaves ausentes são erro, para detectar vars e registers inexistentes)
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("sc").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// renderTemplate renderiza o texto com os dados do host (texto sem {{ é retornado sem alteração)
+func renderTemplate(text string, data map[string]any) (string, error) {
+	if !isTemplate(text) {
+		return text, nil
+	}
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// ValidateTemplate verifica a sintaxe de um comando ou caminho com template antes da execução
+func ValidateTemplate(text string) error {
+	if !isTemplate(text) {
+		return nil
+	}
+	_, err := parseTemplate(text)
+	return err
+}
+
+// hostTemplateData monta os dados de template de um host:
+// {{.Name}}, {{.Host}}, {{.Port}}, {{.User}}, {{.Tags}} e {{.Vars.nome}}
+// Hosts fora do config.yaml não têm tags nem vars
+func hostTemplateData(cfg *config.ConfigFile, hostArg string, target *hostTarget) map[string]any {
+	data := map[string]any{
+		"Name": hostArg,
+		"Host": target.Hostname,
+		"Port": target.Port,
+		"User": target.User,
+		"Tags": []string{},
+		"Vars": map[string]string{},
+	}
+	if target.ConfigHost != nil {
+		data["Tags"] = target.ConfigHost.Tags
+		data["Vars"] = cfg.HostVars(target.ConfigHost)
+	}
+	return data
+}
+
+// RenderHostTemplate renderiza um comando ou caminho com os dados de um host
+func RenderHostTemplate(cfg *config.ConfigFile, hostArg string, selectedUser *config.User, text string) (string, error) {
+	if !isTemplate(text) {
+		return text, nil
+	}
+	target, err := resolveHostTarget(context.Background(), cfg, hostArg, selectedUser)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(text, hostTemplateData(cfg, hostArg, target))
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// killGracePeriod é o tempo de espera pelo encerramento da sessão após o SIGKILL
+// Se o host não responder nesse intervalo, a conexão inteira é fechada
+const killGracePeriod = 2 * time.Second
+
+// Timeouts agrupa os limites de tempo aplicados às conexões
+type Timeouts struct {
+	Connect time.Duration // Tempo máximo para conectar (TCP + handshake SSH), 0 = sem limite
+	Command time.Duration // Tempo máximo de execução do comando ou da transferência, 0 = sem limite
+
+	ConnectFlag bool // Connect veio da linha de comando (tem precedência sobre as rules)
+	CommandFlag bool // Command veio da linha de comando (tem precedência sobre as rules)
+}
+
+// Validate verifica se os limites de tempo são válidos
+func (t Timeouts) Validate() error {
+	if t.Connect < 0 {
+		return fmt.Errorf("--connect-timeout não pode ser negativo")
+	}
+	if t.Command < 0 {
+		return fmt.Errorf("--command-timeout não pode ser negativo")
+	}
+	return nil
+}
+
+// withTimeout deriva um contexto com o limite informado (sem limite quando d <= 0)
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// contextError converte o encerramento de um contexto em um erro classificado
+// (timeout quando o limite foi excedido, canceled quando o usuário interrompeu)
+func contextError(ctx context.Context, operation string, limit time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return withFailureClass(FailureTimeout, fmt.Errorf("tempo limite de %s excedido (%s)", operation, limit))
+	}
+	return withFailureClass(FailureCanceled, fmt.Errorf("%s cancelada", operation))
+}
+
+// dialTCP abre a conexão TCP respeitando o contexto
+func dialTCP(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// newClient executa o handshake SSH sobre conn e cria o cliente
+// Se o contexto for encerrado durante o handshake, a conexão é fechada para interrompê-lo
+func newClient(ctx context.Context, conn net.Conn, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	ncc, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if !stop() {
+		// O contexto foi encerrado durante o handshake
+		if err == nil {
+			ncc.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(ncc, chans, reqs), nil
+}
+
+// connectError substitui o erro de conexão por um erro de tempo limite/cancelamento quando o contexto foi encerrado
+func (s *SSHConnection) connectError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return contextError(ctx, "conexão", s.ConnectTimeout)
+	}
+	return err
+}
+
+// runSession executa s.Command na sessão e aguarda o término respeitando o contexto e s.CommandTimeout.
+// Se o limite for excedido (ou a execução cancelada), o processo remoto recebe SIGKILL e a sessão é fechada.
+func (s *SSHConnection) runSession(ctx context.Context, client *ssh.Client, session *ssh.Session) error {
+	runCtx, cancel := withTimeout(ctx, s.CommandTimeout)
+	defer cancel()
+
+	command := envPrefix(s.Env) + s.Command
+	var sudo *sudoSession
+
+	// O stdin é copiado por conta própria (e não via session.Stdin) para que session.Wait
+	// não fique aguardando uma entrada que ainda não terminou após o fim do comando
+	if s.Sudo {
+		stdinPipe, err := session.StdinPipe()
+		if err != nil {
+			return err
+		}
+		sudo = newSudoSession(s.SudoUser, s.SudoPassword)
+		sudo.dst = session.Stderr
+		if sudo.dst == nil {
+			sudo.dst = io.Discard
+		}
+		session.Stderr = sudo
+		command = sudo.wrap(command)
+
+		// A senha vai para o stdin apenas quando o sudo pede; a entrada do usuário
+		// só é repassada depois que o comando começou a executar
+		sudo.onPrompt = func(answer string, ok bool) {
+			if !ok {
+				stdinPipe.Close()
+				return
+			}
+			io.WriteString(stdinPipe, answer+"\n")
+		}
+		sudo.onStart = func() {
+			go func() {
+				if s.Stdin != nil {
+					io.Copy(stdinPipe, s.Stdin)
+				}
+				stdinPipe.Close()
+			}()
+		}
+	} else if s.Stdin != nil {
+		stdinPipe, err := session.StdinPipe()
+		if err != nil {
+			return err
+		}
+		go func() {
+			io.Copy(stdinPipe, s.Stdin)
+			stdinPipe.Close()
+		}()
+	}
+
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if sudo != nil {
+			sudo.Flush()
+			if sudo.authErr != nil {
+				return withFailureClass(FailureAuth, sudo.authErr)
+			}
+		}
+		return err
+	case <-runCtx.Done():
+	}
+
+	s.debugLog("Encerrando processo remoto: %v", runCtx.Err())
+	if err := session.Signal(ssh.SIGKILL); err != nil {
+		s.debugLog("Falha ao enviar SIGKILL: %v", err)
+	}
+	session.Close()
+
+	// Aguarda o fim da sessão; se o host não responder, derruba a conexão
+	select {
+	case <-done:
+	case <-time.After(killGracePeriod):
+		client.Close()
+		<-done
+	}
+	if sudo != nil {
+		sudo.Flush()
+	}
+
+	return contextError(runCtx, "execução", s.CommandTimeout)
+}
+
+// envPrefix monta os exports das variáveis de ambiente das rules (vazio se não houver)
+// Os exports vão no próprio comando porque o sshd costuma recusar variáveis fora do AcceptEnv
+func envPrefix(env map[string]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		fmt.Fprintf(&b, "export %s=%s; ", name, shellQuote(env[name]))
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alexeiev/sshControl/config"
+	"golang.org/x/term"
+)
+
+// vaultPassphraseAttempts é o número de tentativas da senha do cofre
+const vaultPassphraseAttempts = 3
+
+// vaultSession mantém o cofre aberto em memória durante a execução:
+// a senha mestra é pedida uma única vez, mesmo com vários hosts conectando em paralelo
+var vaultSession struct {
+	mu    sync.Mutex
+	vault *config.Vault
+	err   error
+}
+
+// vaultSecret retorna um segredo do cofre, pedindo a senha mestra na primeira chamada
+func vaultSecret(name string) (string, error) {
+	vaultSession.mu.Lock()
+	defer vaultSession.mu.Unlock()
+
+	if vaultSession.vault == nil && vaultSession.err == nil {
+		vaultSession.vault, vaultSession.err = UnlockVault()
+	}
+	if vaultSession.err != nil {
+		return "", vaultSession.err
+	}
+	secret, ok := vaultSession.vault.Get(name)
+	if !ok {
+		return "", fmt.Errorf("segredo '%s' não encontrado no cofre (use 'sc vault set %s')", name, name)
+	}
+	return secret, nil
+}
+
+// vaultOpen indica se o cofre já foi aberto nesta execução
+func vaultOpen() bool {
+	vaultSession.mu.Lock()
+	defer vaultSession.mu.Unlock()
+	return vaultSession.vault != nil
+}
+
+// UnlockVault abre o cofre pedindo a senha mestra no terminal
+func UnlockVault() (*config.Vault, error) {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	path := config.VaultPath(configPath)
+	if !config.VaultExists(path) {
+		return nil, fmt.Errorf("cofre não encontrado em %s (crie com 'sc vault set <nome>')", path)
+	}
+
+	for attempt := 1; ; attempt++ {
+		fmt.Fprint(os.Stderr, "🔐 Senha do cofre: ")
+		passphrase, err := readPassword()
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler senha do cofre: %w", err)
+		}
+		vault, err := config.OpenVault(path, string(passphrase))
+		if errors.Is(err, config.ErrVaultPassphrase) && attempt < vaultPassphraseAttempts {
+			fmt.Fprintln(os.Stderr, "❌ Senha incorreta, tente novamente.")
+			continue
+		}
+		return vault, err
+	}
+}
+
+// CreateVaultInteractive cria o cofre pedindo a nova senha mestra (com confirmação)
+func CreateVaultInteractive() (*config.Vault, error) {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	path := config.VaultPath(configPath)
+	fmt.Fprintf(os.Stderr, "🔐 Criando o cofre em %s\n", path)
+	passphrase, err := readConfirmedPassword("Nova senha do cofre: ")
+	if err != nil {
+		return nil, err
+	}
+	return config.CreateVault(path, passphrase)
+}
+
+// ReadSecret lê o valor de um segredo: do terminal (com confirmação) ou, com stdin redirecionado, do stdin
+func ReadSecret(name string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("erro ao ler segredo do stdin: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return readConfirmedPassword(fmt.Sprintf("Senha para '%s': ", name))
+}
+
+// readConfirmedPassword lê uma senha duas vezes e confere se são iguais
+func readConfirmedPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	first, err := readPassword()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler senha: %w", err)
+	}
+	fmt.Fprint(os.Stderr, "Confirme: ")
+	second, err := readPassword()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler senha: %w", err)
+	}
+	if string(first) != string(second) {
+		return "", fmt.Errorf("as senhas não conferem")
+	}
+	if len(first) == 0 {
+		return "", fmt.Errorf("senha vazia")
+	}
+	return string(first), nil
+}
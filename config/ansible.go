@@ -0,0 +1,765 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Interoperabilidade com inventários do Ansible (INI e YAML):
+//
+//	grupos               -> tags (um host de um grupo filho também recebe as tags dos grupos pais)
+//	ansible_host/port    -> host/port
+//	ansible_user         -> user
+//	ProxyJump em ansible_ssh_common_args -> jump (jump host já cadastrado em config.jump_hosts)
+//	demais vars do host  -> vars
+//	vars de grupo        -> tags.<grupo>.vars
+
+// ansibleConnectionVars são as vars do Ansible convertidas em campos do host (não viram vars)
+var ansibleConnectionVars = []string{
+	"ansible_host", "ansible_ssh_host",
+	"ansible_port", "ansible_ssh_port",
+	"ansible_user", "ansible_ssh_user",
+	"ansible_ssh_common_args",
+}
+
+// proxyJumpPattern encontra o ProxyJump em ansible_ssh_common_args (-o ProxyJump=... ou -J ...)
+var proxyJumpPattern = regexp.MustCompile(`(?:ProxyJump[= ]|-J\s*)['"]?([^\s'"]+)`)
+
+// ansibleGroupPattern são os caracteres aceitos pelo Ansible em nomes de grupos
+var ansibleGroupPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// AnsibleHost é um host lido de um inventário do Ansible
+type AnsibleHost struct {
+	Host      Host
+	ProxyJump string // Destino do ProxyJump ([user@]host[:port]), resolvido em ImportAnsible
+}
+
+// AnsibleInventory é o resultado da leitura de um inventário do Ansible
+type AnsibleInventory struct {
+	Hosts    []AnsibleHost
+	Groups   map[string]TagConfig // Vars de grupo (viram vars de tag)
+	Warnings []string
+}
+
+// AnsibleImportResult resume a importação de um inventário do Ansible
+type AnsibleImportResult struct {
+	Added    []Host
+	Skipped  []string // Hosts que já existiam
+	TagVars  int      // Vars de grupo adicionadas às tags
+	Warnings []string
+}
+
+// ansibleGroup é um grupo durante a leitura do inventário
+type ansibleGroup struct {
+	vars     map[string]string
+	children []string
+}
+
+// ansibleParser acumula hosts e grupos na ordem em que aparecem no inventário
+type ansibleParser struct {
+	hosts      map[string]map[string]string // vars de cada host
+	hostGroups map[string][]string          // grupos em que cada host aparece diretamente
+	hostOrder  []string
+	groups     map[string]*ansibleGroup
+	warnings   []string
+}
+
+func newAnsibleParser() *ansibleParser {
+	return &ansibleParser{
+		hosts:      make(map[string]map[string]string),
+		hostGroups: make(map[string][]string),
+		groups:     make(map[string]*ansibleGroup),
+	}
+}
+
+// group retorna o grupo, criando-o se necessário
+func (p *ansibleParser) group(name string) *ansibleGroup {
+	g, ok := p.groups[name]
+	if !ok {
+		g = &ansibleGroup{vars: make(map[string]string)}
+		p.groups[name] = g
+	}
+	return g
+}
+
+// addHost registra um host em um grupo, mesclando suas vars
+func (p *ansibleParser) addHost(name, group string, vars map[string]string) {
+	if _, ok := p.hosts[name]; !ok {
+		p.hosts[name] = make(map[string]string)
+		p.hostOrder = append(p.hostOrder, name)
+	}
+	maps.Copy(p.hosts[name], vars)
+	if group != "" && group != "all" && group != "ungrouped" {
+		p.group(group)
+		if !slices.Contains(p.hostGroups[name], group) {
+			p.hostGroups[name] = append(p.hostGroups[name], group)
+		}
+	}
+}
+
+// ReadAnsibleInventory lê um inventário do Ansible em INI ou YAML (detectado pelo conteúdo)
+func ReadAnsibleInventory(filename string) (*AnsibleInventory, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler inventário: %w", err)
+	}
+
+	p := newAnsibleParser()
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		err = p.parseYAML(doc.Content[0])
+	} else {
+		err = p.parseINI(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.inventory(), nil
+}
+
+// parseINI lê o formato INI: [grupo], [grupo:vars] e [grupo:children]
+func (p *ansibleParser) parseINI(r io.Reader) error {
+	section, kind := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind, _ = strings.Cut(line[1:len(line)-1], ":")
+			switch kind {
+			case "":
+				kind = "hosts"
+			case "vars", "children":
+			default:
+				return fmt.Errorf("linha %d: seção inválida '%s'", lineNum, line)
+			}
+			p.group(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return fmt.Errorf("linha %d: esperado chave=valor em [%s:vars]", lineNum, section)
+			}
+			p.group(section).vars[strings.TrimSpace(key)] = unquoteAnsible(strings.TrimSpace(value))
+		case "children":
+			child := strings.Fields(line)[0]
+			p.group(child)
+			g := p.group(section)
+			if !slices.Contains(g.children, child) {
+				g.children = append(g.children, child)
+			}
+		default:
+			fields, err := splitAnsibleLine(line)
+			if err != nil {
+				return fmt.Errorf("linha %d: %w", lineNum, err)
+			}
+			vars := make(map[string]string)
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return fmt.Errorf("linha %d: esperado chave=valor, encontrado '%s'", lineNum, field)
+				}
+				vars[key] = value
+			}
+			names, err := expandAnsibleHost(fields[0], vars)
+			if err != nil {
+				return fmt.Errorf("linha %d: %w", lineNum, err)
+			}
+			for _, name := range names {
+				p.addHost(name, section, vars)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseYAML lê o formato YAML: grupos com hosts, vars e children
+func (p *ansibleParser) parseYAML(root *yaml.Node) error {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if err := p.parseYAMLGroup(root.Content[i].Value, root.Content[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseYAMLGroup lê um grupo do formato YAML (recursivo nos children)
+func (p *ansibleParser) parseYAMLGroup(name string, node *yaml.Node) error {
+	p.group(name)
+	if node.Kind != yaml.MappingNode {
+		return nil // Grupo vazio (ex: "web:" sem conteúdo)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "hosts":
+			for j := 0; value.Kind == yaml.MappingNode && j+1 < len(value.Content); j += 2 {
+				hostName := value.Content[j].Value
+				vars := p.yamlVars(fmt.Sprintf("host '%s'", hostName), value.Content[j+1])
+				names, err := expandAnsibleHost(hostName, vars)
+				if err != nil {
+					return fmt.Errorf("linha %d: %w", value.Content[j].Line, err)
+				}
+				for _, hostName := range names {
+					p.addHost(hostName, name, vars)
+				}
+			}
+		case "vars":
+			maps.Copy(p.group(name).vars, p.yamlVars(fmt.Sprintf("grupo '%s'", name), value))
+		case "children":
+			for j := 0; value.Kind == yaml.MappingNode && j+1 < len(value.Content); j += 2 {
+				child := value.Content[j].Value
+				g := p.group(name)
+				if !slices.Contains(g.children, child) {
+					g.children = append(g.children, child)
+				}
+				if err := p.parseYAMLGroup(child, value.Content[j+1]); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("linha %d: chave '%s' inválida no grupo '%s' (use hosts, vars ou children)", key.Line, key.Value, name)
+		}
+	}
+	return nil
+}
+
+// yamlVars converte um mapping de vars em strings; valores que não são escalares são ignorados com aviso
+func (p *ansibleParser) yamlVars(owner string, node *yaml.Node) map[string]string {
+	vars := make(map[string]string)
+	if node.Kind != yaml.MappingNode {
+		return vars
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			p.warnings = append(p.warnings, fmt.Sprintf("%s: var '%s' não é um valor simples e foi ignorada", owner, key))
+			continue
+		}
+		vars[key] = value.Value
+	}
+	return vars
+}
+
+// splitAnsibleLine divide uma linha de host do INI respeitando aspas simples e duplas
+func splitAnsibleLine(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	inField, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		case r == '#' && !inField:
+			// Comentário no fim da linha
+			return fields, nil
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("aspas não fechadas")
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// unquoteAnsible remove aspas em volta de um valor de [grupo:vars]
+func unquoteAnsible(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// ansibleRangePattern encontra intervalos do Ansible: [01:50], [a:f] e [1:9:2]
+var ansibleRangePattern = regexp.MustCompile(`\[([0-9]+|[a-z]):([0-9]+|[a-z])(?::([0-9]+))?\]`)
+
+// expandAnsibleHost expande intervalos do nome e separa a porta de "host:porta" (gravada em vars)
+func expandAnsibleHost(pattern string, vars map[string]string) ([]string, error) {
+	if name, port, ok := strings.Cut(pattern, ":"); ok && !strings.Contains(pattern, "[") && !strings.Contains(port, ":") {
+		if _, err := strconv.Atoi(port); err == nil {
+			pattern = name
+			if _, exists := vars["ansible_port"]; !exists {
+				vars["ansible_port"] = port
+			}
+		}
+	}
+
+	loc := ansibleRangePattern.FindStringSubmatchIndex(pattern)
+	if loc == nil {
+		return []string{pattern}, nil
+	}
+	prefix, rest := pattern[:loc[0]], pattern[loc[1]:]
+	startText, endText := pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]]
+	step := 1
+	if loc[6] >= 0 {
+		step, _ = strconv.Atoi(pattern[loc[6]:loc[7]])
+		if step < 1 {
+			return nil, fmt.Errorf("intervalo inválido em '%s'", pattern)
+		}
+	}
+
+	suffixes, err := expandAnsibleHost(rest, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	if start, err := strconv.Atoi(startText); err == nil {
+		end, err := strconv.Atoi(endText)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("intervalo inválido em '%s'", pattern)
+		}
+		width := 0
+		if len(startText) > 1 && startText[0] == '0' {
+			width = len(startText)
+		}
+		for n := start; n <= end; n += step {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+	} else {
+		if len(endText) != 1 || endText[0] < startText[0] {
+			return nil, fmt.Errorf("intervalo inválido em '%s'", pattern)
+		}
+		for c := startText[0]; c <= endText[0]; c += byte(step) {
+			values = append(values, string(c))
+		}
+	}
+	if len(values)*len(suffixes) > maxRangeHosts {
+		return nil, fmt.Errorf("intervalo em '%s' gera mais de %d hosts", pattern, maxRangeHosts)
+	}
+
+	var names []string
+	for _, value := range values {
+		for _, suffix := range suffixes {
+			names = append(names, prefix+value+suffix)
+		}
+	}
+	return names, nil
+}
+
+// groupDepths calcula a profundidade de cada grupo a partir de all (grupos sem pai têm profundidade 1)
+func (p *ansibleParser) groupDepths() map[string]int {
+	depth := make(map[string]int)
+	var visit func(name string, d int, path []string)
+	visit = func(name string, d int, path []string) {
+		if slices.Contains(path, name) || depth[name] >= d {
+			return // Ciclo ou profundidade já conhecida
+		}
+		depth[name] = d
+		for _, child := range p.group(name).children {
+			visit(child, d+1, append(path, name))
+		}
+	}
+	for name := range p.groups {
+		if name != "all" {
+			visit(name, 1, nil)
+		}
+	}
+	return depth
+}
+
+// ancestors retorna o grupo e todos os seus grupos pais
+func (p *ansibleParser) ancestors(group string) []string {
+	parents := make(map[string][]string)
+	for name, g := range p.groups {
+		for _, child := range g.children {
+			parents[child] = append(parents[child], name)
+		}
+	}
+	var result []string
+	queue := []string{group}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if slices.Contains(result, name) || name == "all" || name == "ungrouped" {
+			continue
+		}
+		result = append(result, name)
+		parentNames := parents[name]
+		sort.Strings(parentNames)
+		queue = append(queue, parentNames...)
+	}
+	return result
+}
+
+// inventory converte os hosts e grupos lidos para o formato do sshControl
+func (p *ansibleParser) inventory() *AnsibleInventory {
+	inv := &AnsibleInventory{Groups: make(map[string]TagConfig), Warnings: p.warnings}
+	depth := p.groupDepths()
+
+	for _, name := range p.hostOrder {
+		// Tags: grupos do host e seus pais, na ordem em que aparecem
+		var tags []string
+		for _, group := range p.hostGroups[name] {
+			for _, tag := range p.ancestors(group) {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		// Vars efetivas na precedência do Ansible: all < grupos (do mais raso ao mais profundo) < host
+		groups := slices.Clone(tags)
+		sort.SliceStable(groups, func(i, j int) bool {
+			if depth[groups[i]] != depth[groups[j]] {
+				return depth[groups[i]] < depth[groups[j]]
+			}
+			return groups[i] < groups[j]
+		})
+		effective := make(map[string]string)
+		if all, ok := p.groups["all"]; ok {
+			maps.Copy(effective, all.vars)
+		}
+		groupKeys := make(map[string]bool)
+		for _, group := range groups {
+			maps.Copy(effective, p.groups[group].vars)
+			for key := range p.groups[group].vars {
+				groupKeys[key] = true
+			}
+		}
+		maps.Copy(effective, p.hosts[name])
+
+		host := Host{Name: name, Host: name, Port: 22, Tags: tags}
+		if value := firstVar(effective, "ansible_host", "ansible_ssh_host"); value != "" {
+			host.Host = value
+		}
+		if value := firstVar(effective, "ansible_port", "ansible_ssh_port"); value != "" {
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				inv.Warnings = append(inv.Warnings, fmt.Sprintf("host '%s': ansible_port inválido '%s', usando 22", name, value))
+			} else {
+				host.Port = port
+			}
+		}
+		host.User = firstVar(effective, "ansible_user", "ansible_ssh_user")
+
+		ansibleHost := AnsibleHost{}
+		if match := proxyJumpPattern.FindStringSubmatch(effective["ansible_ssh_common_args"]); match != nil {
+			ansibleHost.ProxyJump = match[1]
+		}
+
+		// Vars do host: as do próprio host e as de all que nenhum grupo do host redefine
+		vars := make(map[string]string)
+		if all, ok := p.groups["all"]; ok {
+			for key, value := range all.vars {
+				if !groupKeys[key] {
+					vars[key] = value
+				}
+			}
+		}
+		maps.Copy(vars, p.hosts[name])
+		for _, key := range ansibleConnectionVars {
+			delete(vars, key)
+		}
+		if len(vars) > 0 {
+			host.Vars = vars
+		}
+
+		ansibleHost.Host = host
+		inv.Hosts = append(inv.Hosts, ansibleHost)
+	}
+
+	// Vars de grupo viram vars de tag (vars de conexão já foram aplicadas aos hosts)
+	for name, g := range p.groups {
+		if name == "all" || name == "ungrouped" {
+			continue
+		}
+		vars := maps.Clone(g.vars)
+		for _, key := range ansibleConnectionVars {
+			delete(vars, key)
+		}
+		if len(vars) > 0 {
+			inv.Groups[name] = TagConfig{Vars: vars}
+		}
+	}
+	return inv
+}
+
+// firstVar retorna o valor da primeira chave definida
+func firstVar(vars map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := vars[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ImportAnsible adiciona os hosts do inventário que ainda não existem (pelo nome) e as vars de grupo
+// O ProxyJump é convertido no jump host cadastrado com o mesmo nome ou endereço
+func (c *ConfigFile) ImportAnsible(inv *AnsibleInventory) AnsibleImportResult {
+	result := AnsibleImportResult{Warnings: slices.Clone(inv.Warnings)}
+	for _, ah := range inv.Hosts {
+		if c.FindHost(ah.Host.Name) != nil {
+			result.Skipped = append(result.Skipped, ah.Host.Name)
+			continue
+		}
+		host := ah.Host
+		if ah.ProxyJump != "" {
+			if jumpHost := c.findJumpHostByTarget(ah.ProxyJump); jumpHost != nil {
+				host.Jump = jumpHost.Name
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("host '%s': ProxyJump '%s' não corresponde a nenhum jump host cadastrado (config.jump_hosts)", host.Name, ah.ProxyJump))
+			}
+		}
+		c.AddHost(host)
+		result.Added = append(result.Added, host)
+	}
+
+	// Vars de grupo: mantém as vars de tag já existentes no config.yaml
+	for _, name := range slices.Sorted(maps.Keys(inv.Groups)) {
+		if c.Tags == nil {
+			c.Tags = make(map[string]TagConfig)
+		}
+		tag := c.Tags[name]
+		if tag.Vars == nil {
+			tag.Vars = make(map[string]string)
+		}
+		for key, value := range inv.Groups[name].Vars {
+			if _, exists := tag.Vars[key]; !exists {
+				tag.Vars[key] = value
+				result.TagVars++
+			}
+		}
+		c.Tags[name] = tag
+	}
+	return result
+}
+
+// findJumpHostByTarget encontra o jump host pelo nome ou pelo destino [user@]host[:port] de um ProxyJump
+func (c *ConfigFile) findJumpHostByTarget(target string) *JumpHost {
+	// Com vários saltos (a,b), o sshControl usa apenas o primeiro
+	target, _, _ = strings.Cut(target, ",")
+	if jumpHost := c.FindJumpHost(target); jumpHost != nil {
+		return jumpHost
+	}
+
+	user, address, hasUser := strings.Cut(target, "@")
+	if !hasUser {
+		user, address = "", target
+	}
+	hostname, portText, hasPort := strings.Cut(address, ":")
+	port := 22
+	if hasPort {
+		port, _ = strconv.Atoi(portText)
+	}
+	for i := range c.Config.JumpHosts {
+		jh := &c.Config.JumpHosts[i]
+		if (jh.Host == hostname || jh.Name == hostname) && jh.Port == port && (user == "" || jh.User == user) {
+			return jh
+		}
+	}
+	return nil
+}
+
+// ansibleGroupName converte uma tag em nome de grupo válido no Ansible (env/prod -> env_prod)
+func ansibleGroupName(tag string) string {
+	name := ansibleGroupPattern.ReplaceAllString(tag, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// ansibleHostVars monta as vars de um host no inventário do Ansible
+func (c *ConfigFile) ansibleHostVars(host *Host) (map[string]string, error) {
+	vars := maps.Clone(host.Vars)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	if host.Host != host.Name {
+		vars["ansible_host"] = host.Host
+	}
+	if host.Port != 0 && host.Port != 22 {
+		vars["ansible_port"] = strconv.Itoa(host.Port)
+	}
+	if host.User != "" {
+		vars["ansible_user"] = host.User
+	}
+	jumpHost, err := c.HostJumpHost(host)
+	if err != nil {
+		return vars, err
+	}
+	if jumpHost != nil {
+		vars["ansible_ssh_common_args"] = fmt.Sprintf("-o ProxyJump=%s@%s:%d", jumpHost.User, jumpHost.Host, jumpHost.Port)
+	}
+	return vars, nil
+}
+
+// ExportAnsible escreve os hosts como inventário do Ansible (format: "ini" ou "yaml")
+// Tags viram grupos e vars de tag viram vars de grupo. Retorna avisos (ex: jump host inexistente, addresses).
+func (c *ConfigFile) ExportAnsible(w io.Writer, format string) ([]string, error) {
+	var warnings []string
+	hostVars := make([]map[string]string, len(c.Hosts))
+	groups := make(map[string][]int)
+	for i := range c.Hosts {
+		vars, err := c.ansibleHostVars(&c.Hosts[i])
+		if err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		hostVars[i] = vars
+		if n := len(c.Hosts[i].Addresses); n > 0 {
+			warnings = append(warnings, fmt.Sprintf("host '%s': %d endereço(s) alternativo(s) (addresses) não exportado(s): o Ansible usa apenas ansible_host", c.Hosts[i].Name, n))
+		}
+		for _, tag := range c.Hosts[i].Tags {
+			name := ansibleGroupName(tag)
+			groups[name] = append(groups[name], i)
+		}
+	}
+	groupVars := make(map[string]map[string]string)
+	for tag, tagConfig := range c.Tags {
+		if len(tagConfig.Vars) > 0 {
+			groupVars[ansibleGroupName(tag)] = tagConfig.Vars
+		}
+	}
+	groupNames := slices.Sorted(maps.Keys(groups))
+	for name := range groupVars {
+		if _, ok := groups[name]; !ok {
+			groupNames = append(groupNames, name)
+		}
+	}
+	sort.Strings(groupNames)
+
+	switch format {
+	case "ini":
+		return warnings, c.writeAnsibleINI(w, hostVars, groups, groupVars, groupNames)
+	case "yaml":
+		return warnings, c.writeAnsibleYAML(w, hostVars, groups, groupVars, groupNames)
+	}
+	return warnings, fmt.Errorf("formato '%s' inválido (use ini ou yaml)", format)
+}
+
+// writeAnsibleINI escreve o inventário no formato INI (vars do host na primeira linha em que ele aparece)
+func (c *ConfigFile) writeAnsibleINI(w io.Writer, hostVars []map[string]string, groups map[string][]int, groupVars map[string]map[string]string, groupNames []string) error {
+	var out strings.Builder
+	written := make([]bool, len(c.Hosts))
+	hostLine := func(i int) string {
+		if written[i] {
+			return c.Hosts[i].Name
+		}
+		written[i] = true
+		parts := []string{c.Hosts[i].Name}
+		for _, key := range slices.Sorted(maps.Keys(hostVars[i])) {
+			parts = append(parts, key+"="+quoteAnsible(hostVars[i][key]))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	// Hosts sem tags ficam antes da primeira seção (grupo ungrouped)
+	for i := range c.Hosts {
+		if len(c.Hosts[i].Tags) == 0 {
+			fmt.Fprintln(&out, hostLine(i))
+		}
+	}
+	for _, name := range groupNames {
+		if members, ok := groups[name]; ok {
+			if out.Len() > 0 {
+				out.WriteString("\n")
+			}
+			fmt.Fprintf(&out, "[%s]\n", name)
+			for _, i := range members {
+				fmt.Fprintln(&out, hostLine(i))
+			}
+		}
+		if vars, ok := groupVars[name]; ok {
+			if out.Len() > 0 {
+				out.WriteString("\n")
+			}
+			fmt.Fprintf(&out, "[%s:vars]\n", name)
+			for _, key := range slices.Sorted(maps.Keys(vars)) {
+				fmt.Fprintf(&out, "%s=%s\n", key, quoteAnsible(vars[key]))
+			}
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeAnsibleYAML escreve o inventário no formato YAML (vars dos hosts em all.hosts)
+func (c *ConfigFile) writeAnsibleYAML(w io.Writer, hostVars []map[string]string, groups map[string][]int, groupVars map[string]map[string]string, groupNames []string) error {
+	allHosts := make(map[string]map[string]string)
+	for i := range c.Hosts {
+		allHosts[c.Hosts[i].Name] = hostVars[i]
+	}
+	children := make(map[string]map[string]any)
+	for _, name := range groupNames {
+		group := make(map[string]any)
+		if members, ok := groups[name]; ok {
+			hosts := make(map[string]map[string]string)
+			for _, i := range members {
+				hosts[c.Hosts[i].Name] = map[string]string{}
+			}
+			group["hosts"] = hosts
+		}
+		if vars, ok := groupVars[name]; ok {
+			group["vars"] = vars
+		}
+		children[name] = group
+	}
+
+	all := map[string]any{"hosts": allHosts}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]any{"all": all}); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// quoteAnsible coloca entre aspas valores do INI que contêm espaços, aspas ou #
+func quoteAnsible(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"'#=") {
+		return value
+	}
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -2,24 +2,35 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // User representa um usuário com suas chaves SSH
 type User struct {
-	Name    string   `yaml:"name"`
-	SSHKeys []string `yaml:"ssh_keys"`
+	Name            string   `yaml:"name"`
+	SSHKeys         []string `yaml:"ssh_keys"`
+	PasswordRef     string   `yaml:"password_ref,omitempty"`     // Segredo do cofre com a senha do usuário (sc vault)
+	PasswordCommand string   `yaml:"password_command,omitempty"` // Comando que imprime a senha do usuário (gerenciador de senhas)
+
+	file string // Arquivo de configuração que define o usuário
 }
 
 // JumpHost representa um jump host configurado
 type JumpHost struct {
-	Name string `yaml:"name"`
-	Host string `yaml:"host"`
-	User string `yaml:"user"`
-	Port int    `yaml:"port"`
+	Name            string `yaml:"name"`
+	Host            string `yaml:"host"`
+	User            string `yaml:"user"`
+	Port            int    `yaml:"port"`
+	PasswordCommand string `yaml:"password_command,omitempty"` // Comando que imprime a senha (sobrepõe a do usuário)
+
+	file string // Arquivo de configuração que define o jump host
 }
 
 // Config representa a seção de configuração global
@@ -31,24 +42,100 @@ type Config struct {
 	JumpHosts    []JumpHost `yaml:"jump_hosts"`
 	Proxy        string     `yaml:"proxy"`      // IP:PORT do proxy (ex: 10.0.230.100:8080)
 	ProxyPort    int        `yaml:"proxy_port"` // Porta local no host remoto (ex: 9999)
+
+	// Limites de tempo (podem ser sobrescritos por --connect-timeout e --command-timeout)
+	ConnectTimeout time.Duration `yaml:"connect_timeout"` // Tempo máximo para conectar (0 = sem limite)
+	CommandTimeout time.Duration `yaml:"command_timeout"` // Tempo máximo de execução de comandos (0 = sem limite)
+
+	// Hosts com endereços alternativos (addresses)
+	AddressTimeout  time.Duration `yaml:"address_timeout,omitempty"`  // Tempo máximo de cada endereço (0 = DefaultAddressTimeout)
+	AddressStrategy string        `yaml:"address_strategy,omitempty"` // order (padrão): um endereço por vez; race: em paralelo
+
+	// Fontes de inventário dinâmico (executáveis que retornam hosts em JSON)
+	InventorySources []InventorySource `yaml:"inventory_sources"`
+
+	// Arquivo onde novos hosts, usuários e jump hosts são gravados (vazio = config.yaml)
+	LocalFile string `yaml:"local_file,omitempty"`
 }
 
 // Host representa um host SSH
 type Host struct {
-	Name string   `yaml:"name"`
-	Host string   `yaml:"host"`
-	Port int      `yaml:"port"`
-	Tags []string `yaml:"tags"`
+	Name            string            `yaml:"name"`
+	Host            string            `yaml:"host"`
+	Port            int               `yaml:"port"`
+	Tags            []string          `yaml:"tags"`
+	User            string            `yaml:"user,omitempty"`             // Usuário padrão do host, usado quando -u não é informado
+	Vars            map[string]string `yaml:"vars,omitempty"`             // Variáveis do host nos templates ({{.Vars.nome}})
+	Jump            string            `yaml:"jump,omitempty"`             // Jump host padrão do host (nome ou índice), usado quando -j não é informado
+	PasswordRef     string            `yaml:"password_ref,omitempty"`     // Segredo do cofre com a senha do host (sobrepõe a do usuário)
+	PasswordCommand string            `yaml:"password_command,omitempty"` // Comando que imprime a senha do host (sobrepõe a do usuário)
+	Addresses       []HostAddress     `yaml:"addresses,omitempty"`        // Endereços alternativos, tentados quando host não responde
+
+	source string // Fonte de inventário dinâmico que forneceu o host (vazio = arquivos de configuração)
+	file   string // Arquivo de configuração que define o host (vazio = ainda não gravado)
+	loaded string // Nome do host na carga (para gravar renomeações no lugar)
+}
+
+// HostAddress é um endereço alternativo de um host (ex: IP público via bastion além do IP da VPN)
+type HostAddress struct {
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port,omitempty" json:"port,omitempty"` // Porta (0 = a porta do host)
+	Jump string `yaml:"jump,omitempty" json:"jump,omitempty"` // Jump host usado para este endereço (vazio = conexão direta)
+}
+
+// TagConfig representa as configurações de uma tag (seção tags)
+type TagConfig struct {
+	Vars map[string]string `yaml:"vars,omitempty"` // Variáveis herdadas pelos hosts com a tag
 }
 
 // ConfigFile representa a estrutura completa do arquivo YAML
 type ConfigFile struct {
-	Config Config `yaml:"config"`
-	Hosts  []Host `yaml:"hosts"`
+	Version  int                  `yaml:"version,omitempty"` // Versão do formato (veja configMigrations)
+	Include  []string             `yaml:"include,omitempty"` // Arquivos adicionais (globs), além de conf.d/*.yaml
+	Config   Config               `yaml:"config"`
+	Hosts    []Host               `yaml:"hosts"`
+	Tags     map[string]TagConfig `yaml:"tags,omitempty"`
+	Contexts []Context            `yaml:"contexts,omitempty"` // Contextos nomeados (sc context), apenas no config.yaml principal
+	Rules    []Rule               `yaml:"rules,omitempty"`    // Regras de conexão (veja rules.go), avaliadas em ordem
+
+	path          string               // Caminho do config.yaml (base do cache de inventário e dos includes)
+	inventoryTags map[string]TagConfig // Vars de tags vindas do inventário dinâmico
+	layers        []*configLayer       // Arquivos carregados, na ordem de carga (o config.yaml primeiro)
+	loadedTags    map[string]TagConfig // Tags como foram carregadas (para detectar alterações ao salvar)
+	context       *Context             // Contexto ativo na carga (sc context)
+	contextFilter *Selector            // Filtro de hosts do contexto ativo
+}
+
+// LoadConfig carrega o arquivo de configuração YAML e mescla os hosts das fontes de inventário dinâmico
+func LoadConfig(filename string) (*ConfigFile, error) {
+	cfg, err := LoadStaticConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Problemas de validação não impedem o uso (sc config validate exibe o relatório completo)
+	if result, err := cfg.validate(false); err == nil {
+		for _, issue := range result.Errors {
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", issue)
+		}
+	}
+
+	// Fontes indisponíveis não impedem o uso dos hosts do config.yaml
+	for _, result := range cfg.LoadInventory(false) {
+		switch {
+		case result.Err != nil && result.Stale:
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: inventário '%s' indisponível, usando cache de %s: %v\n",
+				result.Source, result.At.Format("2006-01-02 15:04"), result.Err)
+		case result.Err != nil:
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: inventário '%s' indisponível: %v\n", result.Source, result.Err)
+		}
+	}
+	return cfg, nil
 }
 
-// LoadConfig carrega o arquivo de configuração YAML
-func LoadConfig(filename string) (*ConfigFile, error) {
+// LoadStaticConfig carrega o config.yaml e os arquivos incluídos (include e conf.d),
+// sem executar as fontes de inventário dinâmico
+func LoadStaticConfig(filename string) (*ConfigFile, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao ler arquivo: %w", err)
@@ -58,6 +145,20 @@ func LoadConfig(filename string) (*ConfigFile, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("erro ao parsear YAML: %w", err)
 	}
+	original, err := decodeLayer(data)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao parsear YAML: %w", err)
+	}
+
+	cfg.path = filename
+	cfg.layers = []*configLayer{{path: filename, original: original}}
+	cfg.markOwner(filename)
+
+	// Arquivos incluídos são mesclados em ordem (o último vence)
+	if err := cfg.loadIncludes(); err != nil {
+		return nil, err
+	}
+	cfg.loadedTags = cloneTags(cfg.Tags)
 
 	// Aplica valores default para campos não definidos
 	cfg.applyDefaults()
@@ -112,6 +213,23 @@ func (c *Config) GetProxyConfig() (address string, port int, configured bool) {
 	return c.Proxy, port, true
 }
 
+// DefaultAddressTimeout é o tempo máximo de cada endereço de um host com addresses (address_timeout)
+const DefaultAddressTimeout = 5 * time.Second
+
+// Estratégias de conexão para hosts com endereços alternativos (address_strategy)
+const (
+	AddressStrategyOrder = "order" // Tenta um endereço por vez, na ordem (padrão)
+	AddressStrategyRace  = "race"  // Tenta os endereços em paralelo, com um pequeno intervalo entre eles (happy eyeballs)
+)
+
+// GetAddressTimeout retorna o tempo máximo de cada endereço de um host com addresses
+func (c *Config) GetAddressTimeout() time.Duration {
+	if c.AddressTimeout <= 0 {
+		return DefaultAddressTimeout
+	}
+	return c.AddressTimeout
+}
+
 // GetDownloadDir retorna o diretório padrão para downloads
 // Se não configurado, retorna ~/sshControl como padrão
 func (c *Config) GetDownloadDir() string {
@@ -149,6 +267,23 @@ func (c *ConfigFile) GetEffectiveUser(selectedUser *User) *User {
 	return c.GetDefaultUser()
 }
 
+// HostUser determina o usuário da conexão com um host do config.yaml
+// Prioridade: 1. selectedUser (via flag -u), 2. campo user do host, 3. default_user
+// Um user do host que não está em config.users usa as chaves SSH do usuário efetivo (default_user)
+func (c *ConfigFile) HostUser(host *Host, selectedUser *User) *User {
+	if selectedUser != nil || host == nil || host.User == "" {
+		return c.GetEffectiveUser(selectedUser)
+	}
+	if user := c.FindUser(host.User); user != nil {
+		return user
+	}
+	user := &User{Name: host.User}
+	if effective := c.GetEffectiveUser(nil); effective != nil {
+		user.SSHKeys = effective.SSHKeys
+	}
+	return user
+}
+
 // GetSSHKey retorna a primeira chave SSH disponível para o usuário
 // Deprecated: Use GetSSHKeys para obter todas as chaves
 func (c *ConfigFile) GetSSHKey(username string) string {
@@ -184,27 +319,75 @@ func (c *ConfigFile) FindHost(name string) *Host {
 	return nil
 }
 
-// FindHostByAddress procura um host pelo endereço (campo host)
+// FindHostByAddress procura um host pelo endereço (campo host ou um dos addresses)
 func (c *ConfigFile) FindHostByAddress(address string) *Host {
 	for i := range c.Hosts {
-		if c.Hosts[i].Host == address {
+		if c.Hosts[i].HasAddress(address) {
 			return &c.Hosts[i]
 		}
 	}
 	return nil
 }
 
+// HasAddress indica se o endereço é o do host (campo host) ou um dos alternativos (addresses)
+func (h *Host) HasAddress(address string) bool {
+	return h.Host == address || slices.ContainsFunc(h.Addresses, func(a HostAddress) bool { return a.Host == address })
+}
+
 // HasTag verifica se um host possui uma tag específica
+// Tags hierárquicas também correspondem à tag pai (env/prod possui a tag env)
 func (h *Host) HasTag(tag string) bool {
-	tagLower := strings.ToLower(tag)
 	for _, t := range h.Tags {
-		if strings.ToLower(t) == tagLower {
+		if tagMatches(t, tag) {
 			return true
 		}
 	}
 	return false
 }
 
+// tagMatches verifica se a tag do host é igual à tag procurada ou descendente dela (sem diferenciar maiúsculas)
+func tagMatches(hostTag, tag string) bool {
+	if strings.EqualFold(hostTag, tag) {
+		return true
+	}
+	return len(hostTag) > len(tag) && hostTag[len(tag)] == '/' && strings.EqualFold(hostTag[:len(tag)], tag)
+}
+
+// HostVars retorna as variáveis de template do host: vars das tags, na ordem
+// em que aparecem no host, sobrescritas pelas vars do próprio host
+// Em tags hierárquicas, as vars da tag pai vêm antes (env, depois env/prod)
+func (c *ConfigFile) HostVars(host *Host) map[string]string {
+	vars := make(map[string]string)
+	for _, tag := range host.Tags {
+		var names []string
+		for name := range c.Tags {
+			if tagMatches(tag, name) {
+				names = append(names, name)
+			}
+		}
+		for name := range c.inventoryTags {
+			if tagMatches(tag, name) {
+				names = append(names, name)
+			}
+		}
+		slices.SortFunc(names, func(a, b string) int { return len(a) - len(b) })
+		for _, name := range names {
+			if tagConfig, ok := c.Tags[name]; ok {
+				maps.Copy(vars, tagConfig.Vars)
+			} else {
+				maps.Copy(vars, c.inventoryTags[name].Vars)
+			}
+		}
+	}
+	maps.Copy(vars, host.Vars)
+	return vars
+}
+
+// Source retorna a fonte de inventário dinâmico que forneceu o host (vazio para hosts do config.yaml)
+func (h *Host) Source() string {
+	return h.source
+}
+
 // IsAutoCreated verifica se o host foi criado automaticamente
 func (h *Host) IsAutoCreated() bool {
 	return h.HasTag("autocreated")
@@ -216,8 +399,24 @@ func (c *ConfigFile) AddHost(host Host) {
 }
 
 // SaveConfig salva a configuração atual no arquivo YAML
+// Se filename é o config.yaml carregado, cada alteração é gravada no arquivo que a possui
+// (itens novos vão para config.local_file); caso contrário, grava tudo em um único arquivo.
+// Hosts do inventário dinâmico não são gravados
 func (c *ConfigFile) SaveConfig(filename string) error {
-	data, err := yaml.Marshal(c)
+	if c.path != "" && filepath.Clean(filename) == filepath.Clean(c.path) {
+		return c.saveLayers()
+	}
+
+	static := *c
+	static.Include = nil
+	static.Hosts = nil
+	for _, host := range c.Hosts {
+		if host.source == "" {
+			static.Hosts = append(static.Hosts, host)
+		}
+	}
+
+	data, err := yaml.Marshal(&static)
 	if err != nil {
 		return fmt.Errorf("erro ao serializar configuração: %w", err)
 	}
@@ -225,23 +424,25 @@ func (c *ConfigFile) SaveConfig(filename string) error {
 	// Adiciona o marcador YAML no início
 	content := "---\n" + string(data) + "...\n"
 
-	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
-		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	unlock, err := lockConfigDir(filepath.Dir(filename))
+	if err != nil {
+		return err
 	}
-
-	return nil
+	defer unlock()
+	backups := newBackupSet(filepath.Dir(filename))
+	defer backups.finish()
+	if err := backups.add(filename); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, []byte(content))
 }
 
 // FindHostsByTag retorna todos os hosts que possuem a tag especificada
 func (c *ConfigFile) FindHostsByTag(tag string) []Host {
 	var hosts []Host
-	tagLower := strings.ToLower(tag)
 	for _, host := range c.Hosts {
-		for _, t := range host.Tags {
-			if strings.ToLower(t) == tagLower {
-				hosts = append(hosts, host)
-				break
-			}
+		if host.HasTag(tag) {
+			hosts = append(hosts, host)
 		}
 	}
 	return hosts
@@ -264,11 +465,11 @@ func (c *ConfigFile) GetAllTags() []string {
 }
 
 // GetHostsForTUI retorna hosts filtrados para exibição na TUI
-// Exclui hosts com a tag "autocreated"
+// Exclui hosts com a tag "autocreated" e os que não passam pelo filtro do contexto ativo
 func (c *ConfigFile) GetHostsForTUI() []Host {
 	var hosts []Host
 	for _, host := range c.Hosts {
-		if !host.IsAutoCreated() {
+		if !host.IsAutoCreated() && c.InContext(&host) {
 			hosts = append(hosts, host)
 		}
 	}
@@ -312,6 +513,18 @@ func (c *ConfigFile) ResolveJumpHost(identifier string) *JumpHost {
 	return c.FindJumpHost(identifier)
 }
 
+// HostJumpHost retorna o jump host padrão do host (campo jump), ou nil se não houver
+func (c *ConfigFile) HostJumpHost(host *Host) (*JumpHost, error) {
+	if host == nil || host.Jump == "" {
+		return nil, nil
+	}
+	jumpHost := c.ResolveJumpHost(host.Jump)
+	if jumpHost == nil {
+		return nil, fmt.Errorf("jump host '%s' do host '%s' não encontrado", host.Jump, host.Name)
+	}
+	return jumpHost, nil
+}
+
 // GetJumpHostSSHKey retorna a chave SSH do usuário configurado no jump host
 // Deprecated: Use GetJumpHostSSHKeys para obter todas as chaves
 func (c *ConfigFile) GetJumpHostSSHKey(jumpHost *JumpHost) string {
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Contextos nomeados (sc context)
+// Um contexto agrupa as opções usadas juntas no dia a dia (ex: "prod via bastion como ops"):
+//
+//	contexts:
+//	  - name: prod
+//	    user: ops            # equivale a -u
+//	    jump: bastion        # equivale a -j
+//	    proxy: true          # equivale a -p
+//	    filter: "@prod"      # seletor que limita os hosts da TUI, de sc -s e de sc -l
+//	  - name: lab
+//	    user: ubuntu
+//	    config: ~/lab/sc.yaml  # outro arquivo de configuração
+//
+// Os contextos são lidos apenas do config.yaml principal. O contexto ativo vem de --context,
+// da variável SC_CONTEXT ou do último 'sc context use' (nessa ordem). Flags informadas na
+// linha de comando sempre vencem as opções do contexto.
+//
+// O contexto não é aplicado por LoadConfig: quem o usa resolve o contexto com ActiveContext
+// (passando o valor de --context) e o aplica com UseContext.
+
+const (
+	// ContextEnv é a variável de ambiente que seleciona o contexto
+	ContextEnv = "SC_CONTEXT"
+
+	// CurrentContextFileName é o arquivo (dentro de ~/.sshControl) com o contexto de 'sc context use'
+	CurrentContextFileName = "current_context"
+)
+
+// Context é um contexto nomeado (seção contexts)
+type Context struct {
+	Name   string `yaml:"name"`
+	User   string `yaml:"user,omitempty"`   // Usuário usado quando -u não é informado
+	Jump   string `yaml:"jump,omitempty"`   // Jump host (nome ou índice) usado quando -j não é informado
+	Proxy  bool   `yaml:"proxy,omitempty"`  // Habilita o proxy (-p) por padrão
+	Filter string `yaml:"filter,omitempty"` // Seletor de hosts (ex: @prod ou @prod&@web)
+	Config string `yaml:"config,omitempty"` // Arquivo de configuração do contexto (vazio = config.yaml)
+}
+
+// ContextOrigin indica de onde veio a seleção do contexto ativo
+type ContextOrigin string
+
+const (
+	ContextFromFlag ContextOrigin = "--context"
+	ContextFromEnv  ContextOrigin = ContextEnv
+	ContextFromFile ContextOrigin = "sc context use"
+)
+
+// ContextName retorna o nome do contexto selecionado e a origem da seleção ("" se nenhum)
+// flag é o valor de --context, que tem precedência sobre SC_CONTEXT e 'sc context use'
+func ContextName(flag string) (string, ContextOrigin, error) {
+	if flag != "" {
+		return flag, ContextFromFlag, nil
+	}
+	if name := os.Getenv(ContextEnv); name != "" {
+		return name, ContextFromEnv, nil
+	}
+	path, err := currentContextPath()
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("erro ao ler contexto atual: %w", err)
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name, ContextFromFile, nil
+	}
+	return "", "", nil
+}
+
+// ActiveContext retorna o contexto selecionado, definido no config.yaml principal (nil se nenhum)
+// flag é o valor de --context (veja ContextName)
+func ActiveContext(flag string) (*Context, error) {
+	name, origin, err := ContextName(flag)
+	if err != nil || name == "" {
+		return nil, err
+	}
+	contexts, err := LoadContexts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range contexts {
+		if contexts[i].Name == name {
+			return &contexts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("contexto '%s' (de %s) não encontrado no config.yaml (veja 'sc context list')", name, origin)
+}
+
+// LoadContexts lê os contextos do config.yaml principal (vazio se o arquivo ainda não existe)
+func LoadContexts() ([]Context, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo: %w", err)
+	}
+	var file struct {
+		Contexts []Context `yaml:"contexts"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("erro ao parsear %s: %w", configPath, err)
+	}
+	return file.Contexts, nil
+}
+
+// SetCurrentContext grava o contexto usado por padrão (name vazio desativa)
+func SetCurrentContext(name string) error {
+	path, err := currentContextPath()
+	if err != nil {
+		return err
+	}
+	configDir := filepath.Dir(path)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", configDir, err)
+	}
+	unlock, err := lockConfigDir(configDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if name == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("erro ao remover contexto atual: %w", err)
+		}
+		return nil
+	}
+	return writeFileAtomic(path, []byte(name+"\n"))
+}
+
+// ConfigPath retorna o caminho do arquivo de configuração do contexto (vazio = config.yaml)
+// Caminhos relativos partem de ~/.sshControl
+func (ctx *Context) ConfigPath() string {
+	if ctx == nil || ctx.Config == "" {
+		return ""
+	}
+	path := ExpandHomePath(ctx.Config)
+	if !filepath.IsAbs(path) {
+		if configPath, err := GetConfigPath(); err == nil {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// Context retorna o contexto aplicado por UseContext (nil se nenhum)
+func (c *ConfigFile) Context() *Context {
+	return c.context
+}
+
+// InContext indica se o host passa pelo filtro do contexto ativo (sempre true sem filtro)
+func (c *ConfigFile) InContext(h *Host) bool {
+	return c.contextFilter == nil || c.contextFilter.Matches(h)
+}
+
+// UseContext aplica o contexto (de ActiveContext) à configuração carregada: o filtro passa a limitar
+// os seletores e a listagem de hosts, e Context() o retorna (nil não aplica nenhum contexto)
+// Um filtro inválido retorna erro e o contexto não é aplicado (nunca amplia os hosts em silêncio)
+func (c *ConfigFile) UseContext(ctx *Context) error {
+	c.context, c.contextFilter = nil, nil
+	if ctx == nil {
+		return nil
+	}
+	selector, err := ctx.Selector()
+	if err != nil {
+		return err
+	}
+	c.context, c.contextFilter = ctx, selector
+	return nil
+}
+
+// Selector retorna o seletor do filtro do contexto (nil sem filtro)
+func (ctx *Context) Selector() (*Selector, error) {
+	if ctx.Filter == "" {
+		return nil, nil
+	}
+	selector, err := ParseSelector(strings.Fields(ctx.Filter)...)
+	if err != nil {
+		return nil, fmt.Errorf("filtro do contexto '%s' inválido: %w", ctx.Name, err)
+	}
+	return selector, nil
+}
+
+// currentContextPath retorna o caminho de ~/.sshControl/current_context
+func currentContextPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), CurrentContextFileName), nil
+}
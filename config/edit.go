@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Edição da configuração pela linha de comando (sc host, sc user e sc jump)
+// As funções validam a entrada e alteram a configuração em memória; SaveConfig grava o resultado.
+
+// namePattern define os caracteres aceitos em nomes de hosts, usuários, jump hosts e tags
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/-]*$`)
+
+// validateName verifica um nome de host, usuário, jump host ou tag
+func validateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("nome do %s não informado", kind)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("nome do %s inválido: '%s' (use letras, números e . _ - / :)", kind, name)
+	}
+	return nil
+}
+
+// validateHost verifica os campos de um host antes de gravá-lo
+func (c *ConfigFile) validateHost(host *Host) error {
+	if err := validateName("host", host.Name); err != nil {
+		return err
+	}
+	if host.Host == "" {
+		return fmt.Errorf("endereço do host '%s' não informado", host.Name)
+	}
+	if host.Port < 1 || host.Port > 65535 {
+		return fmt.Errorf("porta inválida: %d (use 1-65535)", host.Port)
+	}
+	for _, tag := range host.Tags {
+		if err := validateName("tag", tag); err != nil {
+			return err
+		}
+	}
+	if host.Jump != "" {
+		jumpHost := c.ResolveJumpHost(host.Jump)
+		if jumpHost == nil {
+			return fmt.Errorf("jump host '%s' não encontrado", host.Jump)
+		}
+		host.Jump = jumpHost.Name
+	}
+	return nil
+}
+
+// staticHost retorna o host do config.yaml (ou de arquivos incluídos) com o nome informado
+func (c *ConfigFile) staticHost(name string) (*Host, error) {
+	host := c.FindHost(name)
+	if host == nil {
+		return nil, fmt.Errorf("host '%s' não encontrado", name)
+	}
+	if host.source != "" {
+		return nil, fmt.Errorf("host '%s' pertence ao inventário dinâmico '%s' e não pode ser alterado", name, host.source)
+	}
+	return host, nil
+}
+
+// CreateHost valida e adiciona um novo host (gravado por SaveConfig no arquivo de hosts novos)
+func (c *ConfigFile) CreateHost(host Host) error {
+	if err := c.validateHost(&host); err != nil {
+		return err
+	}
+	if c.FindHost(host.Name) != nil {
+		return fmt.Errorf("host '%s' já existe", host.Name)
+	}
+	host.Tags = uniqueTags(host.Tags)
+	c.AddHost(host)
+	return nil
+}
+
+// UpdateHost valida e substitui os dados de um host existente (o nome não muda)
+func (c *ConfigFile) UpdateHost(name string, host Host) error {
+	current, err := c.staticHost(name)
+	if err != nil {
+		return err
+	}
+	host.Name = current.Name
+	if err := c.validateHost(&host); err != nil {
+		return err
+	}
+	host.Tags = uniqueTags(host.Tags)
+	host.file, host.loaded = current.file, current.loaded
+	*current = host
+	return nil
+}
+
+// RemoveHost remove um host
+func (c *ConfigFile) RemoveHost(name string) error {
+	if _, err := c.staticHost(name); err != nil {
+		return err
+	}
+	index := slices.IndexFunc(c.Hosts, func(h Host) bool { return h.Name == name && h.source == "" })
+	c.Hosts = slices.Delete(c.Hosts, index, index+1)
+	return nil
+}
+
+// RenameHost altera o nome de um host
+func (c *ConfigFile) RenameHost(oldName, newName string) error {
+	host, err := c.staticHost(oldName)
+	if err != nil {
+		return err
+	}
+	if err := validateName("host", newName); err != nil {
+		return err
+	}
+	if c.FindHost(newName) != nil {
+		return fmt.Errorf("host '%s' já existe", newName)
+	}
+	host.Name = newName
+	return nil
+}
+
+// AddHostTags adiciona tags a um host e retorna as que ainda não existiam
+func (c *ConfigFile) AddHostTags(name string, tags []string) ([]string, error) {
+	host, err := c.staticHost(name)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, tag := range tags {
+		if err := validateName("tag", tag); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(host.Tags, tag) && !slices.Contains(added, tag) {
+			added = append(added, tag)
+		}
+	}
+	host.Tags = append(host.Tags, added...)
+	return added, nil
+}
+
+// RemoveHostTags remove tags de um host e retorna as que foram removidas
+func (c *ConfigFile) RemoveHostTags(name string, tags []string) ([]string, error) {
+	host, err := c.staticHost(name)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var kept []string
+	for _, tag := range host.Tags {
+		if slices.Contains(tags, tag) {
+			removed = append(removed, tag)
+		} else {
+			kept = append(kept, tag)
+		}
+	}
+	if len(removed) > 0 {
+		host.Tags = kept
+	}
+	return removed, nil
+}
+
+// CreateUser valida e adiciona um novo usuário
+func (c *ConfigFile) CreateUser(user User) error {
+	if err := validateName("usuário", user.Name); err != nil {
+		return err
+	}
+	if c.FindUser(user.Name) != nil {
+		return fmt.Errorf("usuário '%s' já existe", user.Name)
+	}
+	if user.SSHKeys == nil {
+		user.SSHKeys = []string{}
+	}
+	c.Config.User = append(c.Config.User, user)
+	return nil
+}
+
+// CreateJumpHost valida e adiciona um novo jump host
+func (c *ConfigFile) CreateJumpHost(jumpHost JumpHost) error {
+	if err := validateName("jump host", jumpHost.Name); err != nil {
+		return err
+	}
+	// Nomes numéricos se confundiriam com o índice usado em -j
+	if _, err := strconv.Atoi(jumpHost.Name); err == nil {
+		return fmt.Errorf("nome do jump host não pode ser um número: '%s'", jumpHost.Name)
+	}
+	if c.FindJumpHost(jumpHost.Name) != nil {
+		return fmt.Errorf("jump host '%s' já existe", jumpHost.Name)
+	}
+	if jumpHost.Host == "" {
+		return fmt.Errorf("endereço do jump host '%s' não informado", jumpHost.Name)
+	}
+	if jumpHost.Port < 1 || jumpHost.Port > 65535 {
+		return fmt.Errorf("porta inválida: %d (use 1-65535)", jumpHost.Port)
+	}
+	if c.FindUser(jumpHost.User) == nil {
+		return fmt.Errorf("usuário '%s' não está definido em users (use 'sc user add')", jumpHost.User)
+	}
+	c.Config.JumpHosts = append(c.Config.JumpHosts, jumpHost)
+	return nil
+}
+
+// uniqueTags remove tags repetidas mantendo a ordem
+func uniqueTags(tags []string) []string {
+	unique := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Editor de arquivos YAML em nível de nó
+// As gravações (SaveConfig e MigrateConfig) partem do arquivo como está no disco e alteram apenas
+// os nós que mudaram: comentários, ordem das chaves, estilo ([a, b] ou lista em bloco), chaves
+// desconhecidas e os marcadores "---"/"..." são preservados.
+
+// defaultIndent é a indentação usada em arquivos novos (a mesma do yaml.Marshal)
+const defaultIndent = 4
+
+// yamlDocument é um arquivo YAML carregado como yaml.Node, com o formato detectado
+type yamlDocument struct {
+	node        yaml.Node
+	indent      int
+	startMarker bool // Arquivo começa com "---"
+	endMarker   bool // Arquivo termina com "..."
+}
+
+// readDocument lê um arquivo YAML preservando comentários (arquivo inexistente = documento vazio)
+func readDocument(path string) (*yamlDocument, error) {
+	doc := &yamlDocument{indent: defaultIndent, startMarker: true, endMarker: true}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &doc.node); err != nil {
+		return nil, err
+	}
+	doc.indent, doc.startMarker, doc.endMarker = detectFormat(data)
+	return doc, nil
+}
+
+// detectFormat identifica a indentação e os marcadores de início e fim do arquivo
+func detectFormat(data []byte) (indent int, startMarker, endMarker bool) {
+	indent = defaultIndent
+	foundIndent := false
+	lastLine := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if lastLine == "" && line == "---" {
+			startMarker = true
+		}
+		// A primeira linha indentada define a indentação do arquivo
+		if !foundIndent && len(trimmed) < len(line) {
+			indent = len(line) - len(trimmed)
+			foundIndent = true
+		}
+		lastLine = line
+	}
+	return indent, startMarker, lastLine == "..."
+}
+
+// root retorna o mapeamento raiz do documento (criado se o documento estiver vazio)
+func (d *yamlDocument) root() *yaml.Node {
+	if d.node.Kind != yaml.DocumentNode || len(d.node.Content) == 0 {
+		d.node = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if d.node.Content[0].Kind != yaml.MappingNode {
+		d.node.Content[0] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	return d.node.Content[0]
+}
+
+// bytes serializa o documento com a indentação e os marcadores originais
+func (d *yamlDocument) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if d.startMarker {
+		buf.WriteString("---\n")
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+	if err := encoder.Encode(&d.node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	if d.endMarker {
+		buf.WriteString("...\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// write grava o documento no arquivo (escrita atômica, permissão 0600)
+func (d *yamlDocument) write(path string) error {
+	data, err := d.bytes()
+	if err != nil {
+		return fmt.Errorf("erro ao serializar configuração: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// syncNode altera dst (nó do arquivo) para refletir a mudança de old para new
+// Nós iguais em old e new não são tocados; chaves presentes no arquivo mas ausentes em old
+// (desconhecidas) são mantidas. Em listas de itens com "name", renames indica o nome anterior
+// de itens renomeados (novo -> antigo) para manter a posição e os comentários do item.
+func syncNode(dst, old, new *yaml.Node, renames map[string]string) {
+	if old != nil && nodesEqual(old, new) {
+		return
+	}
+	if dst.Kind != new.Kind || dst.Kind == yaml.AliasNode {
+		replaceNode(dst, new)
+		return
+	}
+
+	switch new.Kind {
+	case yaml.ScalarNode:
+		if dst.Value == new.Value && dst.ShortTag() == new.ShortTag() {
+			return
+		}
+		dst.Value, dst.Tag = new.Value, new.Tag
+		// Aspas só são mantidas em strings; números e booleanos voltam ao estilo simples
+		if new.ShortTag() != "!!str" || dst.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+			dst.Style = new.Style
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(new.Content); i += 2 {
+			key := new.Content[i].Value
+			index := findKeyIndex(dst, key)
+			if index < 0 {
+				dst.Content = append(dst.Content, copyNode(new.Content[i]), copyNode(new.Content[i+1]))
+				continue
+			}
+			var childRenames map[string]string
+			if key == "hosts" {
+				childRenames = renames
+			}
+			syncNode(dst.Content[index+1], mappingValue(old, key), new.Content[i+1], childRenames)
+		}
+		// Chaves removidas (presentes em old e ausentes em new)
+		if old != nil {
+			for i := 0; i+1 < len(old.Content); i += 2 {
+				key := old.Content[i].Value
+				if findKeyIndex(new, key) < 0 {
+					if index := findKeyIndex(dst, key); index >= 0 {
+						dst.Content = append(dst.Content[:index], dst.Content[index+2:]...)
+					}
+				}
+			}
+		}
+
+	case yaml.SequenceNode:
+		if namedItems(new) || namedItems(dst) {
+			syncNamedItems(dst, old, new, renames)
+		} else {
+			syncScalarItems(dst, old, new)
+		}
+	}
+}
+
+// syncNamedItems sincroniza listas de itens identificados por "name" (hosts, users, jump_hosts)
+// Itens existentes são alterados no lugar e itens novos vão para o fim. Só são removidos os itens
+// que existiam em old: itens gravados por outro processo depois da carga são mantidos.
+func syncNamedItems(dst, old, new *yaml.Node, renames map[string]string) {
+	wanted := make(map[string]*yaml.Node)
+	for _, item := range new.Content {
+		name := itemName(item)
+		if previous, ok := renames[name]; ok {
+			name = previous
+		}
+		wanted[name] = item
+	}
+
+	var content []*yaml.Node
+	written := make(map[*yaml.Node]bool)
+	for _, existing := range dst.Content {
+		name := itemName(existing)
+		if item, ok := wanted[name]; ok && !written[item] {
+			syncNode(existing, findNamedItem(old, name), item, nil)
+			content = append(content, existing)
+			written[item] = true
+		} else if findNamedItem(old, name) == nil || name == "" {
+			content = append(content, existing)
+		}
+	}
+	// Itens novos (ausentes em old); os que existiam em old e sumiram do arquivo foram removidos por outro processo
+	for _, item := range new.Content {
+		name := itemName(item)
+		if previous, ok := renames[name]; ok {
+			name = previous
+		}
+		if !written[item] && (old == nil || findNamedItem(old, name) == nil) {
+			content = append(content, copyNode(item))
+		}
+	}
+	dst.Content = content
+}
+
+// syncScalarItems sincroniza listas simples (tags, ssh_keys), reaproveitando os nós que continuam na lista
+// Assim como em syncNamedItems, só são removidos os valores que existiam em old
+func syncScalarItems(dst, old, new *yaml.Node) {
+	remaining := make(map[string]int)
+	for _, item := range new.Content {
+		remaining[item.Value]++
+	}
+	removable := make(map[string]bool)
+	if old != nil {
+		for _, item := range old.Content {
+			removable[item.Value] = true
+		}
+	}
+
+	var content []*yaml.Node
+	for _, existing := range dst.Content {
+		switch {
+		case remaining[existing.Value] > 0:
+			remaining[existing.Value]--
+			content = append(content, existing)
+		case !removable[existing.Value]:
+			content = append(content, existing)
+		}
+	}
+	for _, item := range new.Content {
+		if remaining[item.Value] > 0 && !removable[item.Value] {
+			remaining[item.Value]--
+			content = append(content, copyNode(item))
+		}
+	}
+	dst.Content = content
+}
+
+// replaceNode substitui o conteúdo de dst pelo de new, mantendo os comentários de dst
+func replaceNode(dst, new *yaml.Node) {
+	replacement := copyNode(new)
+	replacement.HeadComment, replacement.LineComment, replacement.FootComment = dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *replacement
+}
+
+// namedItems indica se a lista contém itens (mapeamentos) identificados por "name"
+func namedItems(seq *yaml.Node) bool {
+	for _, item := range seq.Content {
+		if mappingValue(resolveAlias(item), "name") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// itemName retorna o valor de "name" de um item de lista
+func itemName(item *yaml.Node) string {
+	if name := mappingValue(resolveAlias(item), "name"); name != nil {
+		return name.Value
+	}
+	return ""
+}
+
+// findNamedItem retorna o item da lista com o nome informado (nil se não existir)
+func findNamedItem(seq *yaml.Node, name string) *yaml.Node {
+	if seq == nil || name == "" {
+		return nil
+	}
+	for _, item := range seq.Content {
+		if itemName(item) == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// nodesEqual compara dois nós pelo conteúdo, ignorando estilo e comentários
+func nodesEqual(a, b *yaml.Node) bool {
+	a, b = resolveAlias(a), resolveAlias(b)
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode && a.ShortTag() != b.ShortTag() {
+		return false
+	}
+	for i := range a.Content {
+		if !nodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
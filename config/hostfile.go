@@ -0,0 +1,583 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Importação e exportação de hosts em CSV e JSON (sc hosts import/export).
+//
+// Colunas (CSV) e campos (JSON): name, host, port, user, jump, tags, addresses e vars.
+// No CSV, tags e addresses são separados por ";" (cada endereço como host[:porta][ via jump])
+// e cada var é uma coluna "vars.<nome>".
+// No JSON, o arquivo é uma lista de hosts ou um objeto com a chave "hosts" (como sc -s -o json).
+
+// hostFields são os campos de host aceitos na importação
+var hostFields = []string{"name", "host", "port", "user", "jump", "tags", "addresses"}
+
+// TagMergeStrategy define como as tags e os demais campos (host, port, user, jump, addresses, vars)
+// de um host já cadastrado são tratados na importação
+type TagMergeStrategy string
+
+const (
+	TagsMerge   TagMergeStrategy = "merge"   // União das tags e vars; campos vazios são preenchidos, os divergentes mantidos
+	TagsReplace TagMergeStrategy = "replace" // Tags e campos importados substituem os existentes (vars: por nome)
+	TagsKeep    TagMergeStrategy = "keep"    // Tags e campos existentes são mantidos
+)
+
+// ParseTagMergeStrategy valida o valor da flag --tags
+func ParseTagMergeStrategy(value string) (TagMergeStrategy, error) {
+	switch strategy := TagMergeStrategy(value); strategy {
+	case TagsMerge, TagsReplace, TagsKeep:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("estratégia de tags '%s' inválida (use merge, replace ou keep)", value)
+}
+
+// HostRecord é um host lido de um arquivo de importação
+type HostRecord struct {
+	Host    Host
+	Line    int  // Linha (CSV) ou posição (JSON) do host no arquivo
+	Unnamed bool // A linha não informou name (o nome é o endereço)
+}
+
+// ParseColumnMapping interpreta --map campo=coluna (ex: host=ip_address, vars.role=Função)
+func ParseColumnMapping(values []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, value := range values {
+		field, column, ok := strings.Cut(value, "=")
+		field, column = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("mapeamento '%s' inválido (use campo=coluna)", value)
+		}
+		if !slices.Contains(hostFields, field) && !strings.HasPrefix(field, "vars.") {
+			return nil, fmt.Errorf("campo '%s' inválido (use %s ou vars.<nome>)", field, strings.Join(hostFields, ", "))
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// ReadHostRecords lê hosts de um arquivo CSV ou JSON (format: "csv" ou "json")
+// mapping associa campos do host a nomes de colunas/chaves do arquivo (sem mapeamento, o nome é o próprio campo)
+func ReadHostRecords(r io.Reader, format string, mapping map[string]string) ([]HostRecord, error) {
+	var rows []map[string]any
+	var err error
+	switch format {
+	case "csv":
+		rows, err = readCSVRows(r)
+	case "json":
+		rows, err = readJSONRows(r)
+	default:
+		return nil, fmt.Errorf("formato '%s' inválido (use csv ou json)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []HostRecord
+	seen := make(map[string]int)
+	for i, row := range rows {
+		line := i + 1
+		if format == "csv" {
+			line = i + 2 // Linha 1 é o cabeçalho
+		}
+		host, err := hostFromRow(row, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", line, err)
+		}
+		if previous, ok := seen[host.Name]; ok {
+			return nil, fmt.Errorf("linha %d: host '%s' repetido (já definido na linha %d)", line, host.Name, previous)
+		}
+		seen[host.Name] = line
+		name, _ := lookupColumn(row, mapping, "name")
+		records = append(records, HostRecord{Host: host, Line: line, Unnamed: columnText(name) == ""})
+	}
+	return records, nil
+}
+
+// readCSVRows lê o CSV como uma lista de linhas indexadas pelo cabeçalho
+func readCSVRows(r io.Reader) ([]map[string]any, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CSV inválido: %w", err)
+	}
+
+	var rows []map[string]any
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV inválido: %w", err)
+		}
+		row := make(map[string]any)
+		for i, column := range header {
+			if i < len(fields) {
+				row[strings.TrimSpace(column)] = fields[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readJSONRows lê uma lista de hosts ou um objeto com a chave "hosts"
+func readJSONRows(r io.Reader) ([]map[string]any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(data, &rows); err == nil {
+		return rows, nil
+	}
+	var doc struct {
+		Hosts []map[string]any `json:"hosts"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("JSON inválido (esperada uma lista de hosts ou {\"hosts\": [...]}): %w", err)
+	}
+	return doc.Hosts, nil
+}
+
+// lookupColumn busca o valor de um campo na linha, pelo mapeamento ou pelo nome do campo (sem diferenciar maiúsculas)
+func lookupColumn(row map[string]any, mapping map[string]string, field string) (any, bool) {
+	column := field
+	if mapped, ok := mapping[field]; ok {
+		column = mapped
+	}
+	if value, ok := row[column]; ok {
+		return value, true
+	}
+	for key, value := range row {
+		if strings.EqualFold(key, column) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// columnText converte o valor de uma coluna em texto
+func columnText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// hostFromRow monta um host a partir de uma linha do arquivo
+func hostFromRow(row map[string]any, mapping map[string]string) (Host, error) {
+	text := func(field string) string {
+		value, _ := lookupColumn(row, mapping, field)
+		return columnText(value)
+	}
+
+	host := Host{
+		Name: text("name"),
+		Host: text("host"),
+		User: text("user"),
+		Jump: text("jump"),
+	}
+	if host.Name == "" {
+		host.Name = host.Host
+	}
+	if host.Name == "" {
+		return Host{}, fmt.Errorf("host sem name e sem host")
+	}
+	if portText := text("port"); portText != "" {
+		port, err := strconv.Atoi(portText)
+		if err != nil || port < 1 || port > 65535 {
+			return Host{}, fmt.Errorf("host '%s': porta inválida '%s'", host.Name, portText)
+		}
+		host.Port = port
+	}
+
+	// Tags: lista (JSON) ou texto separado por ";" ou ","
+	if value, ok := lookupColumn(row, mapping, "tags"); ok {
+		var tags []string
+		if list, isList := value.([]any); isList {
+			for _, item := range list {
+				tags = append(tags, columnText(item))
+			}
+		} else {
+			tags = strings.FieldsFunc(columnText(value), func(r rune) bool { return r == ';' || r == ',' })
+		}
+		for _, tag := range tags {
+			if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(host.Tags, tag) {
+				host.Tags = append(host.Tags, tag)
+			}
+		}
+	}
+	if host.Tags == nil {
+		host.Tags = []string{}
+	}
+
+	// Addresses: lista de objetos (JSON) ou texto separado por ";" (host[:porta][ via jump])
+	if value, ok := lookupColumn(row, mapping, "addresses"); ok {
+		addresses, err := parseImportedAddresses(value)
+		if err != nil {
+			return Host{}, fmt.Errorf("host '%s': %w", host.Name, err)
+		}
+		host.Addresses = addresses
+	}
+
+	// Vars: objeto "vars" (JSON), colunas "vars.<nome>" e campos mapeados com --map vars.<nome>=coluna
+	vars := make(map[string]string)
+	if value, ok := lookupColumn(row, mapping, "vars"); ok {
+		if object, isObject := value.(map[string]any); isObject {
+			for key, item := range object {
+				vars[key] = columnText(item)
+			}
+		}
+	}
+	for key, value := range row {
+		if name, ok := strings.CutPrefix(key, "vars."); ok && columnText(value) != "" {
+			vars[name] = columnText(value)
+		}
+	}
+	for field := range mapping {
+		if name, ok := strings.CutPrefix(field, "vars."); ok {
+			if value := text(field); value != "" {
+				vars[name] = value
+			}
+		}
+	}
+	if len(vars) > 0 {
+		host.Vars = vars
+	}
+	return host, nil
+}
+
+// HostsImportResult resume a mesclagem de hosts importados
+type HostsImportResult struct {
+	Added     []string
+	Updated   []string // Hosts já cadastrados com tags ou campos alterados
+	Unchanged []string // Hosts já cadastrados sem alteração
+	Conflicts []string // Campos com valores divergentes que foram mantidos (merge e keep)
+	Warnings  []string
+}
+
+// MergeHosts adiciona os hosts importados, deduplicando pelo nome (FindHost) e, para linhas sem name,
+// pelo endereço e porta (findHostByEndpoint): hosts com nomes diferentes nunca são mesclados
+// Para hosts já cadastrados, tags e campos são atualizados conforme a estratégia (mergeImportedHost)
+// Hosts do inventário dinâmico são somente leitura e não são alterados
+func (c *ConfigFile) MergeHosts(records []HostRecord, strategy TagMergeStrategy) HostsImportResult {
+	var result HostsImportResult
+	for _, record := range records {
+		imported := record.Host
+		existing := c.FindHost(imported.Name)
+		if existing == nil && record.Unnamed {
+			existing = c.findHostByEndpoint(imported.Host, imported.Port)
+		}
+		if existing == nil {
+			// Sem host e port, o host novo usa o nome como endereço e a porta padrão
+			// (em hosts já cadastrados, campos ausentes são mantidos)
+			if imported.Host == "" {
+				imported.Host = imported.Name
+			}
+			if imported.Port == 0 {
+				imported.Port = 22
+			}
+			c.AddHost(imported)
+			result.Added = append(result.Added, imported.Name)
+			continue
+		}
+
+		label := existing.Name
+		if existing.Name != imported.Name {
+			label = fmt.Sprintf("%s (importado como '%s')", existing.Name, imported.Name)
+		}
+		if existing.source != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("host %s vem do inventário '%s' e não foi alterado", label, existing.source))
+			result.Unchanged = append(result.Unchanged, label)
+			continue
+		}
+
+		changed, conflicts := mergeImportedHost(existing, imported, strategy)
+		for _, conflict := range conflicts {
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("host %s: %s", label, conflict))
+		}
+		if changed {
+			result.Updated = append(result.Updated, label)
+		} else {
+			result.Unchanged = append(result.Unchanged, label)
+		}
+	}
+	return result
+}
+
+// findHostByEndpoint procura um host cujo endereço (campo host ou um dos addresses) e porta
+// correspondam (porta 0 = 22)
+func (c *ConfigFile) findHostByEndpoint(address string, port int) *Host {
+	effective := func(port, fallback int) int {
+		if port == 0 {
+			return fallback
+		}
+		return port
+	}
+	port = effective(port, 22)
+	for i := range c.Hosts {
+		host := &c.Hosts[i]
+		hostPort := effective(host.Port, 22)
+		if host.Host == address && hostPort == port {
+			return host
+		}
+		if slices.ContainsFunc(host.Addresses, func(a HostAddress) bool {
+			return a.Host == address && effective(a.Port, hostPort) == port
+		}) {
+			return host
+		}
+	}
+	return nil
+}
+
+// mergeImportedHost aplica a um host já cadastrado as tags e os campos de um host importado
+// Campos vazios na importação são ignorados; com merge, apenas campos vazios são preenchidos e,
+// com keep, nada é alterado: nesses casos os valores divergentes são retornados como conflitos
+func mergeImportedHost(existing *Host, imported Host, strategy TagMergeStrategy) (bool, []string) {
+	changed := false
+	var conflicts []string
+
+	tags := existing.Tags
+	switch strategy {
+	case TagsMerge:
+		tags = slices.Clone(existing.Tags)
+		for _, tag := range imported.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	case TagsReplace:
+		tags = imported.Tags
+	}
+	if !slices.Equal(tags, existing.Tags) {
+		existing.Tags = tags
+		changed = true
+	}
+
+	// field aplica um campo: current é o valor atual, value o importado (vazio = não informado)
+	field := func(name, current, value string, set func()) {
+		if value == "" || value == current {
+			return
+		}
+		if strategy == TagsReplace || (strategy == TagsMerge && current == "") {
+			set()
+			changed = true
+			return
+		}
+		if current != "" {
+			conflicts = append(conflicts, fmt.Sprintf("%s '%s' mantido (importado: '%s')", name, current, value))
+		}
+	}
+	field("host", existing.Host, imported.Host, func() { existing.Host = imported.Host })
+	if imported.Port != 0 {
+		field("port", portText(existing.Port), strconv.Itoa(imported.Port), func() { existing.Port = imported.Port })
+	}
+	field("user", existing.User, imported.User, func() { existing.User = imported.User })
+	field("jump", existing.Jump, imported.Jump, func() { existing.Jump = imported.Jump })
+	field("addresses", FormatHostAddresses(existing.Addresses), FormatHostAddresses(imported.Addresses), func() { existing.Addresses = imported.Addresses })
+	for _, name := range slices.Sorted(maps.Keys(imported.Vars)) {
+		field("vars."+name, existing.Vars[name], imported.Vars[name], func() {
+			if existing.Vars == nil {
+				existing.Vars = make(map[string]string)
+			}
+			existing.Vars[name] = imported.Vars[name]
+		})
+	}
+	return changed, conflicts
+}
+
+// portText retorna a porta como texto (vazio quando não definida)
+func portText(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// ExportHosts escreve os hosts em CSV ou JSON, no formato aceito por ReadHostRecords
+func (c *ConfigFile) ExportHosts(w io.Writer, format string) error {
+	switch format {
+	case "csv":
+		return c.exportHostsCSV(w)
+	case "json":
+		return c.exportHostsJSON(w)
+	}
+	return fmt.Errorf("formato '%s' inválido (use csv ou json)", format)
+}
+
+// exportHostsCSV escreve uma linha por host, com uma coluna vars.<nome> para cada var usada
+func (c *ConfigFile) exportHostsCSV(w io.Writer) error {
+	varNames := make(map[string]bool)
+	for _, host := range c.Hosts {
+		for name := range host.Vars {
+			varNames[name] = true
+		}
+	}
+	sortedVars := slices.Sorted(maps.Keys(varNames))
+
+	writer := csv.NewWriter(w)
+	header := slices.Clone(hostFields)
+	for _, name := range sortedVars {
+		header = append(header, "vars."+name)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, host := range c.Hosts {
+		row := []string{host.Name, host.Host, portText(host.Port), host.User, host.Jump, strings.Join(host.Tags, ";"), FormatHostAddresses(host.Addresses)}
+		for _, name := range sortedVars {
+			row = append(row, host.Vars[name])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportedHost é um host no JSON de exportação
+type exportedHost struct {
+	Name      string            `json:"name"`
+	Host      string            `json:"host"`
+	Port      int               `json:"port,omitempty"` // 0 = padrão (rules ou 22)
+	User      string            `json:"user,omitempty"`
+	Jump      string            `json:"jump,omitempty"`
+	Tags      []string          `json:"tags"`
+	Addresses []HostAddress     `json:"addresses,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+}
+
+// exportHostsJSON escreve {"hosts": [...]}
+func (c *ConfigFile) exportHostsJSON(w io.Writer) error {
+	doc := struct {
+		Hosts []exportedHost `json:"hosts"`
+	}{Hosts: []exportedHost{}}
+	for _, host := range c.Hosts {
+		tags := host.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		doc.Hosts = append(doc.Hosts, exportedHost{
+			Name:      host.Name,
+			Host:      host.Host,
+			Port:      host.Port,
+			User:      host.User,
+			Jump:      host.Jump,
+			Tags:      tags,
+			Addresses: host.Addresses,
+			Vars:      host.Vars,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// parseImportedAddresses interpreta a coluna addresses: lista (JSON) de objetos {host, port, jump}
+// ou de textos, ou texto com os endereços separados por ";"
+func parseImportedAddresses(value any) ([]HostAddress, error) {
+	var items []any
+	if list, isList := value.([]any); isList {
+		items = list
+	} else {
+		for _, item := range strings.Split(columnText(value), ";") {
+			items = append(items, item)
+		}
+	}
+
+	var addresses []HostAddress
+	for _, item := range items {
+		var address HostAddress
+		if object, isObject := item.(map[string]any); isObject {
+			address.Host = columnText(object["host"])
+			address.Jump = columnText(object["jump"])
+			if portText := columnText(object["port"]); portText != "" {
+				port, err := strconv.Atoi(portText)
+				if err != nil || port < 1 || port > 65535 {
+					return nil, fmt.Errorf("porta inválida '%s' em addresses", portText)
+				}
+				address.Port = port
+			}
+		} else {
+			text := columnText(item)
+			if text == "" {
+				continue
+			}
+			parsed, err := parseHostAddress(text)
+			if err != nil {
+				return nil, err
+			}
+			address = parsed
+		}
+		if address.Host == "" {
+			return nil, fmt.Errorf("endereço sem host em addresses")
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// parseHostAddress interpreta um endereço alternativo no formato host[:porta][ via jump]
+// Endereços IPv6 com porta usam colchetes ([2001:db8::1]:2222)
+func parseHostAddress(text string) (HostAddress, error) {
+	var address HostAddress
+	text, jump, _ := strings.Cut(strings.TrimSpace(text), " via ")
+	address.Jump = strings.TrimSpace(jump)
+	text = strings.TrimSpace(text)
+
+	host, portText, err := net.SplitHostPort(text)
+	if err != nil {
+		// Sem porta: o texto é o host (IPv6 sem porta pode vir com ou sem colchetes)
+		host = strings.TrimSuffix(strings.TrimPrefix(text, "["), "]")
+	} else {
+		port, err := strconv.Atoi(portText)
+		if err != nil || port < 1 || port > 65535 {
+			return HostAddress{}, fmt.Errorf("porta inválida '%s' no endereço '%s'", portText, text)
+		}
+		address.Port = port
+	}
+	if host == "" || strings.ContainsAny(host, " \t") {
+		return HostAddress{}, fmt.Errorf("endereço '%s' inválido (use host[:porta][ via jump])", text)
+	}
+	address.Host = host
+	return address, nil
+}
+
+// FormatHostAddresses escreve os endereços alternativos separados por ";" (inverso de parseImportedAddresses)
+// Usado também pela coluna addresses da listagem em CSV (sc -s -o csv)
+func FormatHostAddresses(addresses []HostAddress) string {
+	var parts []string
+	for _, address := range addresses {
+		text := address.Host
+		if address.Port != 0 {
+			text = net.JoinHostPort(address.Host, strconv.Itoa(address.Port))
+		} else if strings.Contains(address.Host, ":") {
+			text = "[" + address.Host + "]"
+		}
+		if address.Jump != "" {
+			text += " via " + address.Jump
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, ";")
+}
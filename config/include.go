@@ -0,0 +1,474 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Arquivos de configuração adicionais:
+//
+//	include: [team/hosts.yaml, ~/projetos/*/sc.yaml]   globs relativos a ~/.sshControl
+//	~/.sshControl/conf.d/*.yaml                          carregados automaticamente, em ordem alfabética
+//	config.local_file                                    arquivo onde novos hosts são gravados
+//
+// Ordem de carga: config.yaml, include (na ordem da lista), conf.d e local_file. Arquivos
+// posteriores vencem: hosts, usuários, jump hosts e fontes de inventário com o mesmo nome são
+// substituídos, vars de tags são mescladas e as opções presentes na seção config sobrescrevem
+// as anteriores. Cada host, usuário e jump host lembra o arquivo de origem, e SaveConfig grava
+// cada alteração no arquivo que a possui (itens novos vão para local_file ou para o config.yaml).
+
+// ConfDirName é o diretório (dentro de ~/.sshControl) com arquivos carregados automaticamente
+const ConfDirName = "conf.d"
+
+// configLayer é um arquivo de configuração carregado, com o conteúdo original (usado por SaveConfig)
+type configLayer struct {
+	path       string
+	original   ConfigFile
+	configKeys []string        // Chaves presentes na seção config do arquivo (arquivos adicionais)
+	shadowed   map[string]bool // Itens do arquivo sobrescritos por um arquivo carregado depois ("hosts/<nome>", "users/<nome>"...)
+}
+
+// configListKeys são as listas da seção config mescladas por nome (e não sobrescritas)
+var configListKeys = []string{"users", "jump_hosts", "inventory_sources"}
+
+// includeFiles retorna os arquivos adicionais na ordem de carga (sem repetições e sem o config.yaml)
+func (c *ConfigFile) includeFiles() ([]string, error) {
+	baseDir := filepath.Dir(c.path)
+	var files []string
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if path != filepath.Clean(c.path) && !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+
+	for _, pattern := range c.Include {
+		pattern = ExpandHomePath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include '%s' inválido: %w", pattern, err)
+		}
+		slices.Sort(matches)
+		for _, match := range matches {
+			add(match)
+		}
+	}
+
+	var confFiles []string
+	for _, ext := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(baseDir, ConfDirName, ext))
+		confFiles = append(confFiles, matches...)
+	}
+	slices.Sort(confFiles)
+	for _, file := range confFiles {
+		add(file)
+	}
+
+	if localFile := c.localFilePath(); localFile != "" && fileExists(localFile) {
+		add(localFile)
+	}
+	return files, nil
+}
+
+// localFilePath retorna o caminho de config.local_file (vazio se não configurado)
+func (c *ConfigFile) localFilePath() string {
+	if c.Config.LocalFile == "" {
+		return ""
+	}
+	path := ExpandHomePath(c.Config.LocalFile)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(c.path), path)
+	}
+	return filepath.Clean(path)
+}
+
+// loadIncludes carrega os arquivos adicionais e os mescla à configuração
+func (c *ConfigFile) loadIncludes() error {
+	files, err := c.includeFiles()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("erro ao ler %s: %w", file, err)
+		}
+		if err := c.mergeLayer(file, data); err != nil {
+			return fmt.Errorf("erro ao parsear %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// mergeLayer mescla um arquivo adicional à configuração (o arquivo vence em caso de conflito)
+func (c *ConfigFile) mergeLayer(path string, data []byte) error {
+	var layer ConfigFile
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return err
+	}
+	original, err := decodeLayer(data)
+	if err != nil {
+		return err
+	}
+	loaded := &configLayer{path: path, original: original}
+	c.layers = append(c.layers, loaded)
+
+	// Opções escalares presentes na seção config sobrescrevem as anteriores
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if section := configSection(&doc); section != nil {
+		scalars := *section
+		scalars.Content = nil
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			key := section.Content[i]
+			loaded.configKeys = append(loaded.configKeys, key.Value)
+			if !slices.Contains(configListKeys, key.Value) {
+				scalars.Content = append(scalars.Content, key, section.Content[i+1])
+			}
+		}
+		if err := scalars.Decode(&c.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, user := range layer.Config.User {
+		user.file = path
+		if i := slices.IndexFunc(c.Config.User, func(u User) bool { return u.Name == user.Name }); i >= 0 {
+			c.shadow(c.Config.User[i].file, "users", user.Name)
+			c.Config.User[i] = user
+		} else {
+			c.Config.User = append(c.Config.User, user)
+		}
+	}
+	for _, jumpHost := range layer.Config.JumpHosts {
+		jumpHost.file = path
+		if i := slices.IndexFunc(c.Config.JumpHosts, func(j JumpHost) bool { return j.Name == jumpHost.Name }); i >= 0 {
+			c.shadow(c.Config.JumpHosts[i].file, "jump_hosts", jumpHost.Name)
+			c.Config.JumpHosts[i] = jumpHost
+		} else {
+			c.Config.JumpHosts = append(c.Config.JumpHosts, jumpHost)
+		}
+	}
+	for _, source := range layer.Config.InventorySources {
+		if i := slices.IndexFunc(c.Config.InventorySources, func(s InventorySource) bool { return s.SourceName() == source.SourceName() }); i >= 0 {
+			c.Config.InventorySources[i] = source
+		} else {
+			c.Config.InventorySources = append(c.Config.InventorySources, source)
+		}
+	}
+	for _, host := range layer.Hosts {
+		host.file, host.loaded = path, host.Name
+		if i := slices.IndexFunc(c.Hosts, func(h Host) bool { return h.Name == host.Name }); i >= 0 {
+			c.shadow(c.Hosts[i].file, "hosts", host.Name)
+			c.Hosts[i] = host
+		} else {
+			c.Hosts = append(c.Hosts, host)
+		}
+	}
+	// Regras dos arquivos incluídos são avaliadas depois das já carregadas
+	c.Rules = append(c.Rules, layer.Rules...)
+	for name, tag := range layer.Tags {
+		if c.Tags == nil {
+			c.Tags = make(map[string]TagConfig)
+		}
+		merged := c.Tags[name]
+		if merged.Vars == nil && tag.Vars != nil {
+			merged.Vars = make(map[string]string)
+		}
+		maps.Copy(merged.Vars, tag.Vars)
+		c.Tags[name] = merged
+	}
+	return nil
+}
+
+// configSection retorna o MappingNode da seção config de um documento (nil se ausente)
+func configSection(doc *yaml.Node) *yaml.Node {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	index := findKeyIndex(doc.Content[0], "config")
+	if index < 0 || doc.Content[0].Content[index+1].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0].Content[index+1]
+}
+
+// decodeLayer decodifica o conteúdo original de um arquivo (sem mesclas nem valores padrão)
+func decodeLayer(data []byte) (ConfigFile, error) {
+	var layer ConfigFile
+	err := yaml.Unmarshal(data, &layer)
+	return layer, err
+}
+
+// markOwner registra o arquivo de origem dos itens do config.yaml principal
+func (c *ConfigFile) markOwner(path string) {
+	for i := range c.Hosts {
+		c.Hosts[i].file, c.Hosts[i].loaded = path, c.Hosts[i].Name
+	}
+	for i := range c.Config.User {
+		c.Config.User[i].file = path
+	}
+	for i := range c.Config.JumpHosts {
+		c.Config.JumpHosts[i].file = path
+	}
+}
+
+// shadow registra que o item do arquivo path foi sobrescrito por um arquivo carregado depois
+// Alterações feitas no item vencedor (remoção, renomeação) não atingem o do arquivo sobrescrito
+func (c *ConfigFile) shadow(path, list, name string) {
+	for _, layer := range c.layers {
+		if layer.path == path {
+			if layer.shadowed == nil {
+				layer.shadowed = make(map[string]bool)
+			}
+			layer.shadowed[list+"/"+name] = true
+		}
+	}
+}
+
+// saveTarget retorna o arquivo que recebe itens novos: config.local_file ou o config.yaml
+func (c *ConfigFile) saveTarget() string {
+	if localFile := c.localFilePath(); localFile != "" {
+		return localFile
+	}
+	return c.path
+}
+
+// layerContent reconstrói o conteúdo de um arquivo com as alterações feitas na configuração
+// Itens sobrescritos por um arquivo carregado depois são mantidos como estavam
+func (c *ConfigFile) layerContent(layer *configLayer) ConfigFile {
+	content := layer.original
+	isTarget := layer.path == c.saveTarget()
+	owns := func(file string) bool {
+		return file == layer.path || (file == "" && isTarget)
+	}
+
+	var static []Host
+	for _, host := range c.Hosts {
+		if host.source == "" {
+			static = append(static, host)
+		}
+	}
+	shadowed := func(list string) func(name string) bool {
+		return func(name string) bool { return layer.shadowed[list+"/"+name] }
+	}
+	content.Hosts = layerItems(layer.original.Hosts, static, func(h Host) itemKey { return itemKey{h.Name, h.loaded, h.file} }, owns, shadowed("hosts"))
+	content.Config.User = layerItems(layer.original.Config.User, c.Config.User, func(u User) itemKey { return itemKey{u.Name, u.Name, u.file} }, owns, shadowed("users"))
+	content.Config.JumpHosts = layerItems(layer.original.Config.JumpHosts, c.Config.JumpHosts, func(j JumpHost) itemKey { return itemKey{j.Name, j.Name, j.file} }, owns, shadowed("jump_hosts"))
+
+	// Tags alteradas desde a carga vão para o último arquivo que as define (ou para o destino de itens novos)
+	content.Tags = maps.Clone(layer.original.Tags)
+	for name, tag := range c.Tags {
+		if reflect.DeepEqual(tag, c.loadedTags[name]) || c.tagOwner(name) != layer.path {
+			continue
+		}
+		if content.Tags == nil {
+			content.Tags = make(map[string]TagConfig)
+		}
+		content.Tags[name] = tag
+	}
+	for name := range content.Tags {
+		if _, ok := c.Tags[name]; !ok {
+			delete(content.Tags, name)
+		}
+	}
+	return content
+}
+
+// itemKey identifica um item de lista: nome atual, nome na carga (acompanha renomeações) e arquivo de origem
+type itemKey struct {
+	name   string
+	loaded string
+	file   string
+}
+
+// layerItems monta uma lista de um arquivo: mantém a ordem original, usa a versão atual dos itens
+// que pertencem ao arquivo, preserva os sobrescritos por outro arquivo e acrescenta os novos no fim
+func layerItems[T any](original, current []T, key func(T) itemKey, owns func(file string) bool, shadowed func(name string) bool) []T {
+	var items []T
+	written := make(map[int]bool)
+	for _, item := range original {
+		name := key(item).name
+		// Sobrescrito na carga: a remoção ou renomeação do item vencedor não o atinge
+		if shadowed(name) {
+			items = append(items, item)
+			continue
+		}
+		// Versão atual do item (inclusive se renomeado)
+		if i := slices.IndexFunc(current, func(c T) bool { k := key(c); return owns(k.file) && k.loaded == name }); i >= 0 && name != "" {
+			items = append(items, current[i])
+			written[i] = true
+			continue
+		}
+		// Sobrescrito por um arquivo carregado depois: mantém como estava (senão, foi removido)
+		if i := slices.IndexFunc(current, func(c T) bool { return key(c).name == name }); i >= 0 && !owns(key(current[i]).file) {
+			items = append(items, item)
+		}
+	}
+	for i, item := range current {
+		if !written[i] && owns(key(item).file) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// tagOwner retorna o último arquivo carregado que define a tag (ou o destino de itens novos)
+func (c *ConfigFile) tagOwner(name string) string {
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if _, ok := c.layers[i].original.Tags[name]; ok {
+			return c.layers[i].path
+		}
+	}
+	return c.saveTarget()
+}
+
+// saveLayers grava os arquivos cujo conteúdo mudou (o destino de itens novos é criado se necessário)
+// Cada arquivo é editado em nível de nó: apenas as entradas alteradas mudam
+func (c *ConfigFile) saveLayers() error {
+	unlock, err := lockConfigDir(filepath.Dir(c.path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	backups := newBackupSet(filepath.Dir(c.path))
+	defer backups.finish()
+
+	layers := slices.Clone(c.layers)
+	target := c.saveTarget()
+	if !slices.ContainsFunc(layers, func(l *configLayer) bool { return l.path == target }) {
+		layers = append(layers, &configLayer{path: target})
+	}
+
+	for _, layer := range layers {
+		content := c.layerContent(layer)
+		before, err := c.layerNode(layer, &layer.original)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar configuração: %w", err)
+		}
+		after, err := c.layerNode(layer, &content)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar configuração: %w", err)
+		}
+		if nodesEqual(before, after) && fileExists(layer.path) {
+			continue
+		}
+
+		doc, err := readDocument(layer.path)
+		if err != nil {
+			return fmt.Errorf("erro ao ler %s: %w", layer.path, err)
+		}
+		syncNode(doc.root(), before, after, c.hostRenames(layer.path))
+
+		if err := os.MkdirAll(filepath.Dir(layer.path), 0700); err != nil {
+			return fmt.Errorf("erro ao criar diretório de %s: %w", layer.path, err)
+		}
+		if err := backups.add(layer.path); err != nil {
+			return err
+		}
+		if err := doc.write(layer.path); err != nil {
+			return err
+		}
+
+		// O conteúdo gravado passa a ser a referência para as próximas gravações
+		data, err := yaml.Marshal(&content)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar configuração: %w", err)
+		}
+		if layer.original, err = decodeLayer(data); err != nil {
+			return fmt.Errorf("erro ao serializar configuração: %w", err)
+		}
+		c.markSaved(layer.path, layer.path == target)
+	}
+	c.loadedTags = cloneTags(c.Tags)
+	return nil
+}
+
+// hostRenames retorna os hosts do arquivo renomeados desde a carga (nome novo -> nome anterior)
+func (c *ConfigFile) hostRenames(path string) map[string]string {
+	renames := make(map[string]string)
+	for _, host := range c.Hosts {
+		if host.file == path && host.loaded != "" && host.loaded != host.Name {
+			renames[host.Name] = host.loaded
+		}
+	}
+	return renames
+}
+
+// markSaved atualiza a origem dos itens após gravar um arquivo (itens novos passam a pertencer ao destino)
+func (c *ConfigFile) markSaved(path string, isTarget bool) {
+	for i := range c.Hosts {
+		host := &c.Hosts[i]
+		if host.source == "" && (host.file == path || (host.file == "" && isTarget)) {
+			host.file, host.loaded = path, host.Name
+		}
+	}
+	for i := range c.Config.User {
+		if c.Config.User[i].file == "" && isTarget {
+			c.Config.User[i].file = path
+		}
+	}
+	for i := range c.Config.JumpHosts {
+		if c.Config.JumpHosts[i].file == "" && isTarget {
+			c.Config.JumpHosts[i].file = path
+		}
+	}
+}
+
+// layerNode converte o conteúdo de um arquivo em yaml.Node
+// Em arquivos adicionais, a seção config mantém apenas as chaves que o arquivo já definia
+// (e as listas não vazias), para não sobrescrever as opções do config.yaml com valores vazios
+func (c *ConfigFile) layerNode(layer *configLayer, content *ConfigFile) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(content); err != nil {
+		return nil, err
+	}
+	if layer.path == c.path {
+		return &node, nil
+	}
+
+	if section := configSection(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}); section != nil {
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			key, value := section.Content[i], section.Content[i+1]
+			if slices.Contains(layer.configKeys, key.Value) ||
+				(slices.Contains(configListKeys, key.Value) && len(value.Content) > 0) {
+				kept = append(kept, key, value)
+			}
+		}
+		section.Content = kept
+		if len(kept) == 0 {
+			index := findKeyIndex(&node, "config")
+			node.Content = slices.Delete(node.Content, index, index+2)
+		}
+	}
+	if len(content.Include) == 0 {
+		if index := findKeyIndex(&node, "include"); index >= 0 {
+			node.Content = slices.Delete(node.Content, index, index+2)
+		}
+	}
+	return &node, nil
+}
+
+// cloneTags copia as tags (incluindo os mapas de vars) para comparação em SaveConfig
+func cloneTags(tags map[string]TagConfig) map[string]TagConfig {
+	if tags == nil {
+		return nil
+	}
+	cloned := make(map[string]TagConfig, len(tags))
+	for name, tag := range tags {
+		cloned[name] = TagConfig{Vars: maps.Clone(tag.Vars)}
+	}
+	return cloned
+}
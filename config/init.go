@@ -17,12 +17,18 @@ const (
 
 // defaultConfigTemplate é o template do arquivo de configuração padrão
 const defaultConfigTemplate = `---
+version: 1                      # Versão do formato do arquivo (atualizada automaticamente pelo sc)
+include: []                     # Arquivos adicionais (globs relativos a ~/.sshControl), além de conf.d/*.yaml
 config:
   default_user: ubuntu
   auto_create: false            # Se true, salva hosts não cadastrados automaticamente com tag "autocreated"
   dir_cp_default: ~/sshControl  # Diretório padrão para downloads via 'sc cp down'
   proxy: "192.168.0.1:3128"     # IP:PORT do proxy HTTP/HTTPS/FTP na máquina local
   proxy_port: 9999              # Porta local no host remoto para acessar o proxy
+  connect_timeout: 30s          # Tempo máximo para conectar (TCP + handshake SSH), 0s = sem limite
+  command_timeout: 0s           # Tempo máximo de execução de comandos e transferências, 0s = sem limite
+  inventory_sources: []         # Fontes de inventário dinâmico: executáveis que retornam hosts em JSON
+  local_file: ""                # Arquivo onde novos hosts são gravados (ex: local.yaml), vazio = config.yaml
   users:
     - name: ubuntu
       ssh_keys:
@@ -55,6 +61,8 @@ hosts:
       - web
       - proxy
       - infra
+    vars: # Variáveis por host usadas em templates (ex: -c 'systemctl status {{.Vars.service}}')
+      service: traefik
 ...
 `
 
@@ -72,7 +80,7 @@ func InitializeConfigDir() (string, error) {
 	// Verifica se o diretório existe
 	if _, err := os.Stat(configDir); os.IsNotExist(err) {
 		// Cria o diretório
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := os.MkdirAll(configDir, 0700); err != nil {
 			return "", fmt.Errorf("erro ao criar diretório %s: %w", configDir, err)
 		}
 		fmt.Printf("✓ Diretório criado: %s\n", configDir)
@@ -84,16 +92,24 @@ func InitializeConfigDir() (string, error) {
 	// Verifica se o arquivo de configuração existe
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		// Cria o arquivo com o template padrão
-		if err := os.WriteFile(configFile, []byte(defaultConfigTemplate), 0644); err != nil {
+		if err := writeFileAtomic(configFile, []byte(defaultConfigTemplate)); err != nil {
 			return "", fmt.Errorf("erro ao criar arquivo de configuração: %w", err)
 		}
 		fmt.Printf("✓ Arquivo de configuração criado: %s\n", configFile)
 		fmt.Println("✓ Configuração de exemplo criada. Edite o arquivo para adicionar seus hosts.")
 		fmt.Println()
 	} else {
-		// Config já existe - verifica se há novas opções a serem adicionadas
-		if err := MigrateConfig(configFile); err != nil {
-			fmt.Fprintf(os.Stderr, "⚠️  Aviso: erro ao verificar atualizações do config: %v\n", err)
+		// Arquivos criados por versões anteriores (0644) passam a ser legíveis apenas pelo dono
+		if info, err := os.Stat(configFile); err == nil && info.Mode().Perm()&0077 != 0 {
+			os.Chmod(configFile, configFileMode)
+		}
+
+		// Config já existe - aplica as migrações pendentes do formato
+		result, err := MigrateConfig(configFile, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: erro ao migrar o config: %v\n", err)
+		} else if result.Changed() {
+			result.WriteSummary(os.Stderr)
 		}
 	}
 
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Inventário dinâmico: cada fonte (config.inventory_sources) é um executável local que escreve
+// no stdout um JSON no formato abaixo. Os hosts são mesclados aos do config.yaml em LoadConfig,
+// mas nunca são gravados de volta por SaveConfig.
+//
+//	{
+//	  "hosts": [
+//	    {"name": "web1", "host": "10.0.0.5", "port": 22, "user": "deploy", "tags": ["web", "env/prod"],
+//	     "vars": {"role": "frontend"}, "jump": "production-jump"}
+//	  ],
+//	  "tags": {"web": {"vars": {"app_dir": "/var/www"}}}
+//	}
+//
+// Apenas "name" é obrigatório; "host" assume o nome e, sem "port", a porta vem das rules ou é 22.
+
+const (
+	// InventoryCacheDirName é o diretório (dentro de ~/.sshControl) com a saída das fontes de inventário
+	InventoryCacheDirName = "cache"
+
+	// defaultInventoryCacheTTL é o tempo de validade do cache de uma fonte sem cache_ttl
+	defaultInventoryCacheTTL = 5 * time.Minute
+
+	// defaultInventoryTimeout é o tempo máximo de execução de uma fonte sem timeout
+	defaultInventoryTimeout = 30 * time.Second
+)
+
+// inventoryNamePattern valida nomes de fontes (usados no nome do arquivo de cache)
+var inventoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// InventorySource representa uma fonte de inventário dinâmico
+type InventorySource struct {
+	Name     string        `yaml:"name"`                // Identificação da fonte (padrão: nome do executável)
+	Command  string        `yaml:"command"`             // Executável local que escreve o inventário em JSON no stdout
+	Args     []string      `yaml:"args,omitempty"`      // Argumentos do executável
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"` // Validade do cache (padrão: 5m)
+	Timeout  time.Duration `yaml:"timeout,omitempty"`   // Tempo máximo de execução (padrão: 30s)
+}
+
+// inventoryDocument é o JSON retornado por uma fonte de inventário
+type inventoryDocument struct {
+	Hosts []inventoryHost         `json:"hosts"`
+	Tags  map[string]inventoryTag `json:"tags"`
+}
+
+// inventoryHost é um host retornado por uma fonte de inventário
+type inventoryHost struct {
+	Name      string            `json:"name"`
+	Host      string            `json:"host"`
+	Port      int               `json:"port"`
+	User      string            `json:"user"`
+	Tags      []string          `json:"tags"`
+	Vars      map[string]string `json:"vars"`
+	Jump      string            `json:"jump"`
+	Addresses []HostAddress     `json:"addresses"`
+}
+
+// inventoryTag são as configurações de uma tag retornadas por uma fonte de inventário
+type inventoryTag struct {
+	Vars map[string]string `json:"vars"`
+}
+
+// InventoryResult é o resultado do carregamento de uma fonte de inventário
+type InventoryResult struct {
+	Source string
+	Hosts  int       // Hosts adicionados (exclui nomes já existentes)
+	Cached bool      // Carregado do cache, sem executar a fonte
+	Stale  bool      // A fonte falhou e foi usado um cache expirado
+	Err    error     // Erro da fonte (com Stale, o cache expirado foi usado)
+	At     time.Time // Momento em que o inventário foi obtido da fonte
+}
+
+// SourceName retorna o nome da fonte (padrão: nome do executável)
+func (s *InventorySource) SourceName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return filepath.Base(s.Command)
+}
+
+// validate verifica os campos da fonte
+func (s *InventorySource) validate() error {
+	if s.Command == "" {
+		return fmt.Errorf("inventário '%s': command é obrigatório", s.Name)
+	}
+	if !inventoryNamePattern.MatchString(s.SourceName()) {
+		return fmt.Errorf("inventário '%s': nome inválido (use letras, números, '.', '_' e '-')", s.SourceName())
+	}
+	return nil
+}
+
+// cacheTTL retorna a validade do cache da fonte
+func (s *InventorySource) cacheTTL() time.Duration {
+	if s.CacheTTL > 0 {
+		return s.CacheTTL
+	}
+	return defaultInventoryCacheTTL
+}
+
+// run executa a fonte e retorna o JSON produzido
+func (s *InventorySource) run() ([]byte, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultInventoryTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, ExpandHomePath(s.Command), s.Args...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	command.Env = append(os.Environ(), "SC_INVENTORY_SOURCE="+s.SourceName())
+
+	if err := command.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("tempo limite de %s excedido", timeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return nil, fmt.Errorf("%w: %s", err, detail)
+		}
+		return nil, err
+	}
+	if _, err := parseInventory(stdout.Bytes()); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseInventory interpreta e valida o JSON de uma fonte de inventário
+func parseInventory(data []byte) (*inventoryDocument, error) {
+	var doc inventoryDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("JSON inválido: %w", err)
+	}
+	for i, host := range doc.Hosts {
+		if host.Name == "" {
+			return nil, fmt.Errorf("host %d sem name", i+1)
+		}
+		if host.Port < 0 || host.Port > 65535 {
+			return nil, fmt.Errorf("host '%s': porta inválida %d", host.Name, host.Port)
+		}
+	}
+	return &doc, nil
+}
+
+// inventoryCacheDir retorna o diretório de cache do inventário (ao lado do config.yaml)
+func (c *ConfigFile) inventoryCacheDir() string {
+	return filepath.Join(filepath.Dir(c.path), InventoryCacheDirName)
+}
+
+// inventoryCachePath retorna o arquivo de cache de uma fonte
+func (c *ConfigFile) inventoryCachePath(source *InventorySource) string {
+	return filepath.Join(c.inventoryCacheDir(), "inventory-"+source.SourceName()+".json")
+}
+
+// LoadInventory carrega as fontes de inventário dinâmico e mescla seus hosts à configuração
+// Fontes com cache válido não são executadas, exceto com refresh. Se uma fonte falhar,
+// o cache expirado (se houver) é usado. names limita as fontes atualizadas com refresh.
+// Hosts do config.yaml (e de fontes anteriores) têm precedência em caso de nomes repetidos.
+func (c *ConfigFile) LoadInventory(refresh bool, names ...string) []InventoryResult {
+	var results []InventoryResult
+	for i := range c.Config.InventorySources {
+		source := &c.Config.InventorySources[i]
+		result := InventoryResult{Source: source.SourceName()}
+		if err := source.validate(); err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		force := refresh && (len(names) == 0 || slices.Contains(names, source.SourceName()))
+		data, at, cached, err := c.readInventorySource(source, force)
+		if err != nil {
+			result.Err = err
+			if data == nil {
+				results = append(results, result)
+				continue
+			}
+			result.Stale = true
+		}
+		result.Cached = cached
+		result.At = at
+
+		doc, err := parseInventory(data)
+		if err != nil {
+			result.Err = fmt.Errorf("cache inválido: %w", err)
+			results = append(results, result)
+			continue
+		}
+		result.Hosts = c.mergeInventory(source.SourceName(), doc)
+		results = append(results, result)
+	}
+	return results
+}
+
+// readInventorySource obtém o JSON da fonte: do cache, se válido, ou executando a fonte
+// Se a execução falhar e houver cache expirado, retorna o cache junto com o erro
+func (c *ConfigFile) readInventorySource(source *InventorySource, force bool) ([]byte, time.Time, bool, error) {
+	cachePath := c.inventoryCachePath(source)
+	cacheInfo, statErr := os.Stat(cachePath)
+	if statErr == nil && !force && time.Since(cacheInfo.ModTime()) < source.cacheTTL() {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return data, cacheInfo.ModTime(), true, nil
+		}
+	}
+
+	data, runErr := source.run()
+	if runErr == nil {
+		if err := os.MkdirAll(c.inventoryCacheDir(), 0700); err == nil {
+			_ = os.WriteFile(cachePath, data, 0600)
+		}
+		return data, time.Now(), false, nil
+	}
+
+	// Fonte indisponível: usa o cache expirado, se houver
+	if statErr == nil {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return data, cacheInfo.ModTime(), true, runErr
+		}
+	}
+	return nil, time.Time{}, false, runErr
+}
+
+// mergeInventory adiciona os hosts e as vars de tags de uma fonte, retornando quantos hosts foram adicionados
+func (c *ConfigFile) mergeInventory(source string, doc *inventoryDocument) int {
+	added := 0
+	for _, h := range doc.Hosts {
+		if c.FindHost(h.Name) != nil {
+			continue
+		}
+		host := Host{
+			Name:      h.Name,
+			Host:      h.Host,
+			Port:      h.Port,
+			User:      h.User,
+			Tags:      h.Tags,
+			Vars:      h.Vars,
+			Jump:      h.Jump,
+			Addresses: h.Addresses,
+			source:    source,
+		}
+		// Sem port, a porta vem das rules ou do padrão (22) na conexão, como nos hosts do config.yaml
+		if host.Host == "" {
+			host.Host = host.Name
+		}
+		c.Hosts = append(c.Hosts, host)
+		added++
+	}
+
+	// Vars de tags do config.yaml têm precedência sobre as do inventário
+	for name, tag := range doc.Tags {
+		if _, ok := c.Tags[name]; ok {
+			continue
+		}
+		if _, ok := c.inventoryTags[name]; ok {
+			continue
+		}
+		if c.inventoryTags == nil {
+			c.inventoryTags = make(map[string]TagConfig)
+		}
+		c.inventoryTags[name] = TagConfig{Vars: tag.Vars}
+	}
+	return added
+}
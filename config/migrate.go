@@ -2,176 +2,250 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
 
-// MigrateConfig verifica se o config.yaml do usuário possui todas as chaves do template.
-// Chaves faltantes são adicionadas com os valores padrão do template, sem remover dados existentes.
-func MigrateConfig(configPath string) error {
-	// Lê o arquivo do usuário
-	userData, err := os.ReadFile(configPath)
-	if err != nil {
-		return fmt.Errorf("erro ao ler config para migração: %w", err)
-	}
+// Migrações do formato do config.yaml
+// O campo "version" indica o formato do arquivo. Cada migração converte a versão N na N+1
+// alterando o yaml.Node do arquivo (comentários, ordem e formatação do resto são preservados).
+// Para mudar o formato (renomear ou reestruturar campos), acrescente uma função ao fim de
+// configMigrations e atualize "version" no defaultConfigTemplate.
 
-	// Parse do config do usuário como yaml.Node (preserva comentários e ordem)
-	var userDoc yaml.Node
-	if err := yaml.Unmarshal(userData, &userDoc); err != nil {
-		return fmt.Errorf("erro ao parsear config do usuário: %w", err)
-	}
+// configMigration converte o config.yaml da versão N (índice em configMigrations) para N+1
+type configMigration struct {
+	description string
+	apply       func(root *yaml.Node) ([]string, error) // Retorna as alterações feitas, para o resumo
+}
 
-	// Parse do template como yaml.Node
-	var templateDoc yaml.Node
-	if err := yaml.Unmarshal([]byte(defaultConfigTemplate), &templateDoc); err != nil {
-		return fmt.Errorf("erro ao parsear template: %w", err)
-	}
+// configMigrations é a lista ordenada de migrações: o índice i converte a versão i para i+1
+var configMigrations = []configMigration{
+	{description: "adiciona o campo version e as opções ausentes", apply: migrateV0ToV1},
+}
 
-	// O documento YAML raiz tem Kind=DocumentNode e o conteúdo está em Content[0]
-	if userDoc.Kind != yaml.DocumentNode || len(userDoc.Content) == 0 {
-		return fmt.Errorf("config do usuário tem formato inesperado")
-	}
-	if templateDoc.Kind != yaml.DocumentNode || len(templateDoc.Content) == 0 {
-		return fmt.Errorf("template tem formato inesperado")
-	}
+// CurrentConfigVersion é a versão do formato do config.yaml gerado por esta versão do sc
+var CurrentConfigVersion = len(configMigrations)
 
-	userRoot := userDoc.Content[0]
-	templateRoot := templateDoc.Content[0]
+// AppliedMigration descreve uma migração aplicada (ou pendente, em modo de simulação)
+type AppliedMigration struct {
+	From        int
+	To          int
+	Description string
+	Changes     []string
+}
 
-	// Faz o merge recursivo
-	changed := mergeNodes(userRoot, templateRoot)
+// MigrationResult é o resultado de MigrateConfig
+type MigrationResult struct {
+	From       int
+	To         int
+	Migrations []AppliedMigration
+	Backup     string // Cópia de segurança feita antes da migração (vazio em simulação ou sem alterações)
+	DryRun     bool
+}
 
-	if !changed {
-		return nil
-	}
+// Changed indica se alguma migração foi (ou seria) aplicada
+func (r *MigrationResult) Changed() bool {
+	return len(r.Migrations) > 0
+}
 
-	// Serializa de volta para YAML
-	out, err := yaml.Marshal(&userDoc)
-	if err != nil {
-		return fmt.Errorf("erro ao serializar config migrado: %w", err)
+// WriteSummary exibe as migrações aplicadas (ou pendentes, em modo de simulação)
+func (r *MigrationResult) WriteSummary(w io.Writer) {
+	if !r.Changed() {
+		fmt.Fprintf(w, "✅ config.yaml já está na versão %d\n", r.To)
+		return
 	}
-
-	// Adiciona marcadores YAML
-	content := "---\n" + string(out) + "...\n"
-
-	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("erro ao salvar config migrado: %w", err)
+	if r.DryRun {
+		fmt.Fprintf(w, "🔎 Migrações pendentes do config.yaml (versão %d → %d), nada foi gravado:\n", r.From, r.To)
+	} else {
+		fmt.Fprintf(w, "✓ config.yaml migrado da versão %d para a %d (cópia de segurança em %s):\n", r.From, r.To, r.Backup)
+	}
+	for _, migration := range r.Migrations {
+		fmt.Fprintf(w, "   • v%d → v%d: %s\n", migration.From, migration.To, migration.Description)
+		for _, change := range migration.Changes {
+			fmt.Fprintf(w, "       %s\n", change)
+		}
 	}
-
-	fmt.Println("✓ Configuração atualizada com novas opções disponíveis.")
-	return nil
 }
 
-// mergeNodes faz merge recursivo de nós YAML.
-// Adiciona ao dst (usuário) chaves que existem em src (template) mas não em dst.
-// Nunca sobrescreve valores existentes do usuário.
-// Retorna true se houve alguma adição.
-func mergeNodes(dst, src *yaml.Node) bool {
-	// Só faz merge de mappings
-	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
-		return false
+// MigrateConfig aplica as migrações pendentes ao config.yaml, da versão do arquivo até CurrentConfigVersion.
+// Antes de gravar, o arquivo é copiado para ~/.sshControl/backups. Com dryRun, nada é gravado.
+func MigrateConfig(configPath string, dryRun bool) (*MigrationResult, error) {
+	// Verificação rápida sem trava: a maioria das execuções encontra o arquivo já atualizado
+	doc, err := readMigrationDocument(configPath)
+	if err != nil {
+		return nil, err
+	}
+	version, err := configVersion(doc.root())
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentConfigVersion {
+		return nil, fmt.Errorf("config.yaml na versão %d, mais nova que a suportada por este sc (%d): atualize o sc", version, CurrentConfigVersion)
+	}
+	result := &MigrationResult{From: version, To: version, DryRun: dryRun}
+	if version == CurrentConfigVersion {
+		return result, nil
 	}
 
-	changed := false
-
-	// Percorre pares key/value do template (src)
-	for i := 0; i < len(src.Content)-1; i += 2 {
-		srcKey := src.Content[i]
-		srcVal := src.Content[i+1]
+	if !dryRun {
+		unlock, err := lockConfigDir(filepath.Dir(configPath))
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
 
-		// Procura a chave no config do usuário (dst)
-		dstIdx := findKeyIndex(dst, srcKey.Value)
+		// Relê com a trava: outro processo pode ter migrado o arquivo nesse meio tempo
+		if doc, err = readMigrationDocument(configPath); err != nil {
+			return nil, err
+		}
+		if version, err = configVersion(doc.root()); err != nil {
+			return nil, err
+		}
+		result.From, result.To = version, version
+		if version >= CurrentConfigVersion {
+			return result, nil
+		}
+	}
 
-		if dstIdx == -1 {
-			// Chave não existe no config do usuário - adiciona
-			keyCopy := copyNode(srcKey)
-			valCopy := copyNode(srcVal)
-			dst.Content = append(dst.Content, keyCopy, valCopy)
-			changed = true
-		} else {
-			dstVal := dst.Content[dstIdx+1]
-			if dstVal.Kind == yaml.MappingNode && srcVal.Kind == yaml.MappingNode {
-				// Ambos são mappings - faz merge recursivo
-				if mergeNodes(dstVal, srcVal) {
-					changed = true
-				}
-			} else if dstVal.Kind == yaml.SequenceNode && srcVal.Kind == yaml.SequenceNode {
-				// Ambos são listas - verifica campos faltantes nos itens existentes
-				if mergeSequenceItems(dstVal, srcVal) {
-					changed = true
-				}
-			}
+	root := doc.root()
+	for v := version; v < CurrentConfigVersion; v++ {
+		migration := configMigrations[v]
+		changes, err := migration.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("erro na migração da versão %d para a %d: %w", v, v+1, err)
 		}
+		setConfigVersion(root, v+1)
+		result.Migrations = append(result.Migrations, AppliedMigration{From: v, To: v + 1, Description: migration.description, Changes: changes})
+		result.To = v + 1
+	}
+	if dryRun {
+		return result, nil
 	}
 
-	return changed
+	// Cópia de segurança antes de alterar o arquivo
+	backups := newBackupSet(filepath.Dir(configPath))
+	defer backups.finish()
+	if err := backups.add(configPath); err != nil {
+		return nil, err
+	}
+	if err := doc.write(configPath); err != nil {
+		return nil, fmt.Errorf("erro ao salvar config migrado: %w", err)
+	}
+	result.Backup = backups.dir
+	return result, nil
 }
 
-// mergeSequenceItems verifica itens de uma lista do usuário contra o "esquema" do template.
-// Para cada item (mapping) da lista do usuário, adiciona campos que existem no template mas faltam no item.
-// Campos adicionados recebem valores default vazios (ex: tags: []).
-func mergeSequenceItems(dst, src *yaml.Node) bool {
-	// Precisa ter pelo menos um item no template para extrair o esquema
-	if len(src.Content) == 0 {
-		return false
+// readMigrationDocument lê o config.yaml como yaml.Node (preserva comentários, ordem e marcadores)
+func readMigrationDocument(configPath string) (*yamlDocument, error) {
+	if _, err := os.Stat(configPath); err != nil {
+		return nil, fmt.Errorf("erro ao ler config para migração: %w", err)
 	}
-
-	// Usa o primeiro item do template como referência de esquema
-	templateItem := src.Content[0]
-	if templateItem.Kind != yaml.MappingNode {
-		return false
+	doc, err := readDocument(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler config para migração: %w", err)
+	}
+	// O documento YAML raiz tem Kind=DocumentNode e o conteúdo está em Content[0]
+	if doc.node.Kind != yaml.DocumentNode || len(doc.node.Content) == 0 || doc.node.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config do usuário tem formato inesperado")
 	}
+	return doc, nil
+}
 
-	changed := false
+// configVersion retorna o valor do campo version (0 se ausente: arquivos anteriores ao versionamento)
+func configVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, "version")
+	if node == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("version inválida no config.yaml: '%s'", node.Value)
+	}
+	return version, nil
+}
 
-	// Para cada item na lista do usuário
-	for _, dstItem := range dst.Content {
-		if dstItem.Kind != yaml.MappingNode {
-			continue
-		}
+// setConfigVersion grava o campo version (no início do arquivo, se ainda não existir)
+func setConfigVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if node := mappingValue(root, "version"); node != nil {
+		node.Value, node.Tag, node.Style = value, "!!int", 0
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value, LineComment: "# Versão do formato do arquivo (atualizada automaticamente pelo sc)"}
+	insertKey(root, 0, key, node)
+}
 
-		// Verifica cada campo do template
-		for i := 0; i < len(templateItem.Content)-1; i += 2 {
-			tmplKey := templateItem.Content[i]
-			tmplVal := templateItem.Content[i+1]
-
-			// Se o campo não existe no item do usuário, adiciona com valor default vazio
-			if findKeyIndex(dstItem, tmplKey.Value) == -1 {
-				keyCopy := copyNode(tmplKey)
-				valCopy := emptyValueNode(tmplVal)
-				dstItem.Content = append(dstItem.Content, keyCopy, valCopy)
-				changed = true
-			}
-		}
+// insertKey insere o par chave/valor na posição informada do mapeamento
+// No início do arquivo, o comentário de cabeçalho continua sendo a primeira linha
+func insertKey(mapping *yaml.Node, index int, key, value *yaml.Node) {
+	if index == 0 && len(mapping.Content) > 0 {
+		key.HeadComment, mapping.Content[0].HeadComment = mapping.Content[0].HeadComment, ""
 	}
+	mapping.Content = slices.Insert(mapping.Content, index, key, value)
+}
 
-	return changed
+// configDefaults são as opções adicionadas a arquivos antigos pela migração para a versão 1
+// Diferente do defaultConfigTemplate, não contém dados de exemplo (usuários, jump hosts, hosts, proxy)
+// connect_timeout é 0s (sem limite) para manter o comportamento das versões sem limite de conexão
+const configDefaults = `
+include: []                     # Arquivos adicionais (globs relativos a ~/.sshControl), além de conf.d/*.yaml
+config:
+  default_user: ""
+  auto_create: false            # Se true, salva hosts não cadastrados automaticamente com tag "autocreated"
+  dir_cp_default: ~/sshControl  # Diretório padrão para downloads via 'sc cp down'
+  proxy: ""                     # IP:PORT do proxy HTTP/HTTPS/FTP na máquina local
+  proxy_port: 0                 # Porta local no host remoto para acessar o proxy
+  connect_timeout: 0s           # Tempo máximo para conectar (TCP + handshake SSH), 0s = sem limite
+  command_timeout: 0s           # Tempo máximo de execução de comandos e transferências, 0s = sem limite
+  inventory_sources: []         # Fontes de inventário dinâmico: executáveis que retornam hosts em JSON
+  local_file: ""                # Arquivo onde novos hosts são gravados (ex: local.yaml), vazio = config.yaml
+  users: []
+  jump_hosts: []
+hosts: []
+`
+
+// migrateV0ToV1 adiciona as opções ausentes com os valores padrão (sem alterar valores existentes)
+func migrateV0ToV1(root *yaml.Node) ([]string, error) {
+	var defaults yaml.Node
+	if err := yaml.Unmarshal([]byte(configDefaults), &defaults); err != nil {
+		return nil, fmt.Errorf("erro ao parsear valores padrão: %w", err)
+	}
+	return addMissingKeys(root, defaults.Content[0], ""), nil
 }
 
-// emptyValueNode cria um nó yaml com valor default vazio baseado no tipo do nó de referência.
-// Sequências viram [], mappings viram {}, escalares viram "".
-func emptyValueNode(ref *yaml.Node) *yaml.Node {
-	switch ref.Kind {
-	case yaml.SequenceNode:
-		return &yaml.Node{
-			Kind:  yaml.SequenceNode,
-			Tag:   "!!seq",
-			Style: yaml.FlowStyle,
-		}
-	case yaml.MappingNode:
-		return &yaml.Node{
-			Kind:  yaml.MappingNode,
-			Tag:   "!!map",
-			Style: yaml.FlowStyle,
-		}
-	default:
-		return &yaml.Node{
-			Kind:  yaml.ScalarNode,
-			Tag:   "!!str",
-			Value: "",
+// addMissingKeys adiciona ao dst (usuário) as chaves que existem em src mas não em dst.
+// Nunca sobrescreve valores existentes do usuário. Retorna as chaves adicionadas (ex: + config.local_file).
+func addMissingKeys(dst, src *yaml.Node, prefix string) []string {
+	// Só faz merge de mappings
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var added []string
+	next := 0 // Chaves novas entram depois da chave anterior do src (mesma ordem do template)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		srcKey, srcVal := src.Content[i], src.Content[i+1]
+		path := prefix + srcKey.Value
+
+		dstIdx := findKeyIndex(dst, srcKey.Value)
+		if dstIdx == -1 {
+			// Chave não existe no config do usuário - adiciona
+			insertKey(dst, next, copyNode(srcKey), copyNode(srcVal))
+			added = append(added, "+ "+path)
+			next += 2
+			continue
 		}
+		next = dstIdx + 2
+		// Ambos são mappings - faz merge recursivo
+		added = append(added, addMissingKeys(dst.Content[dstIdx+1], srcVal, path+".")...)
 	}
+	return added
 }
 
 // findKeyIndex procura uma chave em um MappingNode e retorna o índice dela.
@@ -0,0 +1,50 @@
+package config
+
+// PasswordSource indica de onde vem a senha de uma conexão quando a autenticação por chave falha:
+// um comando externo (password_command) ou um segredo do cofre (password_ref)
+type PasswordSource struct {
+	Command string // Comando executado via sh -c; a primeira linha da saída é a senha
+	Ref     string // Nome do segredo no cofre (sc vault)
+}
+
+// IsZero indica que nenhuma origem de senha foi configurada
+func (p PasswordSource) IsZero() bool {
+	return p.Command == "" && p.Ref == ""
+}
+
+// passwordSource retorna a origem de senha do usuário (password_command tem precedência)
+func (u *User) passwordSource() PasswordSource {
+	if u == nil {
+		return PasswordSource{}
+	}
+	if u.PasswordCommand != "" {
+		return PasswordSource{Command: u.PasswordCommand}
+	}
+	return PasswordSource{Ref: u.PasswordRef}
+}
+
+// HostPasswordSource retorna a origem de senha de uma conexão: a do host, se definida,
+// ou a do usuário da conexão (host nil = conexão direta)
+func (c *ConfigFile) HostPasswordSource(host *Host, userName string) PasswordSource {
+	if host != nil {
+		if host.PasswordCommand != "" {
+			return PasswordSource{Command: host.PasswordCommand}
+		}
+		if host.PasswordRef != "" {
+			return PasswordSource{Ref: host.PasswordRef}
+		}
+	}
+	return c.FindUser(userName).passwordSource()
+}
+
+// JumpHostPasswordSource retorna a origem de senha do jump host: o password_command do jump host
+// ou, sem ele, a do seu usuário
+func (c *ConfigFile) JumpHostPasswordSource(jumpHost *JumpHost) PasswordSource {
+	if jumpHost == nil {
+		return PasswordSource{}
+	}
+	if jumpHost.PasswordCommand != "" {
+		return PasswordSource{Command: jumpHost.PasswordCommand}
+	}
+	return c.FindUser(jumpHost.User).passwordSource()
+}
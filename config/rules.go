@@ -0,0 +1,260 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Regras (seção rules), no estilo do Match do ssh_config:
+//
+//	rules:
+//	  - name: vpn
+//	    match: {cidr: 10.20.0.0/16}
+//	    user: ops
+//	    jump: bastion
+//	  - match: {host: "*.lab.example.com", tag: lab}
+//	    port: 2222
+//	    ssh_keys: [~/.ssh/lab_ed25519]
+//	    env: {LANG: C.UTF-8}
+//
+// As regras são avaliadas em ordem para hosts do config.yaml (e do inventário dinâmico) e para
+// conexões diretas ([user@]host[:port]). Todas as condições de match precisam corresponder (match
+// vazio corresponde a qualquer host) e cada condição aceita uma lista separada por vírgulas.
+// Como no ssh_config, cada opção vem da primeira regra que a define; ssh_keys se acumulam e env
+// é mesclado por variável. Flags e campos do próprio host têm precedência sobre as regras.
+
+// Rule é uma regra da seção rules
+type Rule struct {
+	Name           string            `yaml:"name,omitempty"`            // Nome exibido por sc explain (opcional)
+	Match          RuleMatch         `yaml:"match"`                     // Condições (todas precisam corresponder)
+	User           string            `yaml:"user,omitempty"`            // Usuário quando nem -u nem o host definem um
+	Jump           string            `yaml:"jump,omitempty"`            // Jump host (nome ou índice) quando nem -j nem o host definem um
+	Port           int               `yaml:"port,omitempty"`            // Porta de conexões diretas sem :porta (e de hosts sem port)
+	SSHKeys        []string          `yaml:"ssh_keys,omitempty"`        // Chaves tentadas antes das chaves do usuário
+	Proxy          bool              `yaml:"proxy,omitempty"`           // Habilita o proxy reverso (-p)
+	Env            map[string]string `yaml:"env,omitempty"`             // Variáveis de ambiente dos comandos remotos
+	ConnectTimeout time.Duration     `yaml:"connect_timeout,omitempty"` // Sobrepõe config.connect_timeout
+	CommandTimeout time.Duration     `yaml:"command_timeout,omitempty"` // Sobrepõe config.command_timeout
+}
+
+// RuleMatch são as condições de uma regra
+type RuleMatch struct {
+	CIDR string `yaml:"cidr,omitempty"` // Endereço IP do host dentro da rede (ex: 10.0.0.0/8,192.168.0.0/16); nomes DNS são resolvidos
+	Host string `yaml:"host,omitempty"` // Glob sobre o nome ou o endereço do host (ex: *.prod.example.com)
+	Tag  string `yaml:"tag,omitempty"`  // Tag do host, incluindo as tags filhas (conexões diretas não têm tags)
+}
+
+// RuleEvaluation é o resultado da avaliação de uma regra para um host (exibido por sc explain)
+type RuleEvaluation struct {
+	Rule    *Rule
+	Index   int      // Posição na seção rules (a partir de 1)
+	Matched bool     // Todas as condições corresponderam
+	Reason  string   // Condições que corresponderam, ou a primeira que não correspondeu
+	Applied []string // Opções definidas por esta regra
+	Ignored []string // Opções já definidas por uma regra anterior
+}
+
+// Label identifica a regra nas mensagens (#N ou #N nome)
+func (e RuleEvaluation) Label() string {
+	if e.Rule.Name != "" {
+		return fmt.Sprintf("#%d %s", e.Index, e.Rule.Name)
+	}
+	return fmt.Sprintf("#%d", e.Index)
+}
+
+// RuleResult são as opções obtidas das regras que corresponderam a um host
+type RuleResult struct {
+	User           string
+	Jump           string
+	Port           int
+	SSHKeys        []string
+	Proxy          bool
+	Env            map[string]string
+	ConnectTimeout time.Duration
+	CommandTimeout time.Duration
+
+	Evaluations []RuleEvaluation  // Todas as regras, na ordem de avaliação
+	Sources     map[string]string // Opção -> regra que a definiu (Label)
+}
+
+// Source retorna a regra que definiu a opção (vazio se nenhuma)
+func (r *RuleResult) Source(option string) string {
+	if r == nil {
+		return ""
+	}
+	return r.Sources[option]
+}
+
+// EvaluateRules avalia as regras para um host do config.yaml ou, com host nil, para o endereço
+// de uma conexão direta (ctx limita a resolução DNS das condições cidr)
+func (c *ConfigFile) EvaluateRules(ctx context.Context, host *Host, address string) *RuleResult {
+	target := ruleTarget{name: address, address: address}
+	if host != nil {
+		target = ruleTarget{name: host.Name, address: host.Host, host: host}
+	}
+	// Nomes DNS são resolvidos uma única vez, e só se alguma regra usa cidr
+	if slices.ContainsFunc(c.Rules, func(rule Rule) bool { return rule.Match.CIDR != "" }) {
+		target.ips, target.resolveErr = resolveRuleAddress(ctx, target.address)
+	}
+
+	result := &RuleResult{Sources: make(map[string]string)}
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		eval := RuleEvaluation{Rule: rule, Index: i + 1}
+		eval.Matched, eval.Reason = rule.Match.matches(target)
+		if eval.Matched {
+			result.apply(&eval)
+		}
+		result.Evaluations = append(result.Evaluations, eval)
+	}
+	return result
+}
+
+// ruleTarget é o destino avaliado pelas condições das regras
+type ruleTarget struct {
+	name    string
+	address string
+	host    *Host // nil para conexões diretas
+
+	ips        []net.IP // IPs do endereço (resolvidos quando ele é um nome DNS)
+	resolveErr error
+}
+
+// ruleResolveTimeout limita a resolução DNS do endereço para as condições cidr
+const ruleResolveTimeout = 2 * time.Second
+
+// resolveRuleAddress retorna o IP de um endereço literal ou resolve um nome DNS
+func resolveRuleAddress(ctx context.Context, address string) ([]net.IP, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, ruleResolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// resolvedText descreve os IPs resolvidos de um nome DNS (vazio para IPs literais)
+func (t ruleTarget) resolvedText() string {
+	if net.ParseIP(t.address) != nil {
+		return ""
+	}
+	if t.resolveErr != nil {
+		return fmt.Sprintf(" (não foi possível resolver %s)", t.address)
+	}
+	texts := make([]string, len(t.ips))
+	for i, ip := range t.ips {
+		texts[i] = ip.String()
+	}
+	return fmt.Sprintf(" (%s resolvido para %s)", t.address, strings.Join(texts, ", "))
+}
+
+// matches verifica as condições e descreve o motivo (para sc explain)
+func (m RuleMatch) matches(t ruleTarget) (bool, string) {
+	var reasons []string
+	if m.CIDR != "" {
+		matched := slices.ContainsFunc(splitList(m.CIDR), func(cidr string) bool {
+			_, network, err := net.ParseCIDR(cidr)
+			return err == nil && slices.ContainsFunc(t.ips, network.Contains)
+		})
+		if !matched {
+			return false, fmt.Sprintf("cidr %s não contém %s%s", m.CIDR, t.address, t.resolvedText())
+		}
+		reasons = append(reasons, "cidr "+m.CIDR+t.resolvedText())
+	}
+	if m.Host != "" {
+		matched := slices.ContainsFunc(splitList(m.Host), func(pattern string) bool {
+			return matchName(pattern, t.name) || matchName(pattern, t.address)
+		})
+		if !matched {
+			return false, fmt.Sprintf("host %s não corresponde a %s", m.Host, t.name)
+		}
+		reasons = append(reasons, "host "+m.Host)
+	}
+	if m.Tag != "" {
+		matched := t.host != nil && slices.ContainsFunc(splitList(m.Tag), t.host.HasTag)
+		if !matched {
+			return false, fmt.Sprintf("tag %s ausente", m.Tag)
+		}
+		reasons = append(reasons, "tag "+m.Tag)
+	}
+	if len(reasons) == 0 {
+		return true, "sem condições (todos os hosts)"
+	}
+	return true, strings.Join(reasons, ", ")
+}
+
+// apply aplica as opções da regra que ainda não foram definidas por uma regra anterior
+func (r *RuleResult) apply(eval *RuleEvaluation) {
+	rule := eval.Rule
+	set := func(option string, defined, isSet bool, assign func()) {
+		if !defined {
+			return
+		}
+		if isSet {
+			eval.Ignored = append(eval.Ignored, option)
+			return
+		}
+		assign()
+		r.Sources[option] = eval.Label()
+		eval.Applied = append(eval.Applied, option)
+	}
+
+	set("user", rule.User != "", r.User != "", func() { r.User = rule.User })
+	set("jump", rule.Jump != "", r.Jump != "", func() { r.Jump = rule.Jump })
+	set("port", rule.Port != 0, r.Port != 0, func() { r.Port = rule.Port })
+	set("proxy", rule.Proxy, r.Proxy, func() { r.Proxy = true })
+	set("connect_timeout", rule.ConnectTimeout != 0, r.ConnectTimeout != 0, func() { r.ConnectTimeout = rule.ConnectTimeout })
+	set("command_timeout", rule.CommandTimeout != 0, r.CommandTimeout != 0, func() { r.CommandTimeout = rule.CommandTimeout })
+
+	// Chaves se acumulam (como IdentityFile no ssh_config)
+	for _, key := range rule.SSHKeys {
+		if !slices.Contains(r.SSHKeys, key) {
+			r.SSHKeys = append(r.SSHKeys, key)
+		}
+	}
+	if len(rule.SSHKeys) > 0 {
+		eval.Applied = append(eval.Applied, "ssh_keys")
+	}
+
+	// Variáveis de ambiente são mescladas: vale a primeira regra que define cada uma
+	for _, name := range slices.Sorted(maps.Keys(rule.Env)) {
+		option := "env." + name
+		set(option, true, r.Env[name] != "" || r.Sources[option] != "", func() {
+			if r.Env == nil {
+				r.Env = make(map[string]string)
+			}
+			r.Env[name] = rule.Env[name]
+		})
+	}
+}
+
+// ruleGlobValid verifica os globs de match.host (usado por sc config validate)
+func ruleGlobValid(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// splitList divide uma lista separada por vírgulas, ignorando itens vazios
+func splitList(text string) []string {
+	var items []string
+	for item := range strings.SplitSeq(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
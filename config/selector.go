@@ -0,0 +1,402 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Seletores de hosts (usados por -l, cp up -l, -s, runbooks e pelo filtro da TUI):
+//
+//	@web          hosts com a tag (inclui as tags filhas: @env seleciona env/prod)
+//	web-*         glob sobre o nome do host (*, ? e [abc])
+//	node[01-20]   intervalo numérico: gera node01 ... node20 (aceita [1-3,7])
+//	~^db[0-9]+$   expressão regular sobre o nome do host
+//	@web&@prod    interseção
+//	@web,@db      união (equivalente a informar os seletores em argumentos separados)
+//	!@staging     exclusão dos hosts já selecionados (no início, parte de todos os hosts)
+//
+// Os termos são avaliados da esquerda para a direita. Nomes literais (e os gerados por
+// intervalos) que não existem no config.yaml são mantidos como conexões diretas.
+
+// maxRangeHosts limita a quantidade de nomes gerados por um intervalo
+const maxRangeHosts = 10000
+
+// Selector é um seletor de hosts compilado
+type Selector struct {
+	terms []selectorTerm
+}
+
+// selectorTerm é um termo separado por vírgula: interseção de átomos, opcionalmente negada
+type selectorTerm struct {
+	text    string
+	exclude bool
+	atoms   []selectorAtom
+}
+
+// atomKind identifica o tipo de um átomo do seletor
+type atomKind int
+
+const (
+	atomName atomKind = iota
+	atomTag
+	atomGlob
+	atomRegex
+	atomRange
+)
+
+// selectorAtom é a menor unidade do seletor (@tag, glob, intervalo, ~regex ou nome)
+type selectorAtom struct {
+	kind  atomKind
+	value string
+	re    *regexp.Regexp
+	names []string // Nomes gerados por um intervalo (podem conter glob)
+}
+
+// rangePattern encontra intervalos numéricos entre colchetes (ex: [01-20] ou [1-3,7])
+var rangePattern = regexp.MustCompile(`\[(\d+(?:-\d+)?(?:,\d+(?:-\d+)?)*)\]`)
+
+// ParseSelector compila um ou mais seletores (cada argumento pode conter vários termos separados por vírgula)
+func ParseSelector(args ...string) (*Selector, error) {
+	s := &Selector{}
+	for _, arg := range args {
+		for _, text := range splitTopLevel(arg, ',') {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			term := selectorTerm{text: text}
+			if strings.HasPrefix(text, "!") {
+				term.exclude = true
+				text = strings.TrimPrefix(text, "!")
+			}
+			for _, atomText := range splitTopLevel(text, '&') {
+				atom, err := parseAtom(strings.TrimSpace(atomText))
+				if err != nil {
+					return nil, fmt.Errorf("seletor '%s': %w", term.text, err)
+				}
+				term.atoms = append(term.atoms, atom)
+			}
+			s.terms = append(s.terms, term)
+		}
+	}
+	return s, nil
+}
+
+// parseAtom interpreta um átomo do seletor
+func parseAtom(text string) (selectorAtom, error) {
+	switch {
+	case text == "":
+		return selectorAtom{}, fmt.Errorf("termo vazio")
+	case strings.HasPrefix(text, "@"):
+		tag := strings.TrimPrefix(text, "@")
+		if tag == "" {
+			return selectorAtom{}, fmt.Errorf("tag vazia")
+		}
+		return selectorAtom{kind: atomTag, value: tag}, nil
+	case strings.HasPrefix(text, "~"):
+		re, err := regexp.Compile(strings.TrimPrefix(text, "~"))
+		if err != nil {
+			return selectorAtom{}, fmt.Errorf("expressão regular inválida: %w", err)
+		}
+		return selectorAtom{kind: atomRegex, value: text, re: re}, nil
+	case rangePattern.MatchString(text):
+		names, err := expandRange(text)
+		if err != nil {
+			return selectorAtom{}, err
+		}
+		for _, name := range names {
+			if isGlob(name) {
+				if _, err := path.Match(name, ""); err != nil {
+					return selectorAtom{}, fmt.Errorf("glob inválido '%s'", name)
+				}
+			}
+		}
+		return selectorAtom{kind: atomRange, value: text, names: names}, nil
+	case isGlob(text):
+		if _, err := path.Match(text, ""); err != nil {
+			return selectorAtom{}, fmt.Errorf("glob inválido '%s'", text)
+		}
+		return selectorAtom{kind: atomGlob, value: text}, nil
+	}
+	return selectorAtom{kind: atomName, value: text}, nil
+}
+
+// expandRange gera os nomes de um texto com intervalos numéricos (node[01-03] -> node01 node02 node03)
+func expandRange(text string) ([]string, error) {
+	loc := rangePattern.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return []string{text}, nil
+	}
+	prefix, body, rest := text[:loc[0]], text[loc[2]:loc[3]], text[loc[1]:]
+
+	suffixes, err := expandRange(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, part := range strings.Split(body, ",") {
+		startText, endText, isRange := strings.Cut(part, "-")
+		if !isRange {
+			endText = startText
+		}
+		start, err := strconv.Atoi(startText)
+		if err != nil {
+			return nil, fmt.Errorf("intervalo inválido '[%s]'", body)
+		}
+		end, err := strconv.Atoi(endText)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("intervalo inválido '[%s]'", body)
+		}
+		if len(names)+(end-start+1)*len(suffixes) > maxRangeHosts {
+			return nil, fmt.Errorf("intervalo '[%s]' gera mais de %d hosts", body, maxRangeHosts)
+		}
+
+		// Zeros à esquerda definem a largura (01-20 -> 01, 02, ..., 20)
+		width := 0
+		if len(startText) > 1 && startText[0] == '0' {
+			width = len(startText)
+		}
+		for n := start; n <= end; n++ {
+			for _, suffix := range suffixes {
+				names = append(names, fmt.Sprintf("%s%0*d%s", prefix, width, n, suffix))
+			}
+		}
+	}
+	return names, nil
+}
+
+// splitTopLevel divide o texto pelo separador, ignorando os que estão entre colchetes
+func splitTopLevel(text string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// isGlob indica se o texto contém caracteres de glob
+// Um IPv6 entre colchetes ([2001:db8::1]) é um endereço literal, não uma classe de caracteres
+func isGlob(text string) bool {
+	return strings.ContainsAny(withoutIPv6Literal(text), "*?[")
+}
+
+// withoutIPv6Literal remove do texto um endereço IPv6 entre colchetes, se houver
+func withoutIPv6Literal(text string) string {
+	start := strings.Index(text, "[")
+	end := strings.Index(text, "]")
+	if start < 0 || end < start {
+		return text
+	}
+	inner := text[start+1 : end]
+	if !strings.Contains(inner, ":") || net.ParseIP(inner) == nil {
+		return text
+	}
+	return text[:start] + text[end+1:]
+}
+
+// IsSelectorExpression indica se o texto usa a sintaxe de seletores (e não é apenas um nome)
+// Usado pelo filtro da TUI para decidir entre seletor e busca por substring
+func IsSelectorExpression(text string) bool {
+	return strings.HasPrefix(text, "@") || strings.HasPrefix(text, "~") || strings.HasPrefix(text, "!") ||
+		strings.ContainsAny(withoutIPv6Literal(text), "*?[&,")
+}
+
+// matchName compara um nome de host com um nome ou glob
+func matchName(pattern, name string) bool {
+	if isGlob(pattern) {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+	return pattern == name
+}
+
+// matches verifica se o host do config.yaml corresponde ao átomo
+func (a selectorAtom) matches(h *Host) bool {
+	switch a.kind {
+	case atomTag:
+		return h.HasTag(a.value)
+	case atomRegex:
+		return a.re.MatchString(h.Name)
+	case atomGlob:
+		return matchName(a.value, h.Name)
+	case atomRange:
+		for _, name := range a.names {
+			if matchName(name, h.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return h.Name == a.value
+}
+
+// matches verifica se o host do config.yaml corresponde a todos os átomos do termo
+func (t selectorTerm) matches(h *Host) bool {
+	for _, atom := range t.atoms {
+		if !atom.matches(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// literals retorna os nomes explícitos do termo (nome ou intervalo sem glob), na ordem informada
+// Termos com tags, globs, regex ou interseções retornam nil e são avaliados sobre o config.yaml
+func (t selectorTerm) literals() []string {
+	if len(t.atoms) != 1 {
+		return nil
+	}
+	atom := t.atoms[0]
+	switch atom.kind {
+	case atomName:
+		return []string{atom.value}
+	case atomRange:
+		var names []string
+		for _, name := range atom.names {
+			if isGlob(name) {
+				return nil
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+	return nil
+}
+
+// Select expande o seletor para nomes de hosts, na ordem de seleção e sem repetições
+// Tags, globs e regex consideram apenas os hosts do filtro do contexto ativo; nomes literais não são filtrados
+// Retorna também avisos para os termos que não selecionaram nenhum host
+func (s *Selector) Select(c *ConfigFile) ([]string, []string) {
+	var selected []string
+	var warnings []string
+	index := make(map[string]bool)
+
+	add := func(name string) {
+		if !index[name] {
+			index[name] = true
+			selected = append(selected, name)
+		}
+	}
+	remove := func(name string) {
+		if index[name] {
+			delete(index, name)
+			selected = removeName(selected, name)
+		}
+	}
+
+	// Uma exclusão no início parte de todos os hosts do config.yaml
+	if len(s.terms) > 0 && s.terms[0].exclude {
+		for i := range c.Hosts {
+			if c.InContext(&c.Hosts[i]) {
+				add(c.Hosts[i].Name)
+			}
+		}
+	}
+
+	for _, term := range s.terms {
+		var names []string
+		if literals := term.literals(); literals != nil {
+			names = literals
+		} else {
+			for i := range c.Hosts {
+				if term.matches(&c.Hosts[i]) && c.InContext(&c.Hosts[i]) {
+					names = append(names, c.Hosts[i].Name)
+				}
+			}
+		}
+
+		if len(names) == 0 && !term.exclude {
+			scope := ""
+			if c.contextFilter != nil {
+				scope = fmt.Sprintf(" no contexto '%s' (filtro %s)", c.context.Name, c.context.Filter)
+			}
+			if len(term.atoms) == 1 && term.atoms[0].kind == atomTag {
+				warnings = append(warnings, fmt.Sprintf("Nenhum host encontrado com a tag '%s'%s", term.atoms[0].value, scope))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("Nenhum host corresponde a '%s'%s", term.text, scope))
+			}
+		}
+		for _, name := range names {
+			if term.exclude {
+				remove(name)
+			} else {
+				add(name)
+			}
+		}
+	}
+	return selected, warnings
+}
+
+// Matches verifica se um host do config.yaml é selecionado (filtro da TUI e sc -s)
+func (s *Selector) Matches(h *Host) bool {
+	selected := len(s.terms) > 0 && s.terms[0].exclude
+	for _, term := range s.terms {
+		if term.matches(h) {
+			selected = !term.exclude
+		}
+	}
+	return selected
+}
+
+// Tags retorna as tags citadas no seletor (sem exclusões), para exibição
+func (s *Selector) Tags() []string {
+	var tags []string
+	for _, term := range s.terms {
+		if term.exclude {
+			continue
+		}
+		for _, atom := range term.atoms {
+			if atom.kind == atomTag {
+				tags = append(tags, atom.value)
+			}
+		}
+	}
+	return tags
+}
+
+// removeName remove um nome da lista preservando a ordem
+func removeName(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+	return names
+}
+
+// ReadHostList lê seletores de hosts, um por linha (linhas vazias e comentários com # são ignorados)
+func ReadHostList(r io.Reader) ([]string, error) {
+	var hosts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hosts = append(hosts, strings.Fields(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
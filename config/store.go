@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Gravação segura dos arquivos de configuração:
+//   - trava exclusiva (flock) em ~/.sshControl/.lock durante a leitura-alteração-gravação,
+//     para que execuções simultâneas (ex: dois sc -l com auto_create) não se sobreponham
+//   - escrita em arquivo temporário seguido de rename (uma falha no meio não trunca o arquivo);
+//     links simbólicos são resolvidos e o arquivo de destino é gravado
+//   - cópia de segurança em ~/.sshControl/backups/<data-hora>/ antes de cada gravação
+//     (o cofre usa ~/.sshControl/vault-backups/, fora do alcance de 'sc config restore')
+//   - permissão 0600
+
+const (
+	// lockFileName é o arquivo usado como trava (flock) no diretório de configuração
+	lockFileName = ".lock"
+
+	// BackupDirName é o diretório (dentro de ~/.sshControl) com as cópias de segurança
+	BackupDirName = "backups"
+
+	// VaultBackupDirName é o diretório (dentro de ~/.sshControl) com as cópias de segurança do cofre,
+	// separadas das do config.yaml para que 'sc config restore' não restaure o cofre
+	VaultBackupDirName = "vault-backups"
+
+	// maxBackups é o número de cópias de segurança mantidas (as mais antigas são removidas)
+	maxBackups = 20
+
+	// backupTimeFormat é o formato do nome de cada cópia de segurança
+	backupTimeFormat = "20060102-150405.000"
+
+	// externalBackupDir guarda, dentro de uma cópia, arquivos de fora de ~/.sshControl (caminho absoluto)
+	externalBackupDir = "_abs"
+
+	// configFileMode é a permissão dos arquivos de configuração
+	configFileMode = 0600
+)
+
+// Backup é uma cópia de segurança dos arquivos de configuração, criada antes de uma gravação
+type Backup struct {
+	Name  string    // Nome do diretório (data e hora)
+	Time  time.Time // Momento da cópia
+	Files []string  // Caminhos originais dos arquivos copiados
+}
+
+// lockConfigDir obtém a trava exclusiva do diretório de configuração (aguarda se outro sc estiver gravando)
+func lockConfigDir(dir string) (func(), error) {
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, configFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar trava da configuração: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("erro ao travar a configuração: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+// writeFileAtomic grava o arquivo em um temporário no mesmo diretório e o renomeia sobre o original
+// Se o arquivo for um link simbólico, o destino do link é gravado (o link é preservado)
+func writeFileAtomic(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("erro ao resolver %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	}
+	if err := tmp.Chmod(configFileMode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao ajustar permissões: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	}
+	return nil
+}
+
+// backupSet agrupa as cópias de uma mesma gravação (um diretório por gravação, criado sob demanda)
+type backupSet struct {
+	configDir string
+	root      string // BackupDirName ou VaultBackupDirName
+	dir       string
+}
+
+// newBackupSet prepara uma cópia de segurança para o diretório de configuração
+func newBackupSet(configDir string) *backupSet {
+	return &backupSet{configDir: configDir, root: BackupDirName}
+}
+
+// newVaultBackupSet prepara uma cópia de segurança do cofre (em VaultBackupDirName)
+func newVaultBackupSet(configDir string) *backupSet {
+	return &backupSet{configDir: configDir, root: VaultBackupDirName}
+}
+
+// add copia o arquivo (se existir) para a cópia de segurança
+func (b *backupSet) add(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler %s para backup: %w", path, err)
+	}
+
+	if b.dir == "" {
+		root := filepath.Join(b.configDir, b.root)
+		now := time.Now()
+		b.dir = filepath.Join(root, now.Format(backupTimeFormat))
+		// Duas gravações no mesmo milissegundo: avança o horário até encontrar um nome livre
+		for fileExists(b.dir) {
+			now = now.Add(time.Millisecond)
+			b.dir = filepath.Join(root, now.Format(backupTimeFormat))
+		}
+	}
+
+	target := filepath.Join(b.dir, backupRelPath(b.configDir, path))
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("erro ao criar diretório de backup: %w", err)
+	}
+	if err := os.WriteFile(target, data, configFileMode); err != nil {
+		return fmt.Errorf("erro ao gravar backup de %s: %w", path, err)
+	}
+	return nil
+}
+
+// finish remove as cópias de segurança mais antigas, mantendo as maxBackups mais recentes
+func (b *backupSet) finish() {
+	if b.dir == "" {
+		return
+	}
+	backups, err := listBackups(b.configDir, b.root)
+	if err != nil {
+		return
+	}
+	for _, backup := range backups[min(len(backups), maxBackups):] {
+		os.RemoveAll(filepath.Join(b.configDir, b.root, backup.Name))
+	}
+}
+
+// backupRelPath retorna o caminho do arquivo dentro de uma cópia de segurança
+func backupRelPath(configDir, path string) string {
+	rel, err := filepath.Rel(configDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		abs, _ := filepath.Abs(path)
+		return filepath.Join(externalBackupDir, abs)
+	}
+	return rel
+}
+
+// originalPath é o inverso de backupRelPath
+func originalPath(configDir, rel string) string {
+	if after, ok := strings.CutPrefix(rel, externalBackupDir+string(filepath.Separator)); ok {
+		return string(filepath.Separator) + after
+	}
+	return filepath.Join(configDir, rel)
+}
+
+// ListBackups lista as cópias de segurança do diretório de configuração, da mais recente para a mais antiga
+// As cópias do cofre (VaultBackupDirName) não são incluídas
+func ListBackups(configDir string) ([]Backup, error) {
+	return listBackups(configDir, BackupDirName)
+}
+
+// listBackups lista as cópias de segurança em configDir/dirName, da mais recente para a mais antiga
+func listBackups(configDir, dirName string) ([]Backup, error) {
+	root := filepath.Join(configDir, dirName)
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		at, err := time.ParseInLocation(backupTimeFormat, entry.Name(), time.Local)
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+		backup := Backup{Name: entry.Name(), Time: at}
+		dir := filepath.Join(root, entry.Name())
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(dir, path)
+				backup.Files = append(backup.Files, originalPath(configDir, rel))
+			}
+			return nil
+		})
+		backups = append(backups, backup)
+	}
+	slices.SortFunc(backups, func(a, b Backup) int { return b.Time.Compare(a.Time) })
+	return backups, nil
+}
+
+// RestoreBackup restaura os arquivos de uma cópia de segurança (vazio = a mais recente)
+// O estado atual é copiado antes, então a restauração também pode ser desfeita
+func RestoreBackup(configPath, name string) (*Backup, error) {
+	configDir := filepath.Dir(configPath)
+	unlock, err := lockConfigDir(configDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	backups, err := ListBackups(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("nenhuma cópia de segurança em %s", filepath.Join(configDir, BackupDirName))
+	}
+	backup := &backups[0]
+	if name != "" {
+		index := slices.IndexFunc(backups, func(b Backup) bool { return b.Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("cópia de segurança '%s' não encontrada (use 'sc config restore --list')", name)
+		}
+		backup = &backups[index]
+	}
+
+	current := newBackupSet(configDir)
+	for _, file := range backup.Files {
+		if err := current.add(file); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range backup.Files {
+		data, err := os.ReadFile(filepath.Join(configDir, BackupDirName, backup.Name, backupRelPath(configDir, file)))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler backup de %s: %w", file, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return nil, fmt.Errorf("erro ao criar diretório de %s: %w", file, err)
+		}
+		if err := writeFileAtomic(file, data); err != nil {
+			return nil, err
+		}
+	}
+	current.finish()
+	return backup, nil
+}
@@ -0,0 +1,469 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationIssue é um problema encontrado na configuração, com a posição no arquivo
+type ValidationIssue struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Unknown bool // Chave desconhecida (erro apenas no modo estrito)
+}
+
+// String formata o problema como arquivo:linha:coluna: mensagem
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+}
+
+// ValidationResult agrupa os problemas encontrados por ValidateConfig
+type ValidationResult struct {
+	Files    []string // Arquivos verificados (config.yaml e arquivos incluídos)
+	Errors   []ValidationIssue
+	Warnings []ValidationIssue
+}
+
+// Valid indica se a configuração não tem erros
+func (r *ValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// configValidator percorre os arquivos de configuração como yaml.Node (para obter linha e coluna)
+type configValidator struct {
+	cfg    *ConfigFile // Configuração mesclada, usada para resolver referências entre arquivos
+	strict bool
+	file   string
+	result *ValidationResult
+}
+
+// ValidateConfig carrega o config.yaml (com os arquivos incluídos) e verifica:
+// chaves desconhecidas, nomes repetidos, portas inválidas, referências a usuários e jump hosts
+// inexistentes e fontes de inventário sem comando. No modo estrito, chaves desconhecidas são erros.
+// Erros de sintaxe YAML são retornados como erro (a mensagem já contém a linha).
+func ValidateConfig(filename string, strict bool) (*ValidationResult, error) {
+	cfg, err := LoadStaticConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.validate(strict)
+}
+
+// validate verifica cada arquivo carregado por LoadStaticConfig
+func (c *ConfigFile) validate(strict bool) (*ValidationResult, error) {
+	result := &ValidationResult{}
+	for i, layer := range c.layers {
+		data, err := os.ReadFile(layer.path)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler %s: %w", layer.path, err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("erro ao parsear %s: %w", layer.path, err)
+		}
+		result.Files = append(result.Files, layer.path)
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		v := &configValidator{cfg: c, strict: strict, file: layer.path, result: result}
+		root := resolveAlias(doc.Content[0])
+		v.checkKeys(root, reflect.TypeOf(ConfigFile{}))
+		if include := mappingValue(root, "include"); include != nil && i > 0 {
+			v.warn(include, "include é considerado apenas no config.yaml principal")
+		}
+		if version := mappingValue(root, "version"); version != nil {
+			if i > 0 {
+				v.warn(version, "version é considerado apenas no config.yaml principal")
+			} else if n, err := strconv.Atoi(version.Value); err != nil || n < 0 || n > CurrentConfigVersion {
+				v.fail(version, "version inválida: %s (use 0-%d)", version.Value, CurrentConfigVersion)
+			}
+		}
+		if contexts := mappingValue(root, "contexts"); contexts != nil {
+			if i > 0 {
+				v.warn(contexts, "contexts é considerado apenas no config.yaml principal")
+			} else {
+				v.checkContexts(contexts)
+			}
+		}
+		v.checkFile(root)
+		v.checkRules(mappingValue(root, "rules"))
+	}
+
+	// Ordena por arquivo (ordem de carga), linha e coluna
+	order := func(a, b ValidationIssue) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(result.Files, a.File), slices.Index(result.Files, b.File)),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Column, b.Column),
+		)
+	}
+	slices.SortStableFunc(result.Errors, order)
+	slices.SortStableFunc(result.Warnings, order)
+	return result, nil
+}
+
+// checkFile verifica as seções config e hosts de um arquivo
+func (v *configValidator) checkFile(root *yaml.Node) {
+	if section := mappingValue(root, "config"); section != nil {
+		if node := mappingValue(section, "default_user"); node != nil && node.Value != "" && v.cfg.FindUser(node.Value) == nil {
+			v.fail(node, "default_user '%s' não está definido em users", node.Value)
+		}
+		if node := mappingValue(section, "proxy"); node != nil && node.Value != "" {
+			if _, port, err := net.SplitHostPort(node.Value); err != nil || !validPort(port) {
+				v.fail(node, "proxy '%s' inválido (use IP:PORTA)", node.Value)
+			}
+		}
+		if node := mappingValue(section, "proxy_port"); node != nil && node.Value != "0" && !validPort(node.Value) {
+			v.fail(node, "proxy_port inválida: %s (use 1-65535)", node.Value)
+		}
+		if node := mappingValue(section, "address_strategy"); node != nil && node.Value != "" &&
+			node.Value != AddressStrategyOrder && node.Value != AddressStrategyRace {
+			v.fail(node, "address_strategy inválida: %s (use %s ou %s)", node.Value, AddressStrategyOrder, AddressStrategyRace)
+		}
+		if node := mappingValue(section, "address_timeout"); node != nil && strings.HasPrefix(node.Value, "-") {
+			v.fail(node, "address_timeout não pode ser negativo")
+		}
+
+		names := make(map[string]int)
+		for _, item := range sequenceItems(mappingValue(section, "users")) {
+			name := v.checkName(item, "usuário", names)
+			v.checkPasswordSource(item, "usuário", name)
+		}
+
+		names = make(map[string]int)
+		for _, item := range sequenceItems(mappingValue(section, "jump_hosts")) {
+			name := v.checkName(item, "jump host", names)
+			if node := mappingValue(item, "host"); node == nil || node.Value == "" {
+				v.fail(item, "jump host '%s' sem host", name)
+			}
+			v.checkPort(item, "jump host", name)
+			if node := mappingValue(item, "user"); node != nil && node.Value != "" && v.cfg.FindUser(node.Value) == nil {
+				v.fail(node, "usuário '%s' do jump host '%s' não está definido em users", node.Value, name)
+			}
+		}
+
+		names = make(map[string]int)
+		for _, item := range sequenceItems(mappingValue(section, "inventory_sources")) {
+			command := mappingValue(item, "command")
+			if command == nil || command.Value == "" {
+				v.fail(item, "fonte de inventário sem command")
+				continue
+			}
+			source := InventorySource{Command: command.Value}
+			if node := mappingValue(item, "name"); node != nil {
+				source.Name = node.Value
+			}
+			if line, ok := names[source.SourceName()]; ok {
+				v.fail(item, "fonte de inventário '%s' repetida (definida na linha %d)", source.SourceName(), line)
+			} else {
+				names[source.SourceName()] = item.Line
+			}
+		}
+	}
+
+	names := make(map[string]int)
+	for _, item := range sequenceItems(mappingValue(root, "hosts")) {
+		name := v.checkName(item, "host", names)
+		if node := mappingValue(item, "host"); node == nil || node.Value == "" {
+			v.fail(item, "host '%s' sem host (endereço)", name)
+		}
+		v.checkPort(item, "host", name)
+		v.checkPasswordSource(item, "host", name)
+		if node := mappingValue(item, "jump"); node != nil && node.Value != "" && v.cfg.ResolveJumpHost(node.Value) == nil {
+			v.fail(node, "jump host '%s' do host '%s' não encontrado", node.Value, name)
+		}
+		for _, address := range sequenceItems(mappingValue(item, "addresses")) {
+			host := mappingValue(address, "host")
+			if host == nil || host.Value == "" {
+				v.fail(address, "endereço alternativo do host '%s' sem host", name)
+				continue
+			}
+			if node := mappingValue(address, "port"); node != nil && !validPort(node.Value) {
+				v.fail(node, "porta inválida no endereço %s do host '%s': %s (use 1-65535)", host.Value, name, node.Value)
+			}
+			if node := mappingValue(address, "jump"); node != nil && node.Value != "" && v.cfg.ResolveJumpHost(node.Value) == nil {
+				v.fail(node, "jump host '%s' do endereço %s do host '%s' não encontrado", node.Value, host.Value, name)
+			}
+		}
+	}
+}
+
+// checkContexts verifica a seção contexts do config.yaml principal
+// Usuário e jump host de contextos com outro arquivo (config) são resolvidos nesse arquivo e não são verificados
+func (v *configValidator) checkContexts(contexts *yaml.Node) {
+	names := make(map[string]int)
+	for _, item := range sequenceItems(contexts) {
+		name := v.checkName(item, "contexto", names)
+		if node := mappingValue(item, "filter"); node != nil && node.Value != "" {
+			if _, err := ParseSelector(strings.Fields(node.Value)...); err != nil {
+				v.fail(node, "filter do contexto '%s' inválido: %v", name, err)
+			}
+		}
+		if node := mappingValue(item, "config"); node != nil && node.Value != "" {
+			if path := (&Context{Config: node.Value}).ConfigPath(); !fileExists(path) {
+				v.fail(node, "config do contexto '%s' não encontrado: %s", name, path)
+			}
+			continue
+		}
+		if node := mappingValue(item, "user"); node != nil && node.Value != "" && v.cfg.FindUser(node.Value) == nil {
+			v.fail(node, "usuário '%s' do contexto '%s' não está definido em users", node.Value, name)
+		}
+		if node := mappingValue(item, "jump"); node != nil && node.Value != "" && v.cfg.ResolveJumpHost(node.Value) == nil {
+			v.fail(node, "jump host '%s' do contexto '%s' não encontrado", node.Value, name)
+		}
+	}
+}
+
+// envNamePattern são os nomes aceitos em env das regras (exportados pelo shell remoto)
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ruleOptions são as chaves de uma regra que definem opções de conexão
+var ruleOptions = []string{"user", "jump", "port", "ssh_keys", "proxy", "env", "connect_timeout", "command_timeout"}
+
+// checkRules verifica a seção rules de um arquivo (as regras de todos os arquivos são avaliadas em sequência)
+func (v *configValidator) checkRules(rules *yaml.Node) {
+	for i, item := range sequenceItems(rules) {
+		label := fmt.Sprintf("#%d", i+1)
+		if node := mappingValue(item, "name"); node != nil && node.Value != "" {
+			label = node.Value
+		}
+
+		if match := mappingValue(item, "match"); match != nil {
+			if node := mappingValue(match, "cidr"); node != nil {
+				for _, cidr := range splitList(node.Value) {
+					if _, _, err := net.ParseCIDR(cidr); err != nil {
+						v.fail(node, "cidr '%s' inválido na regra '%s' (use ex: 10.0.0.0/8)", cidr, label)
+					}
+				}
+			}
+			if node := mappingValue(match, "host"); node != nil {
+				for _, pattern := range splitList(node.Value) {
+					if !ruleGlobValid(pattern) {
+						v.fail(node, "glob '%s' inválido na regra '%s'", pattern, label)
+					}
+				}
+			}
+		}
+
+		if node := mappingValue(item, "jump"); node != nil && node.Value != "" && v.cfg.ResolveJumpHost(node.Value) == nil {
+			v.fail(node, "jump host '%s' da regra '%s' não encontrado", node.Value, label)
+		}
+		if node := mappingValue(item, "port"); node != nil && !validPort(node.Value) {
+			v.fail(node, "porta inválida na regra '%s': %s (use 1-65535)", label, node.Value)
+		}
+		if node := mappingValue(item, "env"); node != nil && node.Kind == yaml.MappingNode {
+			for j := 0; j < len(node.Content); j += 2 {
+				if key := node.Content[j]; !envNamePattern.MatchString(key.Value) {
+					v.fail(key, "variável '%s' inválida em env da regra '%s'", key.Value, label)
+				}
+			}
+		}
+		for _, key := range []string{"connect_timeout", "command_timeout"} {
+			if node := mappingValue(item, key); node != nil && strings.HasPrefix(node.Value, "-") {
+				v.fail(node, "%s da regra '%s' não pode ser negativo", key, label)
+			}
+		}
+		if node := mappingValue(item, "proxy"); node != nil && node.Value == "true" && v.cfg.Config.Proxy == "" {
+			v.warn(node, "regra '%s' habilita proxy, mas config.proxy não está configurado", label)
+		}
+		if !slices.ContainsFunc(ruleOptions, func(key string) bool { return mappingValue(item, key) != nil }) {
+			v.warn(item, "regra '%s' não define nenhuma opção", label)
+		}
+	}
+}
+
+// checkName verifica se o item tem nome e se o nome não se repete no arquivo
+func (v *configValidator) checkName(item *yaml.Node, kind string, names map[string]int) string {
+	node := mappingValue(item, "name")
+	if node == nil || node.Value == "" {
+		v.fail(item, "%s sem name", kind)
+		return ""
+	}
+	if line, ok := names[node.Value]; ok {
+		v.fail(node, "%s '%s' repetido (definido na linha %d)", kind, node.Value, line)
+	} else {
+		names[node.Value] = node.Line
+	}
+	return node.Value
+}
+
+// checkPasswordSource avisa quando o item define password_command e password_ref ao mesmo tempo
+func (v *configValidator) checkPasswordSource(item *yaml.Node, kind, name string) {
+	command, ref := mappingValue(item, "password_command"), mappingValue(item, "password_ref")
+	if command != nil && command.Value != "" && ref != nil && ref.Value != "" {
+		v.warn(ref, "%s '%s' define password_command e password_ref: password_command tem precedência", kind, name)
+	}
+}
+
+// checkPort verifica se a porta do item está entre 1 e 65535
+func (v *configValidator) checkPort(item *yaml.Node, kind, name string) {
+	node := mappingValue(item, "port")
+	if node == nil {
+		v.fail(item, "%s '%s' sem port", kind, name)
+	} else if !validPort(node.Value) {
+		v.fail(node, "porta inválida no %s '%s': %s (use 1-65535)", kind, name, node.Value)
+	}
+}
+
+// checkKeys compara as chaves dos mapeamentos com as tags yaml da struct correspondente
+func (v *configValidator) checkKeys(node *yaml.Node, t reflect.Type) {
+	node = resolveAlias(node)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Value == "<<" {
+				continue // Merge de âncoras YAML
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("chave desconhecida '%s'", key.Value)
+				if suggestion := closestKey(key.Value, fields); suggestion != "" {
+					message += fmt.Sprintf(" (você quis dizer '%s'?)", suggestion)
+				}
+				v.add(ValidationIssue{File: v.file, Line: key.Line, Column: key.Column, Message: message, Unknown: true})
+				continue
+			}
+			v.checkKeys(node.Content[i+1], field)
+		}
+	case reflect.Slice:
+		for _, item := range sequenceItems(node) {
+			v.checkKeys(item, t.Elem())
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			v.checkKeys(node.Content[i], t.Elem())
+		}
+	}
+}
+
+// fail registra um erro na posição do nó
+func (v *configValidator) fail(node *yaml.Node, format string, args ...any) {
+	v.add(ValidationIssue{File: v.file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// warn registra um aviso na posição do nó
+func (v *configValidator) warn(node *yaml.Node, format string, args ...any) {
+	v.result.Warnings = append(v.result.Warnings, ValidationIssue{File: v.file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// add registra o problema como erro (chaves desconhecidas são avisos fora do modo estrito)
+func (v *configValidator) add(issue ValidationIssue) {
+	if issue.Unknown && !v.strict {
+		v.result.Warnings = append(v.result.Warnings, issue)
+		return
+	}
+	v.result.Errors = append(v.result.Errors, issue)
+}
+
+// yamlFields retorna os campos exportados da struct indexados pelo nome da tag yaml
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestKey sugere a chave conhecida mais parecida (distância de edição até 2)
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if distance := editDistance(key, name); distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance calcula a distância de Levenshtein entre duas strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// validPort verifica se o valor é uma porta entre 1 e 65535
+func validPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port >= 1 && port <= 65535
+}
+
+// mappingValue retorna o valor de uma chave em um MappingNode (nil se ausente)
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	if index := findKeyIndex(mapping, key); index >= 0 {
+		return resolveAlias(mapping.Content[index+1])
+	}
+	return nil
+}
+
+// sequenceItems retorna os itens de um SequenceNode (nil se o nó não for uma lista)
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	items := make([]*yaml.Node, len(node.Content))
+	for i, item := range node.Content {
+		items[i] = resolveAlias(item)
+	}
+	return items
+}
+
+// resolveAlias segue aliases YAML (*nome) até o nó âncora
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Cofre de senhas (sc vault)
+// Os segredos ficam em ~/.sshControl/vault.json, cifrados com XChaCha20-Poly1305. A chave é
+// derivada da senha mestra com Argon2id (sal aleatório por cofre). Usuários e hosts referenciam
+// um segredo com password_ref, resolvido na autenticação por senha.
+
+const (
+	// VaultFileName é o arquivo do cofre no diretório de configuração
+	VaultFileName = "vault.json"
+
+	vaultFormatVersion = 1
+	vaultKDF           = "argon2id"
+	vaultCipher        = "xchacha20-poly1305"
+	vaultSaltSize      = 16
+	vaultKeySize       = chacha20poly1305.KeySize
+)
+
+// Parâmetros do Argon2id para cofres novos (recomendação da RFC 9106 para uso interativo)
+const (
+	vaultArgonTime    = 3
+	vaultArgonMemory  = 64 * 1024 // KiB
+	vaultArgonThreads = 4
+)
+
+// ErrVaultPassphrase indica senha mestra incorreta (ou cofre adulterado)
+var ErrVaultPassphrase = errors.New("senha do cofre incorreta ou arquivo corrompido")
+
+// vaultKDFParams são os parâmetros de derivação da chave, gravados no arquivo
+type vaultKDFParams struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// vaultFile é o formato do arquivo do cofre (os segredos ficam apenas em Data, cifrados)
+type vaultFile struct {
+	Version int            `json:"version"`
+	KDF     vaultKDFParams `json:"kdf"`
+	Cipher  string         `json:"cipher"`
+	Nonce   []byte         `json:"nonce"`
+	Data    []byte         `json:"data"`
+}
+
+// Vault é o cofre aberto (decifrado em memória)
+type Vault struct {
+	path    string
+	kdf     vaultKDFParams
+	key     []byte
+	secrets map[string]string
+}
+
+// VaultPath retorna o caminho do cofre no diretório do config.yaml
+func VaultPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), VaultFileName)
+}
+
+// VaultExists indica se o cofre já foi criado
+func VaultExists(path string) bool {
+	return fileExists(path)
+}
+
+// CreateVault cria um cofre vazio protegido pela senha mestra (gravado por Save)
+func CreateVault(path, passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("senha do cofre não pode ser vazia")
+	}
+	salt := make([]byte, vaultSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("erro ao gerar sal: %w", err)
+	}
+	kdf := vaultKDFParams{Name: vaultKDF, Salt: salt, Time: vaultArgonTime, Memory: vaultArgonMemory, Threads: vaultArgonThreads}
+	return &Vault{path: path, kdf: kdf, key: deriveVaultKey(passphrase, kdf), secrets: make(map[string]string)}, nil
+}
+
+// OpenVault lê e decifra o cofre (ErrVaultPassphrase se a senha estiver errada)
+func OpenVault(path, passphrase string) (*Vault, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cofre não encontrado em %s (crie com 'sc vault set <nome>')", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler cofre: %w", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("erro ao parsear cofre %s: %w", path, err)
+	}
+	if file.Version != vaultFormatVersion || file.KDF.Name != vaultKDF || file.Cipher != vaultCipher {
+		return nil, fmt.Errorf("formato de cofre não suportado (versão %d, %s, %s)", file.Version, file.KDF.Name, file.Cipher)
+	}
+	// Parâmetros fora do razoável travariam (ou derrubariam) a derivação da chave
+	if file.KDF.Time == 0 || file.KDF.Time > 100 || file.KDF.Threads == 0 || file.KDF.Memory > 4*1024*1024 || len(file.KDF.Salt) < vaultSaltSize {
+		return nil, fmt.Errorf("parâmetros do %s inválidos no cofre %s", vaultKDF, path)
+	}
+
+	vault := &Vault{path: path, kdf: file.KDF, key: deriveVaultKey(passphrase, file.KDF)}
+	aead, err := chacha20poly1305.NewX(vault.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, ErrVaultPassphrase
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, vault.additionalData())
+	if err != nil {
+		return nil, ErrVaultPassphrase
+	}
+	if err := json.Unmarshal(plaintext, &vault.secrets); err != nil {
+		return nil, fmt.Errorf("erro ao parsear segredos do cofre: %w", err)
+	}
+	if vault.secrets == nil {
+		vault.secrets = make(map[string]string)
+	}
+	return vault, nil
+}
+
+// Save cifra e grava o cofre (com trava, cópia de segurança e escrita atômica)
+// Cada gravação usa um nonce novo; a chave (e o sal) são mantidos
+func (v *Vault) Save() error {
+	plaintext, err := json.Marshal(v.secrets)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar segredos: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("erro ao gerar nonce: %w", err)
+	}
+	file := vaultFile{
+		Version: vaultFormatVersion,
+		KDF:     v.kdf,
+		Cipher:  vaultCipher,
+		Nonce:   nonce,
+		Data:    aead.Seal(nil, nonce, plaintext, v.additionalData()),
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar cofre: %w", err)
+	}
+
+	configDir := filepath.Dir(v.path)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("erro ao criar diretório do cofre: %w", err)
+	}
+	unlock, err := lockConfigDir(configDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	backups := newVaultBackupSet(configDir)
+	defer backups.finish()
+	if err := backups.add(v.path); err != nil {
+		return err
+	}
+	return writeFileAtomic(v.path, append(data, '\n'))
+}
+
+// Get retorna o segredo com o nome informado
+func (v *Vault) Get(name string) (string, bool) {
+	secret, ok := v.secrets[name]
+	return secret, ok
+}
+
+// Set grava (ou substitui) um segredo
+func (v *Vault) Set(name, secret string) error {
+	if err := validateName("segredo", name); err != nil {
+		return err
+	}
+	v.secrets[name] = secret
+	return nil
+}
+
+// Remove remove um segredo e indica se ele existia
+func (v *Vault) Remove(name string) bool {
+	if _, ok := v.secrets[name]; !ok {
+		return false
+	}
+	delete(v.secrets, name)
+	return true
+}
+
+// Names retorna os nomes dos segredos em ordem alfabética
+func (v *Vault) Names() []string {
+	return slices.Sorted(maps.Keys(v.secrets))
+}
+
+// additionalData vincula os parâmetros do arquivo à cifra (alterá-los invalida o cofre)
+func (v *Vault) additionalData() []byte {
+	params, _ := json.Marshal(v.kdf)
+	return fmt.Appendf(nil, "sshControl-vault:%d:%s:%s", vaultFormatVersion, vaultCipher, params)
+}
+
+// deriveVaultKey deriva a chave de cifra da senha mestra com Argon2id
+func deriveVaultKey(passphrase string, kdf vaultKDFParams) []byte {
+	return argon2.IDKey([]byte(passphrase), kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, vaultKeySize)
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"net"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alexeiev/sshControl/cmd"
@@ -31,8 +39,81 @@ var (
 	askPassword   bool
 	verbose       bool
 
+	// Flags de rollout (múltiplos hosts)
+	forks    int
+	batch    string
+	canary   int
+	failFast bool
+	pause    time.Duration
+
+	// Flags de saída (múltiplos hosts)
+	streamOutput bool
+	groupOutput  bool
+	diffOutput   bool
+	outputFormat string
+	noStdin      bool
+
+	// Arquivo com a lista de hosts (-f)
+	hostsFile string
+
+	// Flags de sudo (múltiplos hosts)
+	useSudo  bool
+	sudoUser string
+
+	// Comando (-c) e destino do cp up renderizados como template por host (-T)
+	useTemplate bool
+
+	// Flags de tempo limite
+	connectTimeout time.Duration
+	commandTimeout time.Duration
+
 	// Flags do comando cp
 	cpRecursive bool
+
+	// Flags do comando run
+	runEnv         []string
+	runInterpreter string
+
+	// Flags do comando runbook
+	runbookCheck bool
+
+	// Flags dos comandos import e export
+	importDryRun  bool
+	ansibleFormat string
+
+	// Flags do comando hosts
+	hostsImportFormat string
+	hostsExportFormat string
+	hostsMapping      []string
+	hostsTags         string
+	hostsAddTags      []string
+	hostsYes          bool
+
+	// Flags do comando config
+	configStrict      bool
+	configRestoreList bool
+	configRestoreYes  bool
+	configMigrateDry  bool
+
+	// Flags dos comandos de edição (sc host, sc user e sc jump)
+	hostAddPort  int
+	hostAddTags  []string
+	hostAddUser  string
+	hostAddJump  string
+	hostEditAddr string
+	hostEditPort int
+	hostEditUser string
+	hostEditJump string
+	userAddKeys  []string
+	userAddRef   string
+	userAddCmdPw string
+	jumpAddPort  int
+	jumpAddUser  string
+	jumpAddCmdPw string
+
+	// Flags de contexto (sc context)
+	contextName    string
+	contextUseNone bool
 )
 
 var rootCmd = &cobra.Command{
@@ -50,7 +131,7 @@ Para ver exemplos de uso e manual completo, execute: sc man`,
   sc -c "comando" <host>       # Executa comando remoto
   sc -c "comando" -l <hosts>   # Executa em múltiplos hosts
   sc -s                        # Lista servidores cadastrados
-  sc -s @tag                   # Lista servidores filtrados por tag
+  sc -s @tag                   # Lista servidores filtrados (aceita seletores)
   sc man                       # Exibe manual completo com exemplos`,
 	Args: cobra.ArbitraryArgs,
 	Run:  runCommand,
@@ -136,11 +217,432 @@ Ordem dos argumentos:
   sc cp up ./config.yaml /etc/app/ webserver    # Envia para /etc/app/config.yaml
   sc cp up -l web1 web2 web3 ./script.sh /opt/scripts/
   sc cp up -r ./dist/ /var/www/html/ webserver
-  sc cp up -l app1 app2 -j prod-jump ./app.jar /opt/app/`,
-	Args: cobra.MinimumNArgs(2),
+  sc cp up -l app1 app2 -j prod-jump ./app.jar /opt/app/
+  sc cp up -l -f hosts.txt ./app.jar /opt/app/`,
+	Args: cobra.MinimumNArgs(1),
 	Run:  runCpUp,
 }
 
+var runCmd = &cobra.Command{
+	Use:   "run [flags] <script> [args...] [--] <hosts|@tag...>",
+	Short: "Executa um script local em um ou mais hosts",
+	Long: `Envia um script local para cada host, executa com o interpretador do shebang
+(ou /bin/sh) e remove o arquivo ao final.
+
+Sem "--", todos os argumentos após o script são hosts. Com "--", os argumentos
+entre o script e "--" são repassados ao script e os hosts vêm depois.
+As flags do sc devem vir antes do script.
+
+A execução usa o mesmo motor de "sc -c ... -l": rollout, --stream, --group,
+--output, tempo limite, --sudo e stdin replicado funcionam da mesma forma.`,
+	Example: `  sc run ./check-disk.sh @web
+  sc run ./deploy.sh v1.4.2 --force -- web1 web2
+  sc run -e ENV=prod -e TOKEN --sudo ./setup.sh @app
+  sc run --interpreter python3 ./report.py @db
+  sc run -f hosts.txt ./check-disk.sh`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runScript,
+}
+
+var runbookCmd = &cobra.Command{
+	Use:   "runbook [flags] <arquivo.yaml>",
+	Short: "Executa um runbook (sequência de passos em YAML)",
+	Long: `Executa os passos de um runbook em sequência. Cada passo roda em paralelo
+nos seus hosts e pode ser exec, upload, download, script ou wait_port.
+
+Um host que falha em um passo (sem ignore_errors) não executa os passos seguintes.
+Ao final, exibe um relatório por host e por passo.
+
+Use --check para validar o arquivo e exibir o plano sem conectar aos hosts.`,
+	Example: `  sc runbook deploy.yaml
+  sc runbook --check deploy.yaml
+  sc runbook -a --forks 5 deploy.yaml
+  sc runbook -p deploy.yaml
+  sc runbook -o json deploy.yaml`,
+	Args: cobra.ExactArgs(1),
+	Run:  runRunbook,
+}
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Gerencia as fontes de inventário dinâmico",
+	Long: `Fontes de inventário dinâmico (config.inventory_sources) são executáveis locais
+que retornam hosts em JSON. Os hosts são mesclados aos do config.yaml a cada execução
+do sc, usando o cache em ~/.sshControl/cache enquanto ele for válido (cache_ttl).
+
+Hosts do inventário são somente leitura: nunca são gravados no config.yaml.`,
+}
+
+var inventoryRefreshCmd = &cobra.Command{
+	Use:   "refresh [fonte...]",
+	Short: "Executa as fontes de inventário e atualiza o cache",
+	Long: `Executa as fontes de inventário dinâmico ignorando o cache e grava a nova saída
+em ~/.sshControl/cache. Sem argumentos, atualiza todas as fontes.`,
+	Example: `  sc inventory refresh
+  sc inventory refresh aws`,
+	Run: runInventoryRefresh,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Importa hosts de outras ferramentas para o config.yaml",
+}
+
+var importAnsibleCmd = &cobra.Command{
+	Use:   "ansible [flags] <inventário>",
+	Short: "Importa hosts de um inventário do Ansible (INI ou YAML)",
+	Long: `Lê um inventário do Ansible (INI ou YAML) e adiciona ao config.yaml os hosts
+que ainda não existem:
+
+  grupos (e grupos pais)        -> tags
+  ansible_host / ansible_port   -> host / port
+  ansible_user                  -> user
+  ProxyJump em ansible_ssh_common_args -> jump (jump host já cadastrado)
+  demais vars do host           -> vars
+  vars de grupo                 -> tags.<grupo>.vars
+
+Hosts com nome já cadastrado são mantidos sem alteração.`,
+	Example: `  sc import ansible ./inventory/hosts.ini
+  sc import ansible --dry-run ./inventory/prod.yml`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImportAnsible,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exporta os hosts do config.yaml para outras ferramentas",
+}
+
+var exportAnsibleCmd = &cobra.Command{
+	Use:   "ansible [flags]",
+	Short: "Exporta os hosts como inventário do Ansible",
+	Long: `Escreve no stdout um inventário do Ansible com os hosts do config.yaml.
+
+Tags viram grupos (caracteres inválidos viram "_", ex: env/prod -> env_prod),
+vars de tag viram vars de grupo e o jump host de cada host (campo jump) vira
+ansible_ssh_common_args com ProxyJump.`,
+	Example: `  sc export ansible > hosts.ini
+  sc export ansible --format yaml > hosts.yml`,
+	Args: cobra.NoArgs,
+	Run:  runExportAnsible,
+}
+
+var hostsCmd = &cobra.Command{
+	Use:     "hosts",
+	Aliases: []string{"host"},
+	Short:   "Gerencia os hosts: adiciona, remove, edita, importa e exporta",
+	Long: `Gerencia os hosts do config.yaml sem abrir o editor. As alterações são validadas
+e gravadas no arquivo que define o host (hosts novos vão para config.local_file,
+ou para o config.yaml). "sc host" e "sc hosts" são equivalentes.`,
+}
+
+var hostAddCmd = &cobra.Command{
+	Use:   "add [flags] <nome> <endereço>",
+	Short: "Adiciona um host",
+	Long: `Adiciona um host. O endereço aceita host:porta (ex: 10.0.0.5:2222) quando
+--port não é informado.`,
+	Example: `  sc host add web01 10.0.0.5
+  sc host add web02 10.0.0.6 --port 2222 --tag web --tag env/prod
+  sc host add db01 db01.interno --user admin --jump production-jump`,
+	Args: cobra.ExactArgs(2),
+	Run:  runHostAdd,
+}
+
+var hostRmCmd = &cobra.Command{
+	Use:     "rm <nome>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove hosts",
+	Example: `  sc host rm web01
+  sc host rm web01 web02`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runHostRm,
+}
+
+var hostEditCmd = &cobra.Command{
+	Use:   "edit [flags] <nome>",
+	Short: "Altera o endereço, a porta, o usuário ou o jump host de um host",
+	Long: `Altera apenas os campos informados. Use --user "" ou --jump "" para remover
+o usuário ou o jump host padrão do host.`,
+	Example: `  sc host edit web01 --addr 10.0.0.50
+  sc host edit web01 --port 2222 --jump production-jump
+  sc host edit web01 --jump ""`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHostEdit,
+}
+
+var hostRenameCmd = &cobra.Command{
+	Use:     "rename <nome> <novo-nome>",
+	Short:   "Renomeia um host",
+	Example: `  sc host rename web01 web-01`,
+	Args:    cobra.ExactArgs(2),
+	Run:     runHostRename,
+}
+
+var hostTagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Adiciona ou remove tags de um host",
+}
+
+var hostTagAddCmd = &cobra.Command{
+	Use:     "add <host> <tag>...",
+	Short:   "Adiciona tags a um host",
+	Example: `  sc host tag add web01 web env/prod`,
+	Args:    cobra.MinimumNArgs(2),
+	Run:     runHostTag,
+}
+
+var hostTagRmCmd = &cobra.Command{
+	Use:     "rm <host> <tag>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove tags de um host",
+	Example: `  sc host tag rm web01 autocreated`,
+	Args:    cobra.MinimumNArgs(2),
+	Run:     runHostTag,
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Gerencia os usuários do config.yaml",
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add [flags] <nome>",
+	Short: "Adiciona um usuário",
+	Example: `  sc user add deploy --key ~/.ssh/deploy_ed25519
+  sc user add admin --key ~/.ssh/admin_rsa --key ~/.ssh/admin_ed25519`,
+	Args: cobra.ExactArgs(1),
+	Run:  runUserAdd,
+}
+
+var jumpCmd = &cobra.Command{
+	Use:   "jump",
+	Short: "Gerencia os jump hosts do config.yaml",
+}
+
+var jumpAddCmd = &cobra.Command{
+	Use:   "add [flags] <nome> <endereço>",
+	Short: "Adiciona um jump host",
+	Long: `Adiciona um jump host. Sem --user, usa o usuário padrão (default_user); o
+usuário precisa estar definido em users.`,
+	Example: `  sc jump add dc2-jump bastion.dc2.example.com
+  sc jump add dc2-jump bastion.dc2.example.com --port 2222 --user devops`,
+	Args: cobra.ExactArgs(2),
+	Run:  runJumpAdd,
+}
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Cofre criptografado de senhas (password_ref)",
+	Long: `Guarda senhas em ~/.sshControl/vault.json, cifradas com XChaCha20-Poly1305 e uma
+chave derivada da senha mestra com Argon2id.
+
+Usuários e hosts referenciam um segredo com password_ref; a senha é usada
+automaticamente quando a autenticação por chave falha. A senha mestra é pedida
+uma única vez por execução, mesmo em múltiplos hosts (-l).
+
+  config:
+    users:
+      - name: admin
+        ssh_keys: []
+        password_ref: admin-legado
+  hosts:
+    - name: switch01
+      host: 10.0.0.2
+      port: 22
+      password_ref: switch01      # Sobrepõe o password_ref do usuário`,
+}
+
+var vaultSetCmd = &cobra.Command{
+	Use:   "set <nome>",
+	Short: "Grava um segredo no cofre (cria o cofre se necessário)",
+	Long: `Grava (ou substitui) um segredo. A senha é lida do terminal, com confirmação,
+ou do stdin quando redirecionado.`,
+	Example: `  sc vault set admin-legado
+  printf '%s' "$SENHA" | sc vault set switch01`,
+	Args: cobra.ExactArgs(1),
+	Run:  runVaultSet,
+}
+
+var vaultGetCmd = &cobra.Command{
+	Use:     "get <nome>",
+	Short:   "Exibe um segredo do cofre",
+	Example: `  sc vault get admin-legado`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runVaultGet,
+}
+
+var vaultRmCmd = &cobra.Command{
+	Use:     "rm <nome>",
+	Aliases: []string{"remove"},
+	Short:   "Remove um segredo do cofre",
+	Args:    cobra.ExactArgs(1),
+	Run:     runVaultRm,
+}
+
+var vaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lista os segredos do cofre e quem os referencia",
+	Args:  cobra.NoArgs,
+	Run:   runVaultList,
+}
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Contextos nomeados: usuário, jump host, proxy, filtro e config.yaml",
+	Long: `Um contexto agrupa as opções usadas juntas no dia a dia, definidas na seção
+contexts do config.yaml:
+
+  contexts:
+    - name: prod
+      user: ops              # equivale a -u
+      jump: bastion          # equivale a -j
+      proxy: true            # equivale a -p
+      filter: "@prod"        # seletor que limita os hosts da TUI, de sc -s e de sc -l
+    - name: lab
+      user: ubuntu
+      config: ~/lab/sc.yaml  # outro arquivo de configuração
+
+O contexto ativo vem de --context, da variável SC_CONTEXT ou do último
+'sc context use' (nessa ordem). Flags informadas na linha de comando sempre
+vencem as opções do contexto.`,
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lista os contextos (o ativo é marcado com *)",
+	Args:  cobra.NoArgs,
+	Run:   runContextList,
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use [flags] <nome>",
+	Short: "Define o contexto usado por padrão",
+	Example: `  sc context use prod
+  sc context use --none`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runContextUse,
+}
+
+var contextCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Exibe o contexto ativo",
+	Args:  cobra.NoArgs,
+	Run:   runContextCurrent,
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [flags] <host>",
+	Short: "Mostra as regras aplicadas a um host e a conexão resultante",
+	Long: `Avalia a seção rules do config.yaml para um host (nome do config.yaml ou
+[user@]host[:porta]) e mostra quais regras corresponderam, as opções que cada
+uma definiu e a conexão resultante, com a origem de cada valor.
+
+Nenhuma conexão é aberta. As flags -u, -j, -p e de tempo limite (e o contexto
+ativo) são consideradas como em uma conexão real.`,
+	Example: `  sc explain db01
+  sc explain 10.20.0.15
+  sc explain -u deploy ubuntu@web.lab.example.com:2222`,
+	Args: cobra.ExactArgs(1),
+	Run:  runExplain,
+}
+
+var hostsImportCmd = &cobra.Command{
+	Use:   "import [flags] <arquivo.csv|arquivo.json|->",
+	Short: "Importa hosts de um arquivo CSV ou JSON",
+	Long: `Adiciona ao config.yaml os hosts de um arquivo CSV ou JSON.
+
+Colunas (CSV) e campos (JSON): name, host, port, user, jump, tags, addresses e vars.
+No CSV, tags e addresses são separados por ";" (cada endereço como
+host[:porta][ via jump], ex: "203.0.113.5 via bastion;[2001:db8::5]:2222")
+e vars são colunas "vars.<nome>".
+Use --map campo=coluna quando os nomes das colunas forem diferentes.
+
+Hosts já cadastrados (mesmo nome ou, em linhas sem name, mesmo endereço e
+porta) não são duplicados: tags e campos (host, port, user, jump, addresses, vars) são atualizados conforme --tags:
+  merge     une tags e vars, preenche campos vazios (padrão)
+  replace   substitui pelas tags e campos importados
+  keep      mantém as tags e campos existentes
+Com merge e keep, os campos divergentes são mantidos e listados na prévia.
+
+Antes de gravar, exibe o diff da seção hosts do config.yaml e pede confirmação.`,
+	Example: `  sc hosts import novos-hosts.csv
+  sc hosts import --dry-run inventario.json
+  sc hosts import --map name=hostname --map host=ip --map vars.rack=rack dc2.csv
+  sc hosts import --tag dc2 --tags replace -y dc2.csv
+  sc -s -o json | sc hosts import --format json -`,
+	Args: cobra.ExactArgs(1),
+	Run:  runHostsImport,
+}
+
+var hostsExportCmd = &cobra.Command{
+	Use:   "export [flags]",
+	Short: "Exporta os hosts em CSV ou JSON",
+	Long: `Escreve no stdout os hosts do config.yaml em CSV ou JSON, no mesmo formato
+aceito por sc hosts import.`,
+	Example: `  sc hosts export > hosts.csv
+  sc hosts export --format json > hosts.json`,
+	Args: cobra.NoArgs,
+	Run:  runHostsExport,
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Verifica e gerencia o config.yaml",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [flags] [config.yaml]",
+	Short: "Valida o config.yaml e os arquivos incluídos",
+	Long: `Verifica o config.yaml (e os arquivos de include e conf.d) e lista todos os
+problemas com arquivo, linha e coluna:
+
+  - nomes repetidos de hosts, usuários, jump hosts e fontes de inventário
+  - default_user ou usuário de jump host não definido em users
+  - jump host de um host (campo jump) inexistente
+  - portas fora de 1-65535, hosts sem endereço, proxy inválido
+  - chaves desconhecidas (erros com --strict, avisos sem)
+
+Sem argumento, valida ~/.sshControl/config.yaml. Termina com código 1 se houver
+erros, para uso em CI.`,
+	Example: `  sc config validate
+  sc config validate --strict ./config.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigValidate,
+}
+
+var configRestoreCmd = &cobra.Command{
+	Use:   "restore [flags] [cópia]",
+	Short: "Restaura uma cópia de segurança da configuração",
+	Long: `Antes de cada gravação (auto-criação de hosts, sc host, importações, migrações),
+os arquivos alterados são copiados para ~/.sshControl/backups/<data-hora>/. As 20
+cópias mais recentes são mantidas.
+
+Sem argumento, restaura a cópia mais recente. O estado atual é copiado antes da
+restauração, então ela também pode ser desfeita.`,
+	Example: `  sc config restore --list
+  sc config restore
+  sc config restore 20260301-142310.512`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigRestore,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [flags] [config.yaml]",
+	Short: "Atualiza o formato do config.yaml para a versão atual",
+	Long: `O campo version do config.yaml indica o formato do arquivo. Quando uma versão
+nova do sc muda o formato, as migrações pendentes são aplicadas em ordem
+(v0 → v1 → ...) na próxima execução, preservando comentários e formatação.
+Antes de gravar, o arquivo é copiado para ~/.sshControl/backups/ (veja
+sc config restore).
+
+Use --dry-run para ver as migrações pendentes sem alterar o arquivo.`,
+	Example: `  sc config migrate --dry-run
+  sc config migrate
+  sc config migrate --dry-run ./config.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigMigrate,
+}
+
 // showWithPager exibe o conteúdo usando um paginador (less, more) ou saída direta
 func showWithPager(content string) {
 	// Tenta usar less primeiro (melhor experiência)
@@ -227,6 +729,60 @@ EXECUÇÃO EM MÚLTIPLOS HOSTS
   sc -j 1 -c "df -h" -l db1 db2 db3       Via jump host
   sc -a -c "uptime" -l web1 web2 web3     Solicita senha uma vez antes
 
+  Concorrência e rollout:
+  sc -c "uptime" -l --forks 20 @all       No máximo 20 hosts simultâneos
+  sc -c "cmd" -l --batch 10 @web          Lotes de 10 hosts
+  sc -c "cmd" -l --batch 25% --pause 30s @web
+                                          Lotes de 25% com pausa entre eles
+  sc -c "cmd" -l --canary 2 @web          Executa em 2 hosts antes dos demais
+  sc -c "cmd" -l --fail-fast @web         Interrompe na primeira falha
+
+  Saída em tempo real:
+  sc -c "tail -f /var/log/syslog" -l --stream @web
+                                          Cada linha prefixada pelo host
+
+  Saídas agrupadas:
+  sc -c "cat /etc/os-release" -l --group @all
+                                          Cada saída distinta uma vez, com seus hosts
+  sc -c "sysctl -a" -l --diff @db         Diff de cada grupo contra a maioria
+
+  Saída para automação (CI):
+  sc -c "uptime" -l -o json @web          Resultados em JSON no stdout
+  sc -c "uptime" -l -o ndjson @web        Um objeto JSON por host
+  sc -s -o csv                            Listagem de servidores em CSV
+  sc cp up -l @web app.conf /etc -o yaml  Resultados do envio em YAML
+
+  Stdin replicado para todos os hosts:
+  sc -c "sudo tee /etc/motd" -l @web < motd.txt
+                                          Conteúdo enviado ao stdin de cada host
+
+  Sudo em múltiplos hosts:
+  sc -c "systemctl restart nginx" -l --sudo @web
+                                          Senha do sudo pedida uma única vez
+  sc -c "whoami" -l --sudo-user postgres @db
+                                          Executa como outro usuário
+
+  Templates por host (-T, no -c e no destino do cp up):
+  sc -T -c "hostnamectl set-hostname {{.Name}}" -l @web
+                                          Nome do host no config.yaml
+  sc -T -c "deploy --env {{.Vars.env}}" -l @app
+                                          vars do host ou das suas tags
+  sc cp up -T -l @web app.conf "{{.Vars.app_dir}}/"
+                                          Destino diferente em cada host
+  Campos: .Name .Host .Port .User .Tags .Vars
+  Sem -T, {{ }} é enviado literalmente (ex: docker ps --format '{{.Names}}');
+  com -T, use {{"{{"}} para abrir chaves literais
+  Vars por tag no config.yaml:
+    tags:
+      production:
+        vars: {env: prod}
+
+  Tempo limite:
+  sc -c "cmd" -l --connect-timeout 5s @web
+                                          Desiste de hosts que não conectam em 5s
+  sc -c "cmd" -l --command-timeout 10m @web
+                                          Encerra (SIGKILL) comandos após 10 minutos
+
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
 TAGS (Agrupamento de Hosts)
@@ -243,7 +799,28 @@ TAGS (Agrupamento de Hosts)
   sc -c "df -h" -l @web @db               Múltiplas tags
   sc -c "hostname" -l @production server1 Combina tag e host
 
-  Na TUI, digite "/" e busque pelo nome da tag para filtrar.
+  Tags hierárquicas: @env seleciona hosts com env, env/prod, env/stg...
+
+SELETORES DE HOSTS
+  Usados por -l, cp up -l, sc run, sc -s, runbooks e pelo filtro da TUI:
+  @web&@prod                              Interseção (web e prod)
+  @db,!@staging                           Exclusão (db, exceto staging)
+  web-*                                   Glob no nome do host
+  node[01-20]                             Intervalo (node01 ... node20)
+  ~^db[0-9]+$                             Expressão regular no nome
+  -f hosts.txt                            Hosts do arquivo (um por linha)
+  -                                       Hosts do stdin (não é replicado)
+
+  sc -c "uptime" -l '@web&@prod'
+  sc -c "df -h" -l '@db,!@staging'
+  sc -c "uptime" -l 'node[01-20]'         Nomes fora do config.yaml viram
+                                          conexões diretas
+  cat hosts.txt | sc -c "uptime" -l -
+  sc -s 'web-*'
+
+  Use aspas para que o shell não interprete !, &, * e [].
+
+  Na TUI, digite "/" e um seletor (ex: @web&@prod) ou parte do nome.
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
@@ -257,6 +834,164 @@ AUTO-CRIAÇÃO DE HOSTS
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
+ARQUIVOS ADICIONAIS
+  Arquivos em ~/.sshControl/conf.d/*.yaml são carregados automaticamente.
+  Outros arquivos podem ser incluídos com globs relativos a ~/.sshControl:
+
+  include: [team/*.yaml]                  No topo do config.yaml
+  config:
+    local_file: local.yaml                Destino dos hosts novos
+
+  Ordem: config.yaml, include, conf.d e local_file. Arquivos posteriores
+  vencem (hosts, usuários e jump hosts por nome; vars de tags por chave).
+  Alterações são gravadas no arquivo que define o host; hosts novos vão
+  para local_file (ou para o config.yaml, se não configurado). Remover ou
+  renomear um host que sobrescreve outro não altera a entrada sobrescrita.
+
+VALIDAÇÃO DA CONFIGURAÇÃO
+  sc config validate                      Lista os problemas com arquivo:linha:coluna
+  sc config validate --strict config.yaml Chaves desconhecidas também são erros
+
+  Verifica nomes repetidos, default_user e usuários de jump hosts não
+  definidos, jump hosts inexistentes, portas inválidas e chaves desconhecidas
+  (com sugestão, ex: 'prot' -> 'port'). Termina com código 1 se houver erros.
+  Os erros também são exibidos como avisos ao carregar a configuração.
+
+VERSÃO DO FORMATO
+  sc config migrate --dry-run             Exibe as migrações pendentes
+  sc config migrate                       Aplica as migrações pendentes
+
+  O campo version indica o formato do config.yaml (ausente = 0). Migrações
+  pendentes (v0 → v1 → ...) são aplicadas na próxima execução, com cópia de
+  segurança antes. Dados de exemplo do template não são copiados.
+
+CÓPIAS DE SEGURANÇA
+  sc config restore --list                Lista as cópias de segurança
+  sc config restore                       Restaura a cópia mais recente
+  sc config restore 20260301-101500.123   Restaura uma cópia específica
+
+  Gravações usam trava (~/.sshControl/.lock), arquivo temporário + rename
+  e permissão 0600. Antes de cada gravação os arquivos alterados são copiados
+  para ~/.sshControl/backups/<data-hora>/ (mantidas as 20 mais recentes).
+  O cofre (vault.json) é copiado para ~/.sshControl/vault-backups/.
+
+CONTEXTOS
+  sc context list                         Lista os contextos (* = ativo)
+  sc context use prod                     Define o contexto usado por padrão
+  sc context use --none                   Desativa o contexto padrão
+  sc context current                      Exibe o contexto ativo
+  sc --context lab -s                     Usa um contexto apenas neste comando
+
+  contexts:                               (apenas no config.yaml principal)
+    - name: prod
+      user: ops                           Equivale a -u
+      jump: bastion                       Equivale a -j
+      proxy: true                         Equivale a -p
+      filter: "@prod"                     Limita os hosts da TUI, de -s e de -l
+      config: ~/lab/sc.yaml               Outro arquivo de configuração
+
+  Ordem: --context, SC_CONTEXT e 'sc context use'. Flags informadas na linha
+  de comando vencem as opções do contexto. O banner da TUI exibe o contexto.
+  Contexto desconhecido ou com filter inválido só interrompe os comandos
+  que conectam; os demais apenas avisam.
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+REGRAS DE CONEXÃO
+  sc explain db01                         Regras aplicadas e conexão resultante
+  sc explain 10.20.0.15                   Também para conexões diretas
+
+  rules:                                  (avaliadas em ordem)
+    - name: vpn
+      match:
+        cidr: 10.20.0.0/16                IP do host dentro da rede (nomes DNS
+                                          são resolvidos, limite de 2s)
+        host: "*.lab.example.com"         Glob sobre o nome ou o endereço
+        tag: lab                          Tag do host (inclui as filhas)
+      user: ops
+      jump: bastion
+      port: 2222
+      ssh_keys: [~/.ssh/lab_ed25519]      Tentadas antes das chaves do usuário
+      proxy: true
+      env: {LANG: C.UTF-8}                Exportadas antes dos comandos
+      connect_timeout: 5s
+      command_timeout: 10m
+
+  Todas as condições precisam corresponder (match vazio = todos os hosts) e
+  cada uma aceita valores separados por vírgula. Cada opção vem da primeira
+  regra que a define; ssh_keys se acumulam e env é mesclado por variável.
+  Flags, contexto e os campos do host (user, jump, port, user@, :porta)
+  têm precedência sobre as regras.
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+ENDEREÇOS ALTERNATIVOS
+  config:
+    address_timeout: 3s                   Tempo máximo de cada endereço (padrão: 5s)
+    address_strategy: order               order (um por vez) ou race (em paralelo)
+
+  hosts:
+    - name: db-prod
+      host: 10.20.0.15                    Tentado primeiro
+      addresses:
+        - host: 203.0.113.15
+          jump: production-jump           Sem jump = o das rules ou direta
+        - host: db-prod.example.com
+          port: 2222                      Sem port = a porta do host
+
+  Com race, uma nova conexão é aberta a cada 250ms (ou quando a anterior
+  falha) e o handshake é feito com a primeira que abrir. -j vale para todos
+  os endereços. O endereço usado é exibido ao conectar e no campo address
+  de --output; um host não é auto-criado se o endereço já estiver em addresses.
+  As rules são avaliadas também para cada endereço alternativo (ex: cidr):
+  o jump e a porta que o endereço não define vêm das regras que corresponderem.
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+INVENTÁRIO DINÂMICO
+  Executáveis locais em config.inventory_sources retornam hosts em JSON
+  ({"hosts": [{"name", "host", "port", "user", "tags", "vars", "jump",
+  "addresses"}], "tags": {}}).
+  Os hosts são mesclados aos do config.yaml e nunca são gravados nele.
+
+  inventory_sources:
+    - name: aws
+      command: ~/.sshControl/plugins/aws-inventory
+      cache_ttl: 10m                      Validade do cache (default: 5m)
+
+  sc inventory refresh                    Executa as fontes e atualiza o cache
+  sc inventory refresh aws                Atualiza apenas a fonte "aws"
+
+  O cache fica em ~/.sshControl/cache. Se a fonte falhar, o cache expirado
+  é usado com um aviso. O campo jump do host define o jump host padrão
+  (usado quando -j não é informado) e o campo user, o usuário padrão
+  (usado quando -u não é informado).
+
+  Inventários do Ansible (INI ou YAML):
+  sc import ansible --dry-run hosts.ini   Exibe os hosts que seriam importados
+  sc import ansible hosts.ini             Adiciona os hosts novos ao config.yaml
+  sc export ansible --format yaml         Escreve o inventário no stdout
+
+  Hosts em CSV ou JSON (colunas name, host, port, user, jump, tags,
+  addresses, vars.*):
+  sc hosts import --dry-run dc2.csv       Exibe o diff sem gravar
+  sc hosts import --map host=ip dc2.csv   Mapeia colunas com outros nomes
+  sc hosts import --tags replace dc2.csv  Tags e campos de hosts existentes:
+                                          merge, replace ou keep
+  sc hosts export --format json           Escreve os hosts no stdout
+
+  Edição pela linha de comando ("sc host" e "sc hosts" são equivalentes):
+  sc host add web01 10.0.0.5 -t web       Adiciona um host (--port, --tag,
+                                          --user, --jump)
+  sc host edit web01 --port 2222          Altera --addr, --port, --user ou --jump
+  sc host rename web01 web-01             Renomeia um host
+  sc host tag add web-01 canary           Adiciona tags (tag rm remove)
+  sc host rm web-01                       Remove hosts
+  sc user add deploy -k ~/.ssh/deploy     Adiciona um usuário
+  sc jump add dc2-jump bastion.dc2        Adiciona um jump host (--port, --user)
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
 CÓPIA DE ARQUIVOS (SFTP)
   Download de arquivos do servidor remoto:
   sc cp down [flags] <host> <remoto> [local]
@@ -289,6 +1024,50 @@ CÓPIA DE ARQUIVOS (SFTP)
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
+EXECUÇÃO DE SCRIPTS LOCAIS
+  sc run [flags] <script> [args...] [--] <hosts|@tag...>
+                                         Envia o script, executa e remove
+                                         (interpretador do shebang ou /bin/sh)
+
+  Sem "--", tudo após o script são hosts. As flags do sc vêm antes do script.
+
+  Flags do run:
+  -e, --env KEY=VALUE   Variável de ambiente (KEY sozinho copia do ambiente local)
+  --interpreter <cmd>   Interpretador (sobrescreve o shebang)
+  Também aceita -u, -j, -a, -v, rollout, --stream, --group, -o, --sudo e tempo limite
+
+  Exemplos:
+  sc run ./check-disk.sh @web
+  sc run ./deploy.sh v1.4.2 --force -- web1 web2
+  sc run -e ENV=prod --sudo ./setup.sh @app
+  sc run ./import.sh @db < dados.csv
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+RUNBOOKS (Passos em YAML)
+  sc runbook [flags] <arquivo.yaml>      Executa os passos em sequência
+  sc runbook --check <arquivo.yaml>      Exibe o plano sem conectar
+
+  Exemplo de arquivo:
+    name: Atualizar nginx
+    hosts: "@web"
+    steps:
+      - name: Enviar configuração
+        upload: {src: nginx.conf, dest: /etc/nginx/}
+      - name: Reiniciar
+        exec: systemctl restart nginx
+        sudo: true
+        register: restart
+      - name: Aguardar porta
+        wait_port: {port: 80, timeout: 30s}
+        when: not .restart.Failed
+
+  Ações: exec, upload, download, script, wait_port
+  Opções por passo: name, hosts, when, ignore_errors, register, sudo
+  Flags: --check, --forks, -o, -u, -j, -a, -v, --connect-timeout, --command-timeout
+
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
 PROXY REVERSO
   Compartilha proxy HTTP/HTTPS/FTP da máquina local com hosts remotos.
   Configure no config.yaml:
@@ -322,10 +1101,25 @@ PORT FORWARD (Túnel SSH)
 
 COMANDOS ÚTEIS
   sc -s                     Lista servidores e jump hosts cadastrados
-  sc -s @tag                Lista servidores filtrados por tag
+  sc -s <seletor>           Lista servidores filtrados (ex: @tag, 'web-*')
   sc -V, sc --version       Exibe versão do sshControl
   sc update                 Atualiza para versão mais recente
   sc cp                     Copia arquivos via SFTP (veja sc cp --help)
+  sc run                    Executa script local nos hosts (veja sc run --help)
+  sc runbook                Executa um runbook YAML (veja sc runbook --help)
+  sc inventory refresh      Atualiza o cache do inventário dinâmico
+  sc import ansible <inv>   Importa hosts de um inventário do Ansible
+  sc export ansible         Exporta os hosts como inventário do Ansible
+  sc hosts import <arquivo> Importa hosts de CSV/JSON (veja sc hosts import --help)
+  sc hosts export           Exporta os hosts em CSV ou JSON
+  sc host add|rm|edit       Edita hosts (veja sc host --help)
+  sc user add / sc jump add Cadastra usuários e jump hosts
+  sc vault set|get|rm|list  Cofre criptografado de senhas (password_ref)
+  sc config validate        Valida o config.yaml e os arquivos incluídos
+  sc config restore         Restaura uma cópia de segurança da configuração
+  sc config migrate         Atualiza o formato do config.yaml (--dry-run para simular)
+  sc context list|use       Contextos nomeados (usuário, jump host, proxy e filtro)
+  sc explain <host>         Mostra as regras (rules) aplicadas a um host
   sc port-forward           Encaminha porta local para remota (veja sc port-forward --help)
   sc man                    Exibe este manual
   sc --help                 Exibe ajuda rápida
@@ -341,6 +1135,22 @@ FLAGS DISPONÍVEIS
   -p, --proxy               Habilita proxy reverso
   -a, --ask-password        Solicita senha antes de conectar
   -v, --verbose             Modo debug (informações detalhadas da conexão)
+  --forks <n>               Máximo de hosts simultâneos (com -l)
+  --batch <n|p%>            Executa em lotes (com -l)
+  --canary <n>              Executa primeiro em N hosts (com -l)
+  --fail-fast               Interrompe na primeira falha (com -l)
+  --pause <duração>         Pausa entre lotes (com -l)
+  --stream                  Saída em tempo real prefixada pelo host (com -l)
+  --group                   Agrupa hosts com saída idêntica (com -l)
+  --diff                    Agrupa e exibe diff contra a maioria (com -l)
+  -o, --output <formato>    text, json, ndjson, yaml ou csv (com -l ou -s)
+  --no-stdin                Não replica o stdin redirecionado para os hosts (com -l)
+  --sudo                    Executa o comando via sudo (com -l)
+  --sudo-user <usuario>     Usuário alvo do sudo, implica --sudo (com -l)
+  -T, --template            Renderiza o comando como template por host ({{.Name}}, {{.Vars.nome}})
+  --connect-timeout <dur>   Tempo máximo para conectar (config: connect_timeout)
+  --command-timeout <dur>   Tempo máximo de execução (config: command_timeout)
+  --context <nome>          Contexto a usar (sobrepõe SC_CONTEXT e sc context use)
   -V, --version             Exibe versão
   -h, --help                Exibe ajuda
 
@@ -350,13 +1160,35 @@ AUTENTICAÇÃO
   Ordem de tentativa:
   1. Chave SSH (configurada no config.yaml)
   2. SSH Agent (se disponível)
-  3. Senha (interativa ou via -a)
+  3. Senha (via -a, password_command, cofre via password_ref ou interativa)
 
   A flag -a solicita senha antes de tentar conectar, útil para:
   - Primeira conexão (antes de instalar chave)
   - Automações em múltiplos hosts
   - Servidores sem chave configurada
 
+COFRE DE SENHAS
+  sc vault set <nome>       Grava um segredo (cria o cofre; lê do stdin se redirecionado)
+  sc vault get <nome>       Exibe um segredo
+  sc vault rm <nome>        Remove um segredo
+  sc vault list             Lista os segredos e quem os referencia
+
+  users ou hosts:
+    password_ref: <nome>    Senha do cofre usada se a chave falhar
+                            (a do host tem precedência; jump hosts usam a do usuário)
+
+  Arquivo ~/.sshControl/vault.json: XChaCha20-Poly1305, chave derivada da
+  senha mestra com Argon2id. A senha mestra é pedida uma vez por execução.
+
+SENHA VIA COMANDO EXTERNO
+  users, hosts ou jump_hosts:
+    password_command: pass show infra/admin
+
+  Executado com sh -c (a primeira linha da saída é a senha), no máximo uma
+  vez por execução e só se a chave falhar. O stderr vai para o terminal e o
+  stdin vem de /dev/tty (prompts de desbloqueio). Precedência: host, depois
+  usuário; password_command antes de password_ref.
+
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
 MAIS INFORMAÇÕES
@@ -372,18 +1204,67 @@ func init() {
 	rootCmd.AddCommand(manCmd)
 	rootCmd.AddCommand(cpCmd)
 	rootCmd.AddCommand(pfCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(runbookCmd)
+	rootCmd.AddCommand(inventoryCmd)
+	inventoryCmd.AddCommand(inventoryRefreshCmd)
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importAnsibleCmd)
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportAnsibleCmd)
+	rootCmd.AddCommand(hostsCmd)
+	hostsCmd.AddCommand(hostsImportCmd)
+	hostsCmd.AddCommand(hostsExportCmd)
+	hostsCmd.AddCommand(hostAddCmd)
+	hostsCmd.AddCommand(hostRmCmd)
+	hostsCmd.AddCommand(hostEditCmd)
+	hostsCmd.AddCommand(hostRenameCmd)
+	hostsCmd.AddCommand(hostTagCmd)
+	hostTagCmd.AddCommand(hostTagAddCmd)
+	hostTagCmd.AddCommand(hostTagRmCmd)
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userAddCmd)
+	rootCmd.AddCommand(jumpCmd)
+	jumpCmd.AddCommand(jumpAddCmd)
+	rootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultSetCmd)
+	vaultCmd.AddCommand(vaultGetCmd)
+	vaultCmd.AddCommand(vaultRmCmd)
+	vaultCmd.AddCommand(vaultListCmd)
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextCurrentCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configRestoreCmd)
+	configCmd.AddCommand(configMigrateCmd)
 	cpCmd.AddCommand(cpDownCmd)
 	cpCmd.AddCommand(cpUpCmd)
 
+	// Contexto (vale para todos os comandos)
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Contexto a usar (sobrepõe SC_CONTEXT e 'sc context use')")
+
 	rootCmd.Flags().StringVarP(&username, "user", "u", "", "Nome do usuário da configuração a ser usado")
 	rootCmd.Flags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice, ex: production-jump ou 1)")
 	rootCmd.Flags().StringVarP(&command, "command", "c", "", "Comando a ser executado remotamente")
 	rootCmd.Flags().BoolVarP(&multipleHosts, "list", "l", false, "Executa comando em múltiplos hosts (requer -c)")
-	rootCmd.Flags().BoolVarP(&showServers, "servers", "s", false, "Lista servidores (use 'sc -s @tag' para filtrar por tag)")
+	rootCmd.Flags().BoolVarP(&showServers, "servers", "s", false, "Lista servidores (use 'sc -s @tag' ou outro seletor para filtrar)")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "V", false, "Exibe a versão do sshControl")
 	rootCmd.Flags().BoolVarP(&proxyEnabled, "proxy", "p", false, "Habilita tunnel SSH reverso para compartilhar proxy")
 	rootCmd.Flags().BoolVarP(&askPassword, "ask-password", "a", false, "Solicita senha antes de tentar autenticação (útil para automações)")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Modo debug: exibe informações detalhadas da conexão")
+	addRolloutFlags(rootCmd)
+	rootCmd.Flags().BoolVar(&streamOutput, "stream", false, "Exibe a saída de cada host em tempo real, prefixada pelo nome do host (com -l)")
+	addGroupFlags(rootCmd)
+	addHostsFileFlag(rootCmd)
+	addOutputFlag(rootCmd)
+	rootCmd.Flags().BoolVar(&noStdin, "no-stdin", false, "Não replica o stdin local para os hosts (com -l)")
+	rootCmd.Flags().BoolVar(&useSudo, "sudo", false, "Executa o comando via sudo em cada host (com -l)")
+	rootCmd.Flags().StringVar(&sudoUser, "sudo-user", "", "Usuário alvo do sudo (implica --sudo, padrão: root)")
+	rootCmd.Flags().BoolVarP(&useTemplate, "template", "T", false, "Renderiza o comando como template por host ({{.Name}}, {{.Vars.nome}})")
+	addTimeoutFlags(rootCmd)
 
 	// Flags do comando cp (persistentes para down e up)
 	cpCmd.PersistentFlags().BoolVarP(&cpRecursive, "recursive", "r", false, "Copia diretórios recursivamente")
@@ -391,15 +1272,302 @@ func init() {
 	cpCmd.PersistentFlags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice)")
 	cpCmd.PersistentFlags().BoolVarP(&askPassword, "ask-password", "a", false, "Solicita senha antes de tentar autenticação")
 	cpCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Modo debug: exibe informações detalhadas da conexão")
+	addTimeoutFlags(cpDownCmd)
+	addTimeoutFlags(cpUpCmd)
+	addHostsFileFlag(cpUpCmd)
+
+	// Flags do comando run (as flags do sc vêm antes do script)
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().StringVarP(&username, "user", "u", "", "Nome do usuário da configuração a ser usado")
+	runCmd.Flags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice)")
+	runCmd.Flags().BoolVarP(&askPassword, "ask-password", "a", false, "Solicita senha antes de tentar autenticação")
+	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Modo debug: exibe informações detalhadas da conexão")
+	runCmd.Flags().BoolVarP(&proxyEnabled, "proxy", "p", false, "Habilita tunnel SSH reverso para compartilhar proxy")
+	runCmd.Flags().StringArrayVarP(&runEnv, "env", "e", nil, "Variável de ambiente para o script (KEY=VALUE ou KEY para copiar do ambiente local)")
+	runCmd.Flags().StringVar(&runInterpreter, "interpreter", "", "Interpretador do script (padrão: shebang ou /bin/sh)")
+	addRolloutFlags(runCmd)
+	runCmd.Flags().BoolVar(&streamOutput, "stream", false, "Exibe a saída de cada host em tempo real, prefixada pelo nome do host")
+	addGroupFlags(runCmd)
+	addHostsFileFlag(runCmd)
+	addOutputFlag(runCmd)
+	runCmd.Flags().BoolVar(&noStdin, "no-stdin", false, "Não replica o stdin local para os hosts")
+	runCmd.Flags().BoolVar(&useSudo, "sudo", false, "Executa o script via sudo em cada host")
+	runCmd.Flags().StringVar(&sudoUser, "sudo-user", "", "Usuário alvo do sudo (implica --sudo, padrão: root)")
+	addTimeoutFlags(runCmd)
+
+	// Flags do comando runbook
+	runbookCmd.Flags().BoolVar(&runbookCheck, "check", false, "Valida o runbook e exibe o plano sem conectar aos hosts")
+	runbookCmd.Flags().StringVarP(&username, "user", "u", "", "Nome do usuário da configuração a ser usado")
+	runbookCmd.Flags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice)")
+	runbookCmd.Flags().BoolVarP(&proxyEnabled, "proxy", "p", false, "Habilita tunnel SSH reverso para compartilhar proxy")
+	runbookCmd.Flags().BoolVarP(&askPassword, "ask-password", "a", false, "Solicita senha antes de tentar autenticação")
+	runbookCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Modo debug: exibe informações detalhadas da conexão")
+	runbookCmd.Flags().IntVar(&forks, "forks", 0, "Número máximo de hosts executando cada passo ao mesmo tempo (0 = sem limite)")
+	addOutputFlag(runbookCmd)
+	addTimeoutFlags(runbookCmd)
 
 	// Flag específica do upload para múltiplos hosts
 	cpUpCmd.Flags().BoolVarP(&multipleHosts, "list", "l", false, "Envia para múltiplos hosts em paralelo")
+	cpUpCmd.Flags().BoolVarP(&useTemplate, "template", "T", false, "Renderiza o destino como template por host ({{.Name}}, {{.Vars.nome}})")
+	addRolloutFlags(cpUpCmd)
+	addOutputFlag(cpUpCmd)
 
 	// Flags do comando port-forward
 	pfCmd.Flags().StringVarP(&username, "user", "u", "", "Nome do usuário da configuração a ser usado")
 	pfCmd.Flags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice)")
 	pfCmd.Flags().BoolVarP(&askPassword, "ask-password", "a", false, "Solicita senha antes de tentar autenticação")
 	pfCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Modo debug: exibe informações detalhadas da conexão")
+	pfCmd.Flags().DurationVar(&connectTimeout, "connect-timeout", 0, "Tempo máximo para conectar (ex: 10s; padrão: connect_timeout do config.yaml)")
+
+	// Flags dos comandos import e export
+	importAnsibleCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Exibe os hosts que seriam importados sem gravar o config.yaml")
+	exportAnsibleCmd.Flags().StringVar(&ansibleFormat, "format", "ini", "Formato do inventário: ini ou yaml")
+
+	// Flags do comando hosts
+	hostsImportCmd.Flags().StringVar(&hostsImportFormat, "format", "", "Formato do arquivo: csv ou json (padrão: pela extensão)")
+	hostsImportCmd.Flags().StringArrayVar(&hostsMapping, "map", nil, "Mapeia um campo para uma coluna do arquivo (ex: host=ip, vars.rack=rack)")
+	hostsImportCmd.Flags().StringVar(&hostsTags, "tags", "merge", "Tags e campos de hosts já cadastrados: merge, replace ou keep")
+	hostsImportCmd.Flags().StringArrayVar(&hostsAddTags, "tag", nil, "Tag adicionada a todos os hosts importados (pode repetir)")
+	hostsImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Exibe o diff sem gravar o config.yaml")
+	hostsImportCmd.Flags().BoolVarP(&hostsYes, "yes", "y", false, "Grava sem pedir confirmação")
+	hostsExportCmd.Flags().StringVar(&hostsExportFormat, "format", "csv", "Formato de saída: csv ou json")
+
+	// Flags dos comandos de edição
+	hostAddCmd.Flags().IntVarP(&hostAddPort, "port", "p", 0, "Porta SSH (padrão: a do endereço ou 22)")
+	hostAddCmd.Flags().StringArrayVarP(&hostAddTags, "tag", "t", nil, "Tag do host (pode repetir)")
+	hostAddCmd.Flags().StringVarP(&hostAddUser, "user", "u", "", "Usuário padrão do host")
+	hostAddCmd.Flags().StringVarP(&hostAddJump, "jump", "j", "", "Jump host padrão do host (nome ou índice)")
+	hostEditCmd.Flags().StringVar(&hostEditAddr, "addr", "", "Novo endereço")
+	hostEditCmd.Flags().IntVarP(&hostEditPort, "port", "p", 0, "Nova porta SSH")
+	hostEditCmd.Flags().StringVarP(&hostEditUser, "user", "u", "", "Novo usuário padrão (\"\" remove)")
+	hostEditCmd.Flags().StringVarP(&hostEditJump, "jump", "j", "", "Novo jump host padrão (\"\" remove)")
+	userAddCmd.Flags().StringArrayVarP(&userAddKeys, "key", "k", nil, "Chave SSH privada (pode repetir)")
+	userAddCmd.Flags().StringVar(&userAddRef, "password-ref", "", "Segredo do cofre com a senha do usuário (sc vault)")
+	userAddCmd.Flags().StringVar(&userAddCmdPw, "password-command", "", "Comando que imprime a senha do usuário (ex: 'pass show ssh/admin')")
+	jumpAddCmd.Flags().IntVarP(&jumpAddPort, "port", "p", 22, "Porta SSH do jump host")
+	jumpAddCmd.Flags().StringVarP(&jumpAddUser, "user", "u", "", "Usuário do jump host (padrão: default_user)")
+	jumpAddCmd.Flags().StringVar(&jumpAddCmdPw, "password-command", "", "Comando que imprime a senha do jump host")
+
+	// Flags do comando config
+	configValidateCmd.Flags().BoolVar(&configStrict, "strict", false, "Trata chaves desconhecidas como erros")
+	configRestoreCmd.Flags().BoolVar(&configRestoreList, "list", false, "Lista as cópias de segurança disponíveis")
+	configRestoreCmd.Flags().BoolVarP(&configRestoreYes, "yes", "y", false, "Restaura sem pedir confirmação")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDry, "dry-run", false, "Exibe as migrações pendentes sem alterar o arquivo")
+
+	// Flags do comando context
+	contextUseCmd.Flags().BoolVar(&contextUseNone, "none", false, "Desativa o contexto padrão")
+
+	// Flags do comando explain
+	explainCmd.Flags().StringVarP(&username, "user", "u", "", "Nome do usuário da configuração a ser usado")
+	explainCmd.Flags().StringVarP(&jumpHost, "jump", "j", "", "Jump host a usar (nome ou índice)")
+	explainCmd.Flags().BoolVarP(&proxyEnabled, "proxy", "p", false, "Considera o proxy reverso habilitado")
+	addTimeoutFlags(explainCmd)
+}
+
+// addRolloutFlags registra as flags de concorrência e rollout para múltiplos hosts
+func addRolloutFlags(c *cobra.Command) {
+	c.Flags().IntVar(&forks, "forks", 0, "Número máximo de hosts executando ao mesmo tempo (0 = sem limite)")
+	c.Flags().StringVar(&batch, "batch", "", "Executa em lotes de N hosts ou P% do total (ex: 10 ou 25%)")
+	c.Flags().IntVar(&canary, "canary", 0, "Executa primeiro em N hosts e interrompe se algum falhar")
+	c.Flags().BoolVar(&failFast, "fail-fast", false, "Não inicia novos hosts após a primeira falha")
+	c.Flags().DurationVar(&pause, "pause", 0, "Pausa entre lotes (ex: 30s, 2m)")
+}
+
+// addOutputFlag registra a flag de formato de saída legível por máquina
+func addOutputFlag(c *cobra.Command) {
+	c.Flags().StringVarP(&outputFormat, "output", "o", "text", "Formato da saída: text, json, ndjson, yaml ou csv (com -l ou -s)")
+}
+
+// addHostsFileFlag registra a flag de arquivo com a lista de hosts
+func addHostsFileFlag(c *cobra.Command) {
+	c.Flags().StringVarP(&hostsFile, "hosts-file", "f", "", "Arquivo com hosts/seletores, um por linha (\"-\" lê do stdin)")
+}
+
+// hostSelectorArgs acrescenta aos seletores os hosts lidos de --hosts-file e do argumento "-" (stdin)
+// Retorna também se o stdin foi consumido (nesse caso ele não é replicado para os hosts)
+func hostSelectorArgs(args []string) ([]string, bool) {
+	var selectors []string
+	stdinUsed := false
+	readStdin := func() {
+		if stdinUsed {
+			return
+		}
+		hosts, err := config.ReadHostList(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao ler hosts do stdin: %v\n", err)
+			os.Exit(1)
+		}
+		selectors = append(selectors, hosts...)
+		stdinUsed = true
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			readStdin()
+			continue
+		}
+		selectors = append(selectors, arg)
+	}
+
+	switch hostsFile {
+	case "":
+	case "-":
+		readStdin()
+	default:
+		file, err := os.Open(config.ExpandHomePath(hostsFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao abrir arquivo de hosts: %v\n", err)
+			os.Exit(1)
+		}
+		hosts, err := config.ReadHostList(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao ler arquivo de hosts: %v\n", err)
+			os.Exit(1)
+		}
+		selectors = append(selectors, hosts...)
+	}
+	return selectors, stdinUsed
+}
+
+// addGroupFlags registra as flags de agrupamento de saídas idênticas
+func addGroupFlags(c *cobra.Command) {
+	c.Flags().BoolVar(&groupOutput, "group", false, "Agrupa hosts com saída idêntica e exibe cada saída uma única vez")
+	c.Flags().BoolVar(&diffOutput, "diff", false, "Como --group, exibindo cada grupo como diff em relação à saída da maioria")
+}
+
+// validateGroupFlags verifica se --group/--diff não conflitam com outros modos de saída
+func validateGroupFlags(format cmd.OutputFormat) {
+	if !groupOutput && !diffOutput {
+		return
+	}
+	if format.IsStructured() {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --group e --diff não podem ser usadas com --output\n")
+		os.Exit(1)
+	}
+	if streamOutput {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --group e --diff não podem ser usadas com --stream\n")
+		os.Exit(1)
+	}
+}
+
+// parsedOutputFormat valida a flag --output e sai em caso de erro
+func parsedOutputFormat() cmd.OutputFormat {
+	format, err := cmd.ParseOutputFormat(outputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	return format
+}
+
+// addTimeoutFlags registra as flags de tempo limite de conexão e de execução
+func addTimeoutFlags(c *cobra.Command) {
+	c.Flags().DurationVar(&connectTimeout, "connect-timeout", 0, "Tempo máximo para conectar (ex: 10s; padrão: connect_timeout do config.yaml)")
+	c.Flags().DurationVar(&commandTimeout, "command-timeout", 0, "Tempo máximo de execução do comando ou transferência (ex: 5m; padrão: command_timeout do config.yaml)")
+}
+
+// resolveTimeouts determina os limites de tempo: flags têm precedência sobre o config.yaml
+func resolveTimeouts(c *cobra.Command, cfg *config.ConfigFile) cmd.Timeouts {
+	timeouts := cmd.Timeouts{
+		Connect: cfg.Config.ConnectTimeout,
+		Command: cfg.Config.CommandTimeout,
+	}
+	if c.Flags().Changed("connect-timeout") {
+		timeouts.Connect, timeouts.ConnectFlag = connectTimeout, true
+	}
+	if c.Flags().Changed("command-timeout") {
+		timeouts.Command, timeouts.CommandFlag = commandTimeout, true
+	}
+	if err := timeouts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	return timeouts
+}
+
+// applyContext aplica o contexto ativo (sc context) às flags -u, -j e -p não informadas
+func applyContext(c *cobra.Command, cfg *config.ConfigFile) {
+	ctx := cfg.Context()
+	if ctx == nil {
+		return
+	}
+	if flag := c.Flag("user"); flag != nil && !flag.Changed && ctx.User != "" {
+		username = ctx.User
+	}
+	if flag := c.Flag("jump"); flag != nil && !flag.Changed && ctx.Jump != "" {
+		jumpHost = ctx.Jump
+	}
+	if flag := c.Flag("proxy"); flag != nil && !flag.Changed && ctx.Proxy {
+		proxyEnabled = true
+	}
+}
+
+// initConfig inicializa o diretório de configuração e resolve o contexto ativo (--context, SC_CONTEXT
+// ou 'sc context use'), retornando o arquivo de configuração a usar (o do contexto, se definido)
+// Comandos que conectam (connecting) encerram com erro se o contexto for inválido; os demais
+// (validação, edição, importação e exportação) apenas avisam e usam o config.yaml principal
+func initConfig(connecting bool) (string, *config.Context) {
+	configPath, err := config.InitializeConfigDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, err := config.ActiveContext(contextName)
+	if err == nil && ctx != nil {
+		if path := ctx.ConfigPath(); path != "" {
+			if _, statErr := os.Stat(path); statErr != nil {
+				err = fmt.Errorf("arquivo de configuração do contexto '%s' não encontrado: %s", ctx.Name, path)
+			} else {
+				configPath = path
+			}
+		}
+	}
+	if err != nil {
+		if connecting {
+			fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %v (contexto ignorado)\n", err)
+		return configPath, nil
+	}
+
+	// Um filtro inválido não pode liberar todos os hosts: os comandos que conectam encerram
+	if ctx != nil {
+		if _, err := ctx.Selector(); err != nil {
+			if connecting {
+				fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: %v\n", err)
+		}
+	}
+	return configPath, ctx
+}
+
+// interruptContext retorna um contexto cancelado no primeiro Ctrl+C (ou SIGTERM),
+// permitindo encerrar os comandos remotos em andamento. Um segundo Ctrl+C encerra o sc imediatamente.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// rolloutOptions monta e valida as opções de rollout a partir das flags
+func rolloutOptions() cmd.RolloutOptions {
+	opts := cmd.RolloutOptions{
+		Forks:    forks,
+		Batch:    batch,
+		Canary:   canary,
+		FailFast: failFast,
+		Pause:    pause,
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	return opts
 }
 
 func runCommand(cobraCmd *cobra.Command, args []string) {
@@ -415,11 +1583,7 @@ func runCommand(cobraCmd *cobra.Command, args []string) {
 	}
 
 	// Inicializa o diretório de configuração e obtém o caminho do arquivo
-	configPath, err := config.InitializeConfigDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
-		os.Exit(1)
-	}
+	configPath, configContext := initConfig(true)
 
 	// Carrega o arquivo de configuração
 	cfg, err := config.LoadConfig(configPath)
@@ -428,24 +1592,58 @@ func runCommand(cobraCmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Verifique se o arquivo está no formato correto.\n")
 		os.Exit(1)
 	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Se a flag -s foi usada, lista os servidores e sai
-	// Se houver um argumento adicional com @tag, usa como filtro de tag
+	// Argumentos adicionais são seletores que filtram a listagem (ex: @tag, web-*, @web&@prod)
 	if showServers {
-		tagFilter := ""
-		if len(args) > 0 {
-			arg := args[0]
-			if strings.HasPrefix(arg, "@") {
-				tagFilter = strings.TrimPrefix(arg, "@")
-			} else {
-				fmt.Fprintf(os.Stderr, "Erro: Use @tag para filtrar por tag (ex: sc -s @%s)\n", arg)
-				os.Exit(1)
-			}
+		selectors, _ := hostSelectorArgs(args)
+		if _, err := config.ParseSelector(selectors...); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		if err := cmd.ListServers(cfg, selectors, parsedOutputFormat()); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao escrever listagem: %v\n", err)
+			os.Exit(1)
 		}
-		cmd.ListServers(cfg, tagFilter)
 		return
 	}
 
+	// Formatos estruturados só se aplicam a -l e -s
+	format := parsedOutputFormat()
+	if format.IsStructured() && !multipleHosts {
+		fmt.Fprintf(os.Stderr, "Erro: A opção --output requer -l ou -s\n")
+		os.Exit(1)
+	}
+	if format.IsStructured() && streamOutput {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --output e --stream não podem ser usadas juntas\n")
+		os.Exit(1)
+	}
+	validateGroupFlags(format)
+	if (groupOutput || diffOutput) && !multipleHosts {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --group e --diff requerem -l\n")
+		os.Exit(1)
+	}
+
+	// --sudo-user implica --sudo; ambos só se aplicam a -l
+	if sudoUser != "" {
+		useSudo = true
+	}
+	if useSudo && !multipleHosts {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --sudo e --sudo-user requerem -l\n")
+		os.Exit(1)
+	}
+	if hostsFile != "" && !multipleHosts {
+		fmt.Fprintf(os.Stderr, "Erro: A opção --hosts-file requer -l ou -s\n")
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
+
 	// Resolve o Jump Host se solicitado
 	var selectedJumpHost *config.JumpHost
 	if jumpHost != "" {
@@ -496,20 +1694,42 @@ func runCommand(cobraCmd *cobra.Command, args []string) {
 
 	// Modo múltiplos hosts
 	if multipleHosts {
+		args, stdinUsed := hostSelectorArgs(args)
 		if len(args) == 0 {
 			fmt.Fprintf(os.Stderr, "Erro: A opção -l requer especificar pelo menos um host\n")
 			fmt.Fprintf(os.Stderr, "Uso: sc -c \"comando\" -l <host1> <host2> <host3> ...\n")
 			os.Exit(1)
 		}
-		cmd.ConnectMultiple(cfg, configPath, args, selectedUser, selectedJumpHost, command, proxyEnabled, askPassword, verbose)
+		opts := cmd.MultipleOptions{
+			Rollout:  rolloutOptions(),
+			Stream:   streamOutput,
+			Group:    groupOutput,
+			Diff:     diffOutput,
+			Output:   format,
+			Timeouts: resolveTimeouts(cobraCmd, cfg),
+			Sudo:     useSudo,
+			SudoUser: sudoUser,
+			Template: useTemplate,
+		}
+		// Quando o stdin local é redirecionado (arquivo ou pipe), ele é replicado para todos os hosts
+		// (exceto quando foi usado para ler a lista de hosts)
+		if !noStdin && !stdinUsed && !term.IsTerminal(int(os.Stdin.Fd())) {
+			opts.Stdin = os.Stdin
+		}
+		ctx, stop := interruptContext()
+		ok := cmd.ConnectMultiple(ctx, cfg, configPath, args, selectedUser, selectedJumpHost, command, proxyEnabled, askPassword, verbose, opts)
+		stop()
 		showUpdateNotification(updateResultChan, version)
+		if !ok {
+			os.Exit(1)
+		}
 		return
 	}
 
 	// Verifica se há argumentos (modo direto)
 	if len(args) > 0 {
 		hostArg := args[0]
-		cmd.Connect(cfg, configPath, hostArg, selectedUser, selectedJumpHost, command, proxyEnabled, askPassword, verbose)
+		cmd.Connect(cfg, configPath, hostArg, selectedUser, selectedJumpHost, command, useTemplate, proxyEnabled, askPassword, verbose, resolveTimeouts(cobraCmd, cfg))
 		showUpdateNotification(updateResultChan, version)
 		return
 	}
@@ -648,17 +1868,17 @@ func runCpDown(cobraCmd *cobra.Command, args []string) {
 	remotePath := args[1]
 
 	// Inicializa configuração
-	configPath, err := config.InitializeConfigDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
-		os.Exit(1)
-	}
+	configPath, configContext := initConfig(true)
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
 		os.Exit(1)
 	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Determina o diretório de destino
 	var localPath string
@@ -674,6 +1894,9 @@ func runCpDown(cobraCmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
+
 	// Resolve o Jump Host se solicitado
 	var selectedJumpHost *config.JumpHost
 	if jumpHost != "" {
@@ -703,74 +1926,24 @@ func runCpDown(cobraCmd *cobra.Command, args []string) {
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
-	// Resolve o host
-	var hostname string
-	var port int
-	var sshKeys []string
-
-	usernameToUse := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
-	}
-
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		u, h, p, err := cmd.ParseConnectionString(hostArg)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
-			os.Exit(1)
-		}
-		if u != "" && u != effectiveUser.Name {
-			usernameToUse = u
-			if userFromConfig := cfg.FindUser(usernameToUse); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				sshKeys = nil
-			}
-		}
-		hostname = h
-		port = p
-	}
-
-	// Busca as chaves SSH do jump host
-	var jumpHostSSHKeys []string
-	if selectedJumpHost != nil {
-		jumpHostSSHKeys = cfg.GetJumpHostSSHKeys(selectedJumpHost)
+	// Resolve o host (config.yaml, rules ou conexão direta) e cria a conexão SSH
+	sshConn, err := cmd.NewHostConnection(cfg, hostArg, selectedUser, selectedJumpHost, resolveTimeouts(cobraCmd, cfg), verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Solicita senha se -a for especificado
-	password := ""
 	if askPassword {
-		fmt.Printf("Password for %s@%s: ", usernameToUse, hostname)
+		fmt.Printf("Password for %s@%s: ", sshConn.User, sshConn.Host)
 		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		fmt.Println()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
 			os.Exit(1)
 		}
-		password = string(passwordBytes)
-	}
-
-	// Cria conexão SSH
-	sshConn := cmd.NewSSHConnection(
-		usernameToUse,
-		hostname,
-		port,
-		sshKeys,
-		password,
-		selectedJumpHost,
-		jumpHostSSHKeys,
-		"",
-		false,
-		"",
-		0,
-		verbose,
-	)
+		sshConn.Password = string(passwordBytes)
+	}
 
 	// Cria transferência
 	ft := &cmd.FileTransfer{
@@ -781,13 +1954,16 @@ func runCpDown(cobraCmd *cobra.Command, args []string) {
 	}
 
 	fmt.Println()
-	fmt.Printf("Baixando %s de %s@%s...\n", remotePath, usernameToUse, hostname)
-	if selectedJumpHost != nil {
-		fmt.Printf("   via Jump Host: %s\n", selectedJumpHost.Name)
+	fmt.Printf("Baixando %s de %s@%s...\n", remotePath, sshConn.User, sshConn.Host)
+	if sshConn.JumpHost != nil {
+		fmt.Printf("   via Jump Host: %s\n", sshConn.JumpHost.Name)
 	}
 	fmt.Println()
 
-	if err := ft.Download(sshConn); err != nil {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	if err := ft.Download(ctx, sshConn); err != nil {
 		fmt.Fprintf(os.Stderr, "\nErro: %v\n", err)
 		os.Exit(1)
 	}
@@ -821,7 +1997,7 @@ func runCpUp(cobraCmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 
-		hostArgs = args[:localIdx]
+		hostArgs, _ = hostSelectorArgs(args[:localIdx])
 		localPath = args[localIdx]
 
 		if localIdx+1 < len(args) {
@@ -836,7 +2012,17 @@ func runCpUp(cobraCmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 	} else {
+		if hostsFile != "" {
+			fmt.Fprintf(os.Stderr, "Erro: A opção --hosts-file requer -l\n")
+			os.Exit(1)
+		}
+
 		// Modo host único: arquivo local primeiro
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Erro: Nenhum host especificado\n")
+			fmt.Fprintf(os.Stderr, "Uso: sc cp up <arquivo_local> [destino_remoto] <host>\n")
+			os.Exit(1)
+		}
 		localPath = args[0]
 
 		if len(args) == 2 {
@@ -856,17 +2042,20 @@ func runCpUp(cobraCmd *cobra.Command, args []string) {
 	}
 
 	// Inicializa configuração
-	configPath, err := config.InitializeConfigDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
-		os.Exit(1)
-	}
+	configPath, configContext := initConfig(true)
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
 		os.Exit(1)
 	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
 
 	// Resolve o Jump Host se solicitado
 	var selectedJumpHost *config.JumpHost
@@ -897,22 +2086,52 @@ func runCpUp(cobraCmd *cobra.Command, args []string) {
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
+	// Formatos estruturados só se aplicam ao envio para múltiplos hosts
+	format := parsedOutputFormat()
+	isMultiple := multipleHosts || len(hostArgs) > 1
+	if format.IsStructured() && !isMultiple {
+		fmt.Fprintf(os.Stderr, "Erro: A opção --output requer múltiplos hosts (-l)\n")
+		os.Exit(1)
+	}
+
+	// Com -T, o destino é um template ({{.Name}}, {{.Vars.app_dir}}) renderizado por host
+	if useTemplate {
+		if err := cmd.ValidateTemplate(remotePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: template inválido no destino: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Cria transferência
 	ft := &cmd.FileTransfer{
 		LocalPath:  localPath,
 		RemotePath: remotePath,
 		Recursive:  cpRecursive,
 		Verbose:    verbose,
+		Quiet:      format.IsStructured(),
+		Timeouts:   resolveTimeouts(cobraCmd, cfg),
+		Template:   useTemplate,
 	}
 
+	ctx, stop := interruptContext()
+	defer stop()
+
 	// Modo múltiplos hosts
-	if multipleHosts || len(hostArgs) > 1 {
+	if isMultiple {
+		rollout := rolloutOptions()
+
+		// Mensagens informativas vão para stderr quando a saída é estruturada
+		info := os.Stdout
+		if format.IsStructured() {
+			info = os.Stderr
+		}
+
 		// Solicita senha antes se -a for especificado
 		password := ""
 		if askPassword {
-			fmt.Printf("Password for %s (será usada para todos os hosts): ", effectiveUser.Name)
+			fmt.Fprintf(info, "Password for %s (será usada para todos os hosts): ", effectiveUser.Name)
 			passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-			fmt.Println()
+			fmt.Fprintln(info)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
 				os.Exit(1)
@@ -920,100 +2139,77 @@ func runCpUp(cobraCmd *cobra.Command, args []string) {
 			password = string(passwordBytes)
 		}
 
-		fmt.Println()
-		fmt.Printf("Enviando %s para %d host(s)...\n", localPath, len(hostArgs))
+		fmt.Fprintln(info)
+		fmt.Fprintf(info, "Enviando %s para %d host(s)...\n", localPath, len(hostArgs))
 		if selectedJumpHost != nil {
-			fmt.Printf("   via Jump Host: %s\n", selectedJumpHost.Name)
+			fmt.Fprintf(info, "   via Jump Host: %s\n", selectedJumpHost.Name)
 		}
-		fmt.Println()
+		fmt.Fprintln(info)
 
 		startTime := time.Now()
-		results := ft.UploadMultiple(cfg, hostArgs, effectiveUser, selectedJumpHost, password, askPassword)
+		results, skipped := ft.UploadMultiple(ctx, cfg, hostArgs, selectedUser, selectedJumpHost, password, askPassword, rollout, format)
 		duration := time.Since(startTime)
 
-		cmd.DisplayTransferResults(results, duration)
+		if format.IsStructured() {
+			if err := cmd.WriteTransferResults(os.Stdout, format, results, skipped, startTime, duration); err != nil {
+				fmt.Fprintf(os.Stderr, "Erro ao escrever resultados: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			cmd.DisplayTransferResults(results, skipped, duration)
+		}
+
+		// Sai com erro se algum host falhou ou não foi executado
+		if len(skipped) > 0 {
+			os.Exit(1)
+		}
+		for _, result := range results {
+			if !result.Success {
+				os.Exit(1)
+			}
+		}
 		return
 	}
 
 	// Modo host único
 	hostArg := hostArgs[0]
 
-	var hostname string
-	var port int
-	var sshKeys []string
-
-	usernameToUse := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
+	// Resolve o host (config.yaml, rules ou conexão direta) e cria a conexão SSH
+	sshConn, err := cmd.NewHostConnection(cfg, hostArg, selectedUser, selectedJumpHost, ft.Timeouts, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
 	}
 
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		u, h, p, err := cmd.ParseConnectionString(hostArg)
+	if useTemplate {
+		remotePath, err = cmd.RenderHostTemplate(cfg, hostArg, selectedUser, remotePath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Erro: template inválido no destino: %v\n", err)
 			os.Exit(1)
 		}
-		if u != "" && u != effectiveUser.Name {
-			usernameToUse = u
-			if userFromConfig := cfg.FindUser(usernameToUse); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				sshKeys = nil
-			}
-		}
-		hostname = h
-		port = p
-	}
-
-	// Busca as chaves SSH do jump host
-	var jumpHostSSHKeys []string
-	if selectedJumpHost != nil {
-		jumpHostSSHKeys = cfg.GetJumpHostSSHKeys(selectedJumpHost)
+		ft.RemotePath = remotePath
 	}
 
 	// Solicita senha se -a for especificado
-	password := ""
 	if askPassword {
-		fmt.Printf("Password for %s@%s: ", usernameToUse, hostname)
+		fmt.Printf("Password for %s@%s: ", sshConn.User, sshConn.Host)
 		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		fmt.Println()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
 			os.Exit(1)
 		}
-		password = string(passwordBytes)
-	}
-
-	// Cria conexão SSH
-	sshConn := cmd.NewSSHConnection(
-		usernameToUse,
-		hostname,
-		port,
-		sshKeys,
-		password,
-		selectedJumpHost,
-		jumpHostSSHKeys,
-		"",
-		false,
-		"",
-		0,
-		verbose,
-	)
+		sshConn.Password = string(passwordBytes)
+	}
 
 	fmt.Println()
-	fmt.Printf("Enviando %s para %s@%s:%s...\n", localPath, usernameToUse, hostname, remotePath)
-	if selectedJumpHost != nil {
-		fmt.Printf("   via Jump Host: %s\n", selectedJumpHost.Name)
+	fmt.Printf("Enviando %s para %s@%s:%s...\n", localPath, sshConn.User, sshConn.Host, remotePath)
+	if sshConn.JumpHost != nil {
+		fmt.Printf("   via Jump Host: %s\n", sshConn.JumpHost.Name)
 	}
 	fmt.Println()
 
-	if err := ft.Upload(sshConn); err != nil {
+	if err := ft.Upload(ctx, sshConn); err != nil {
 		fmt.Fprintf(os.Stderr, "\nErro: %v\n", err)
 		os.Exit(1)
 	}
@@ -1046,17 +2242,20 @@ func runPortForward(cobraCmd *cobra.Command, args []string) {
 	}
 
 	// Inicializa configuração
-	configPath, err := config.InitializeConfigDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Erro ao inicializar configuração: %v\n", err)
-		os.Exit(1)
-	}
+	configPath, configContext := initConfig(true)
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
 		os.Exit(1)
 	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
 
 	// Resolve o Jump Host se solicitado
 	var selectedJumpHost *config.JumpHost
@@ -1087,74 +2286,27 @@ func runPortForward(cobraCmd *cobra.Command, args []string) {
 	// Valida chaves SSH apenas do usuário efetivo
 	config.ValidateEffectiveUserSSHKeys(effectiveUser)
 
-	// Resolve o host
-	var hostname string
-	var port int
-	var sshKeys []string
-
-	usernameToUse := effectiveUser.Name
-	for _, key := range effectiveUser.SSHKeys {
-		sshKeys = append(sshKeys, config.ExpandHomePath(key))
-	}
-
-	if host := cfg.FindHost(hostArg); host != nil {
-		hostname = host.Host
-		port = host.Port
-	} else {
-		u, h, p, err := cmd.ParseConnectionString(hostArg)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
-			os.Exit(1)
-		}
-		if u != "" && u != effectiveUser.Name {
-			usernameToUse = u
-			if userFromConfig := cfg.FindUser(usernameToUse); userFromConfig != nil {
-				sshKeys = nil // Limpa chaves anteriores
-				for _, key := range userFromConfig.SSHKeys {
-					sshKeys = append(sshKeys, config.ExpandHomePath(key))
-				}
-			} else {
-				sshKeys = nil
-			}
-		}
-		hostname = h
-		port = p
-	}
-
-	// Busca as chaves SSH do jump host
-	var jumpHostSSHKeys []string
-	if selectedJumpHost != nil {
-		jumpHostSSHKeys = cfg.GetJumpHostSSHKeys(selectedJumpHost)
+	// Resolve o host (config.yaml, rules ou conexão direta) e cria a conexão SSH
+	// O port forward não tem limite de duração: vale apenas o tempo limite de conexão
+	timeouts := resolveTimeouts(cobraCmd, cfg)
+	timeouts.Command, timeouts.CommandFlag = 0, true
+	sshConn, err := cmd.NewHostConnection(cfg, hostArg, selectedUser, selectedJumpHost, timeouts, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Solicita senha se -a for especificado
-	password := ""
 	if askPassword {
-		fmt.Printf("Password for %s@%s: ", usernameToUse, hostname)
+		fmt.Printf("Password for %s@%s: ", sshConn.User, sshConn.Host)
 		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		fmt.Println()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Erro ao ler senha: %v\n", err)
 			os.Exit(1)
 		}
-		password = string(passwordBytes)
-	}
-
-	// Cria conexão SSH
-	sshConn := cmd.NewSSHConnection(
-		usernameToUse,
-		hostname,
-		port,
-		sshKeys,
-		password,
-		selectedJumpHost,
-		jumpHostSSHKeys,
-		"",
-		false,
-		"",
-		0,
-		verbose,
-	)
+		sshConn.Password = string(passwordBytes)
+	}
 
 	// Cria sessão de port forward
 	pf := cmd.NewPortForwardSession(sshConn, cmd.PortForward{
@@ -1164,12 +2316,888 @@ func runPortForward(cobraCmd *cobra.Command, args []string) {
 	})
 
 	// Inicia o port forwarding (bloqueia até Ctrl+C)
-	if err := pf.Start(); err != nil {
+	if err := pf.Start(context.Background()); err != nil {
 		fmt.Fprintf(os.Stderr, "\nErro: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+func runScript(cobraCmd *cobra.Command, args []string) {
+	// Separa script, argumentos e hosts: sc run <script> [args...] -- <hosts...>
+	// (sem "--", tudo após o script são hosts)
+	scriptPath := args[0]
+	var scriptArgs, hostArgs []string
+	dash := -1
+	for i, arg := range args[1:] {
+		if arg == "--" {
+			dash = i + 1
+			break
+		}
+	}
+	if dash >= 0 {
+		scriptArgs = args[1:dash]
+		hostArgs = args[dash+1:]
+	} else {
+		hostArgs = args[1:]
+	}
+	hostArgs, stdinUsed := hostSelectorArgs(hostArgs)
+	if len(hostArgs) == 0 {
+		fmt.Fprintf(os.Stderr, "Erro: Nenhum host especificado\n")
+		fmt.Fprintf(os.Stderr, "Uso: sc run <script> [args...] -- <hosts|@tag...>\n")
+		os.Exit(1)
+	}
+
+	script, err := cmd.LoadScript(scriptPath, scriptArgs, runEnv, runInterpreter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	format := parsedOutputFormat()
+	if format.IsStructured() && streamOutput {
+		fmt.Fprintf(os.Stderr, "Erro: As opções --output e --stream não podem ser usadas juntas\n")
+		os.Exit(1)
+	}
+	validateGroupFlags(format)
+	if sudoUser != "" {
+		useSudo = true
+	}
+
+	// Inicializa configuração
+	configPath, configContext := initConfig(true)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
+
+	// Resolve o Jump Host se solicitado
+	var selectedJumpHost *config.JumpHost
+	if jumpHost != "" {
+		selectedJumpHost = cfg.ResolveJumpHost(jumpHost)
+		if selectedJumpHost == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Jump host '%s' não encontrado\n", jumpHost)
+			os.Exit(1)
+		}
+	}
+
+	// Valida e aplica o usuário
+	var selectedUser *config.User
+	if username != "" {
+		selectedUser = cfg.FindUser(username)
+		if selectedUser == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Usuário '%s' não encontrado no config.yaml\n", username)
+			os.Exit(1)
+		}
+	}
+
+	opts := cmd.MultipleOptions{
+		Rollout:  rolloutOptions(),
+		Stream:   streamOutput,
+		Group:    groupOutput,
+		Diff:     diffOutput,
+		Output:   format,
+		Timeouts: resolveTimeouts(cobraCmd, cfg),
+		Sudo:     useSudo,
+		SudoUser: sudoUser,
+		Script:   script,
+	}
+	if !noStdin && !stdinUsed && !term.IsTerminal(int(os.Stdin.Fd())) {
+		opts.Stdin = os.Stdin
+	}
+
+	ctx, stop := interruptContext()
+	ok := cmd.ConnectMultiple(ctx, cfg, configPath, hostArgs, selectedUser, selectedJumpHost, script.String(), proxyEnabled, askPassword, verbose, opts)
+	stop()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func runRunbook(cobraCmd *cobra.Command, args []string) {
+	rb, err := cmd.LoadRunbook(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	if forks < 0 {
+		fmt.Fprintf(os.Stderr, "Erro: --forks não pode ser negativo\n")
+		os.Exit(1)
+	}
+	format := parsedOutputFormat()
+
+	// Inicializa configuração
+	configPath, configContext := initConfig(true)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
+
+	// Resolve o Jump Host se solicitado
+	var selectedJumpHost *config.JumpHost
+	if jumpHost != "" {
+		selectedJumpHost = cfg.ResolveJumpHost(jumpHost)
+		if selectedJumpHost == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Jump host '%s' não encontrado\n", jumpHost)
+			os.Exit(1)
+		}
+	}
+
+	// Valida e aplica o usuário
+	var selectedUser *config.User
+	if username != "" {
+		selectedUser = cfg.FindUser(username)
+		if selectedUser == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Usuário '%s' não encontrado no config.yaml\n", username)
+			os.Exit(1)
+		}
+	}
+
+	opts := cmd.RunbookOptions{
+		Check:    runbookCheck,
+		Forks:    forks,
+		Timeouts: resolveTimeouts(cobraCmd, cfg),
+		Output:   format,
+	}
+
+	ctx, stop := interruptContext()
+	ok := cmd.RunRunbook(ctx, cfg, rb, selectedUser, selectedJumpHost, proxyEnabled, askPassword, verbose, opts)
+	stop()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func runInventoryRefresh(cobraCmd *cobra.Command, args []string) {
+	// Inicializa configuração
+	configPath, _ := initConfig(false)
+
+	// Carrega apenas o config.yaml: as fontes são executadas abaixo, sem cache
+	cfg, err := config.LoadStaticConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	if len(cfg.Config.InventorySources) == 0 {
+		fmt.Println("Nenhuma fonte de inventário configurada (config.inventory_sources)")
+		return
+	}
+	for _, name := range args {
+		found := false
+		for i := range cfg.Config.InventorySources {
+			if cfg.Config.InventorySources[i].SourceName() == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Erro: fonte de inventário '%s' não encontrada\n", name)
+			os.Exit(1)
+		}
+	}
+
+	failed := false
+	for _, result := range cfg.LoadInventory(true, args...) {
+		switch {
+		case result.Err != nil && result.Stale:
+			failed = true
+			fmt.Printf("❌ %s: %v (usando cache de %s, %d host(s))\n", result.Source, result.Err, result.At.Format("2006-01-02 15:04"), result.Hosts)
+		case result.Err != nil:
+			failed = true
+			fmt.Printf("❌ %s: %v\n", result.Source, result.Err)
+		case result.Cached:
+			fmt.Printf("📦 %s: %d host(s) (cache de %s)\n", result.Source, result.Hosts, result.At.Format("2006-01-02 15:04"))
+		default:
+			fmt.Printf("✅ %s: %d host(s)\n", result.Source, result.Hosts)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func runImportAnsible(cobraCmd *cobra.Command, args []string) {
+	inv, err := config.ReadAnsibleInventory(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao ler inventário %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	// Inicializa configuração
+	configPath, _ := initConfig(false)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	result := cfg.ImportAnsible(inv)
+	fmt.Printf("📥 Inventário %s: %d host(s), %d grupo(s) com vars\n", args[0], len(inv.Hosts), len(inv.Groups))
+	for _, host := range result.Added {
+		detail := fmt.Sprintf("%s:%d", host.Host, host.Port)
+		if host.User != "" {
+			detail = host.User + "@" + detail
+		}
+		if host.Jump != "" {
+			detail += " via " + host.Jump
+		}
+		fmt.Printf("   + %-20s %s [%s]\n", host.Name, detail, strings.Join(host.Tags, ", "))
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("   = %-20s já cadastrado, mantido sem alteração\n", name)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", warning)
+	}
+
+	if importDryRun {
+		fmt.Printf("ℹ️  --dry-run: %d host(s) seriam adicionados, nada foi gravado\n", len(result.Added))
+		return
+	}
+	if len(result.Added) == 0 && result.TagVars == 0 {
+		fmt.Println("ℹ️  Nenhum host novo para adicionar")
+		return
+	}
+	if err := cfg.SaveConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao salvar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %d host(s) adicionado(s) ao %s\n", len(result.Added), configPath)
+}
+
+func runExportAnsible(cobraCmd *cobra.Command, args []string) {
+	// Inicializa configuração
+	configPath, _ := initConfig(false)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	warnings, err := cfg.ExportAnsible(os.Stdout, ansibleFormat)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", warning)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runHostsImport(cobraCmd *cobra.Command, args []string) {
+	mapping, err := config.ParseColumnMapping(hostsMapping)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	strategy, err := config.ParseTagMergeStrategy(hostsTags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Inicializa configuração
+	configPath, _ := initConfig(false)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	opts := cmd.HostsImportOptions{
+		Format:  hostsImportFormat,
+		Mapping: mapping,
+		Tags:    strategy,
+		AddTags: hostsAddTags,
+		DryRun:  importDryRun,
+		Yes:     hostsYes,
+	}
+	if err := cmd.ImportHosts(cfg, configPath, args[0], opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runHostsExport(cobraCmd *cobra.Command, args []string) {
+	// Inicializa configuração
+	configPath, _ := initConfig(false)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	if err := cfg.ExportHosts(os.Stdout, hostsExportFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runConfigMigrate(cobraCmd *cobra.Command, args []string) {
+	// Não usa InitializeConfigDir: a migração automática aplicaria as migrações antes da prévia (--dry-run)
+	var configPath string
+	if len(args) > 0 {
+		configPath = args[0]
+	} else {
+		path, err := config.GetConfigPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		configPath = path
+	}
+
+	result, err := config.MigrateConfig(configPath, configMigrateDry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	result.WriteSummary(os.Stdout)
+}
+
+func runConfigRestore(cobraCmd *cobra.Command, args []string) {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	configDir := filepath.Dir(configPath)
+
+	backups, err := config.ListBackups(configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao listar cópias de segurança: %v\n", err)
+		os.Exit(1)
+	}
+	if configRestoreList {
+		if len(backups) == 0 {
+			fmt.Println("Nenhuma cópia de segurança encontrada")
+			return
+		}
+		fmt.Printf("💾 Cópias de segurança em %s:\n", filepath.Join(configDir, config.BackupDirName))
+		for _, backup := range backups {
+			fmt.Printf("   %s  %s  %s\n", backup.Name, backup.Time.Format("2006-01-02 15:04:05"), strings.Join(relativeFiles(configDir, backup.Files), ", "))
+		}
+		return
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else if len(backups) > 0 {
+		name = backups[0].Name
+	}
+
+	// Confirma no terminal
+	if !configRestoreYes && term.IsTerminal(int(os.Stdin.Fd())) && name != "" {
+		fmt.Printf("Restaurar a cópia de segurança %s? O estado atual também será copiado. [s/N]: ", name)
+		var response string
+		fmt.Scanln(&response)
+		if response != "s" && response != "S" {
+			fmt.Println("Restauração cancelada.")
+			return
+		}
+	}
+
+	backup, err := config.RestoreBackup(configPath, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Cópia de segurança %s restaurada: %s\n", backup.Name, strings.Join(relativeFiles(configDir, backup.Files), ", "))
+}
+
+// relativeFiles exibe os caminhos relativos ao diretório de configuração (quando possível)
+func relativeFiles(configDir string, files []string) []string {
+	relative := make([]string, len(files))
+	for i, file := range files {
+		if rel, err := filepath.Rel(configDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			relative[i] = rel
+		} else {
+			relative[i] = file
+		}
+	}
+	return relative
+}
+
+// loadEditableConfig carrega a configuração para os comandos de edição (sc host, sc user e sc jump)
+func loadEditableConfig() (string, *config.ConfigFile) {
+	configPath, _ := initConfig(false)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+	return configPath, cfg
+}
+
+// saveEditedConfig grava a configuração alterada pelos comandos de edição
+func saveEditedConfig(cfg *config.ConfigFile, configPath string) {
+	if err := cfg.SaveConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao salvar configuração: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// warnUnknownHostUser avisa quando o usuário do host não está em users (a conexão usará as chaves do default_user)
+func warnUnknownHostUser(cfg *config.ConfigFile, user string) {
+	if user != "" && cfg.FindUser(user) == nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: usuário '%s' não está definido em users: serão usadas as chaves SSH do default_user (use 'sc user add')\n", user)
+	}
+}
+
+func runHostAdd(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	host := config.Host{
+		Name: args[0],
+		Host: args[1],
+		Port: hostAddPort,
+		Tags: hostAddTags,
+		User: hostAddUser,
+		Jump: hostAddJump,
+	}
+	// Aceita host:porta quando --port não é informado
+	if address, port, err := net.SplitHostPort(args[1]); err == nil && hostAddPort == 0 {
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: porta inválida em '%s'\n", args[1])
+			os.Exit(1)
+		}
+		host.Host, host.Port = address, portNumber
+	}
+	if host.Port == 0 {
+		host.Port = 22
+	}
+	if host.Tags == nil {
+		host.Tags = []string{}
+	}
+
+	if err := cfg.CreateHost(host); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	warnUnknownHostUser(cfg, host.User)
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Host '%s' adicionado (%s:%d)\n", host.Name, host.Host, host.Port)
+}
+
+func runHostRm(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	for _, name := range args {
+		if err := cfg.RemoveHost(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	saveEditedConfig(cfg, configPath)
+	for _, name := range args {
+		fmt.Printf("✅ Host '%s' removido\n", name)
+	}
+}
+
+func runHostEdit(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	current := cfg.FindHost(args[0])
+	if current == nil {
+		fmt.Fprintf(os.Stderr, "Erro: host '%s' não encontrado\n", args[0])
+		os.Exit(1)
+	}
+	flags := cobraCmd.Flags()
+	if !flags.Changed("addr") && !flags.Changed("port") && !flags.Changed("user") && !flags.Changed("jump") {
+		fmt.Fprintln(os.Stderr, "Erro: informe ao menos uma alteração (--addr, --port, --user ou --jump)")
+		os.Exit(1)
+	}
+
+	host := *current
+	if flags.Changed("addr") {
+		host.Host = hostEditAddr
+	}
+	if flags.Changed("port") {
+		host.Port = hostEditPort
+	}
+	if flags.Changed("user") {
+		host.User = hostEditUser
+	}
+	if flags.Changed("jump") {
+		host.Jump = hostEditJump
+	}
+
+	if err := cfg.UpdateHost(args[0], host); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	warnUnknownHostUser(cfg, host.User)
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Host '%s' atualizado (%s:%d)\n", host.Name, host.Host, host.Port)
+}
+
+func runHostRename(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	if err := cfg.RenameHost(args[0], args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Host '%s' renomeado para '%s'\n", args[0], args[1])
+}
+
+// runHostTag atende sc host tag add e sc host tag rm
+func runHostTag(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	name, tags := args[0], args[1:]
+	var changed []string
+	var err error
+	action := "adicionada(s)"
+	if cobraCmd.Name() == "rm" {
+		action = "removida(s)"
+		changed, err = cfg.RemoveHostTags(name, tags)
+	} else {
+		changed, err = cfg.AddHostTags(name, tags)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if len(changed) == 0 {
+		fmt.Printf("ℹ️  Nenhuma alteração nas tags de '%s'\n", name)
+		return
+	}
+
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Tag(s) %s %s em '%s' (tags: %s)\n", strings.Join(changed, ", "), action, name,
+		strings.Join(cfg.FindHost(name).Tags, ", "))
+}
+
+func runUserAdd(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	user := config.User{Name: args[0], SSHKeys: userAddKeys, PasswordRef: userAddRef, PasswordCommand: userAddCmdPw}
+	if err := cfg.CreateUser(user); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	for _, warning := range config.ValidateSSHKeyPairs(&user) {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s\n", warning)
+	}
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Usuário '%s' adicionado (%d chave(s))\n", user.Name, len(user.SSHKeys))
+}
+
+func runJumpAdd(cobraCmd *cobra.Command, args []string) {
+	configPath, cfg := loadEditableConfig()
+
+	jumpHost := config.JumpHost{Name: args[0], Host: args[1], Port: jumpAddPort, User: jumpAddUser, PasswordCommand: jumpAddCmdPw}
+	if jumpHost.User == "" {
+		if defaultUser := cfg.GetDefaultUser(); defaultUser != nil {
+			jumpHost.User = defaultUser.Name
+		}
+	}
+	if err := cfg.CreateJumpHost(jumpHost); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	saveEditedConfig(cfg, configPath)
+	fmt.Printf("✅ Jump host '%s' adicionado (%s@%s:%d)\n", jumpHost.Name, jumpHost.User, jumpHost.Host, jumpHost.Port)
+}
+
+func runVaultSet(cobraCmd *cobra.Command, args []string) {
+	name := args[0]
+	vault := openVault(true)
+
+	secret, err := cmd.ReadSecret(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	_, replaced := vault.Get(name)
+	if err := vault.Set(name, secret); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if err := vault.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao gravar o cofre: %v\n", err)
+		os.Exit(1)
+	}
+	if replaced {
+		fmt.Printf("✅ Segredo '%s' atualizado\n", name)
+	} else {
+		fmt.Printf("✅ Segredo '%s' gravado (use password_ref: %s em usuários ou hosts)\n", name, name)
+	}
+}
+
+func runVaultGet(cobraCmd *cobra.Command, args []string) {
+	vault := openVault(false)
+	secret, ok := vault.Get(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Erro: segredo '%s' não encontrado no cofre\n", args[0])
+		os.Exit(1)
+	}
+	fmt.Println(secret)
+}
+
+func runVaultRm(cobraCmd *cobra.Command, args []string) {
+	vault := openVault(false)
+	if !vault.Remove(args[0]) {
+		fmt.Fprintf(os.Stderr, "Erro: segredo '%s' não encontrado no cofre\n", args[0])
+		os.Exit(1)
+	}
+	if err := vault.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao gravar o cofre: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Segredo '%s' removido\n", args[0])
+}
+
+func runVaultList(cobraCmd *cobra.Command, args []string) {
+	vault := openVault(false)
+
+	// Quem referencia cada segredo (usuários e hosts do config.yaml)
+	refs := make(map[string][]string)
+	if configPath, err := config.GetConfigPath(); err == nil {
+		if cfg, err := config.LoadStaticConfig(configPath); err == nil {
+			for _, user := range cfg.Config.User {
+				if user.PasswordRef != "" {
+					refs[user.PasswordRef] = append(refs[user.PasswordRef], "usuário "+user.Name)
+				}
+			}
+			for _, host := range cfg.Hosts {
+				if host.PasswordRef != "" {
+					refs[host.PasswordRef] = append(refs[host.PasswordRef], "host "+host.Name)
+				}
+			}
+		}
+	}
+
+	names := vault.Names()
+	if len(names) == 0 {
+		fmt.Println("Nenhum segredo no cofre")
+	}
+	for _, name := range names {
+		if len(refs[name]) > 0 {
+			fmt.Printf("   %s  (%s)\n", name, strings.Join(refs[name], ", "))
+		} else {
+			fmt.Printf("   %s\n", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(refs)) {
+		if _, ok := vault.Get(name); !ok {
+			fmt.Fprintf(os.Stderr, "⚠️  Aviso: password_ref '%s' (%s) não existe no cofre\n", name, strings.Join(refs[name], ", "))
+		}
+	}
+}
+
+// openVault abre o cofre pedindo a senha mestra (create: cria o cofre se ainda não existir)
+func openVault(create bool) *config.Vault {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	var vault *config.Vault
+	if create && !config.VaultExists(config.VaultPath(configPath)) {
+		vault, err = cmd.CreateVaultInteractive()
+	} else {
+		vault, err = cmd.UnlockVault()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	return vault
+}
+
+func runContextList(cobraCmd *cobra.Command, args []string) {
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if len(contexts) == 0 {
+		fmt.Println("ℹ️  Nenhum contexto definido (adicione a seção contexts ao config.yaml)")
+		return
+	}
+	active, _, err := config.ContextName(contextName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %v\n", err)
+	}
+
+	orDash := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+	fmt.Printf("  %-15s %-12s %-18s %-6s %-18s %s\n", "Nome", "Usuário", "Jump Host", "Proxy", "Filtro", "Config")
+	for _, ctx := range contexts {
+		marker := " "
+		if ctx.Name == active {
+			marker = "*"
+		}
+		proxy := "não"
+		if ctx.Proxy {
+			proxy = "sim"
+		}
+		fmt.Printf("%s %-15s %-12s %-18s %-6s %-18s %s\n", marker, ctx.Name, orDash(ctx.User), orDash(ctx.Jump), proxy, orDash(ctx.Filter), orDash(ctx.Config))
+	}
+	if active != "" && !slices.ContainsFunc(contexts, func(c config.Context) bool { return c.Name == active }) {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: contexto ativo '%s' não está definido no config.yaml\n", active)
+	}
+}
+
+func runContextUse(cobraCmd *cobra.Command, args []string) {
+	if contextUseNone == (len(args) == 1) {
+		fmt.Fprintf(os.Stderr, "Erro: informe o nome do contexto ou --none\n")
+		os.Exit(1)
+	}
+
+	if contextUseNone {
+		if err := config.SetCurrentContext(""); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Contexto padrão desativado")
+	} else {
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		if !slices.ContainsFunc(contexts, func(c config.Context) bool { return c.Name == args[0] }) {
+			fmt.Fprintf(os.Stderr, "Erro: contexto '%s' não encontrado no config.yaml (veja 'sc context list')\n", args[0])
+			os.Exit(1)
+		}
+		if err := config.SetCurrentContext(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Contexto ativo: %s\n", args[0])
+	}
+
+	// SC_CONTEXT e --context continuam tendo precedência
+	if name := os.Getenv(config.ContextEnv); name != "" {
+		fmt.Fprintf(os.Stderr, "⚠️  Aviso: %s=%s está definido e tem precedência sobre 'sc context use'\n", config.ContextEnv, name)
+	}
+}
+
+func runContextCurrent(cobraCmd *cobra.Command, args []string) {
+	name, origin, err := config.ContextName(contextName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if name == "" {
+		fmt.Fprintln(os.Stderr, "Nenhum contexto ativo")
+		os.Exit(1)
+	}
+	if _, err := config.ActiveContext(contextName); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(name)
+	fmt.Fprintf(os.Stderr, "(definido por %s)\n", origin)
+}
+
+func runExplain(cobraCmd *cobra.Command, args []string) {
+	configPath, configContext := initConfig(true)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+	if err := cfg.UseContext(configContext); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Opções do contexto ativo valem para as flags não informadas
+	applyContext(cobraCmd, cfg)
+	if ctx := cfg.Context(); ctx != nil {
+		fmt.Printf("🧭 Contexto '%s' ativo (user, jump e proxy do contexto valem como -u, -j e -p)\n", ctx.Name)
+	}
+
+	// Resolve o Jump Host se solicitado
+	var selectedJumpHost *config.JumpHost
+	if jumpHost != "" {
+		selectedJumpHost = cfg.ResolveJumpHost(jumpHost)
+		if selectedJumpHost == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Jump host '%s' não encontrado\n", jumpHost)
+			os.Exit(1)
+		}
+	}
+
+	// Valida e aplica o usuário
+	var selectedUser *config.User
+	if username != "" {
+		selectedUser = cfg.FindUser(username)
+		if selectedUser == nil {
+			fmt.Fprintf(os.Stderr, "Erro: Usuário '%s' não encontrado no config.yaml\n", username)
+			os.Exit(1)
+		}
+	}
+
+	if err := cmd.Explain(cfg, args[0], selectedUser, selectedJumpHost, proxyEnabled, resolveTimeouts(cobraCmd, cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runConfigValidate(cobraCmd *cobra.Command, args []string) {
+	var configPath string
+	if len(args) > 0 {
+		configPath = args[0]
+	} else {
+		configPath, _ = initConfig(false)
+	}
+
+	result, err := config.ValidateConfig(configPath, configStrict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s: %v\n", configPath, err)
+		os.Exit(1)
+	}
+
+	for _, issue := range result.Errors {
+		fmt.Printf("❌ %s\n", issue)
+	}
+	for _, issue := range result.Warnings {
+		fmt.Printf("⚠️  %s\n", issue)
+	}
+	if !result.Valid() {
+		fmt.Printf("\n%d erro(s), %d aviso(s) em %d arquivo(s)\n", len(result.Errors), len(result.Warnings), len(result.Files))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Configuração válida: %d arquivo(s), %d aviso(s)\n", len(result.Files), len(result.Warnings))
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)